@@ -0,0 +1,40 @@
+package clog
+
+import (
+	"errors"
+	"time"
+)
+
+var errBadSegmentSizer = errors.New("adaptive segment size needs 0 < minBytes <= maxBytes & a positive target")
+
+// segmentSizer picks the maxSegBytes of each new segment from the rate at which the segment before it was filled; so
+// that a segment is rolled about every target. see WithAdaptiveSegmentSize
+type segmentSizer struct {
+	minBytes uint64
+	maxBytes uint64
+	target   time.Duration
+}
+
+// next returns the maxSegBytes of the segment that follows one that was filled with filled bytes over took nanoseconds.
+func (z *segmentSizer) next(filled uint64, took uint64) uint64 {
+	if took == 0 {
+		return z.maxBytes
+	}
+	size := uint64(float64(filled) / float64(took) * float64(z.target.Nanoseconds()))
+	if size < z.minBytes {
+		return z.minBytes
+	}
+	if size > z.maxBytes {
+		return z.maxBytes
+	}
+	return size
+}
+
+// nextSegmentSize returns the maxSegBytes of the segment that is created to follow earlierActive, which may be nil.
+// The caller should hold l.mu
+func (l *Clog) nextSegmentSize(earlierActive *segment) uint64 {
+	if l.sizer == nil || earlierActive == nil {
+		return l.maxSegBytes
+	}
+	return l.sizer.next(earlierActive.size(), l.clock.age(earlierActive.baseOffset))
+}
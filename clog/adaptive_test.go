@@ -0,0 +1,85 @@
+package clog
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithAdaptiveSegmentSize(t *testing.T) {
+	t.Parallel()
+
+	t.Run("sizes follow the rate of appends", func(t *testing.T) {
+		t.Parallel()
+
+		z := &segmentSizer{minBytes: 100, maxBytes: 10_000, target: time.Minute}
+		tt := []struct {
+			name   string
+			filled uint64
+			took   time.Duration
+			want   uint64
+		}{
+			{name: "a segment a minute", filled: 1_000, took: time.Minute, want: 1_000},
+			{name: "twice as fast", filled: 1_000, took: 30 * time.Second, want: 2_000},
+			{name: "too slow", filled: 1_000, took: time.Hour, want: 100},
+			{name: "too fast", filled: 1_000, took: time.Second, want: 10_000},
+			{name: "instantly", filled: 1_000, took: 0, want: 10_000},
+		}
+		for _, v := range tt {
+			if got := z.next(v.filled, uint64(v.took.Nanoseconds())); got != v.want {
+				t.Errorf("%s: \ngot \n\t%#+v \nwanted \n\t%#+v", v.name, got, v.want)
+			}
+		}
+	})
+
+	t.Run("bad bounds", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		_, err := New(path, 100, 100_000, time.Hour, WithAdaptiveSegmentSize(1_000, 100, time.Minute))
+		if !errors.Is(err, errBadSegmentSizer) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadSegmentSizer)
+		}
+	})
+
+	t.Run("clog", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		mu := sync.Mutex{}
+		now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		clk := func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		}
+		l, err := New(path, 10, 100_000, time.Hour, WithClock(clk), WithAdaptiveSegmentSize(10, 1_000, time.Minute))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer l.Close()
+
+		// the first segment, of maxSegBytes, is filled with 17 bytes over 6 seconds; 170 bytes a minute.
+		errA := l.Append([]byte("order # 1"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		mu.Lock()
+		now = now.Add(6 * time.Second)
+		mu.Unlock()
+		errB := l.Append([]byte("order # 2"))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		l.mu.RLock()
+		got := l.segments[len(l.segments)-1].maxSegBytes
+		l.mu.RUnlock()
+		if got != 170 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 170)
+		}
+	})
+}
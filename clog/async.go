@@ -0,0 +1,69 @@
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// AppendFuture is the result of an append that is in progress. see Clog.AppendAsync
+type AppendFuture struct {
+	done   chan struct{}
+	offset RecordOffset
+	err    error
+}
+
+// Done returns a channel that is closed once the append has completed; either because the record is durable, or because
+// the append failed.
+func (f *AppendFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the append has completed, & returns the offset of the record or the error that the append failed with.
+func (f *AppendFuture) Wait() (RecordOffset, error) {
+	<-f.done
+	return f.offset, f.err
+}
+
+// resolve completes the future.
+func (f *AppendFuture) resolve(offset RecordOffset, err error) {
+	f.offset, f.err = offset, err
+	close(f.done)
+}
+
+// AppendAsync adds an item to the commitlog, as a record, without waiting for it to be synced to stable storage; it
+// returns a future that resolves with the offset of the record once it is durable, regardless of the commitlog's
+// SyncPolicy. It lets high-throughput producers pipeline their appends, rather than blocking on an fsync for each one.
+//
+// The record is written before AppendAsync returns; so records appended one after the other with AppendAsync keep their
+// order, & are read in that order. The syncs of records appended meanwhile are shared. see SyncAlways
+// If the record can not be written, the future is already resolved with the error when it is returned.
+//
+// usage:
+//
+//	futures := []*AppendFuture{}
+//	for _, order := range orders {
+//		futures = append(futures, l.AppendAsync(order))
+//	}
+//	for _, f := range futures {
+//		o, err := f.Wait()
+//	}
+func (l *Clog) AppendAsync(b []byte) *AppendFuture {
+	start := time.Now()
+	f := &AppendFuture{done: make(chan struct{})}
+
+	offset, ticket, err := l.writeBulk(context.Background(), [][]byte{b}, nil, true)
+	if err != nil {
+		l.tracer.add("append-async", start, len(b), err)
+		f.resolve(RecordOffset{}, err)
+		return f
+	}
+	go func() {
+		errC := l.commit(ticket, offset.BaseOffset)
+		l.tracer.add("append-async", start, len(b), errC)
+		if errC != nil {
+			offset = RecordOffset{}
+		}
+		f.resolve(offset, errC)
+	}()
+	return f
+}
@@ -0,0 +1,71 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppendAsync(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// SyncOnSeal does not stop the futures from resolving once the records are durable.
+	l, err := New(path, 1_000, 100_000, time.Hour, WithSyncPolicy(SyncOnSeal), WithFsyncStallHandler(0, nil))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+
+	want := []string{}
+	futures := []*AppendFuture{}
+	for i := 0; i < 10; i++ {
+		r := fmt.Sprintf("order # %d", i)
+		want = append(want, r)
+		futures = append(futures, l.AppendAsync([]byte(r)))
+	}
+	var prev RecordOffset
+	for i, f := range futures {
+		o, errW := f.Wait()
+		if errW != nil {
+			t.Fatal("\n\t", errW)
+		}
+		if i > 0 && o.Sequence != prev.Sequence+1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", o, "the sequence to follow that of the previous record")
+		}
+		prev = o
+	}
+	if syncs := l.FsyncStalls(); syncs == 0 || syncs > 10 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", syncs, "between 1 and one fsync per append")
+	}
+
+	records, _, errR := l.ReadRecords(0, 0)
+	if errR != nil {
+		t.Fatal("\n\t", errR)
+	}
+	got := []string{}
+	for _, r := range records {
+		got = append(got, string(r))
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	// a record that can not be written resolves its future straight away.
+	errC := l.Close()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	f := l.AppendAsync([]byte("order # 10"))
+	select {
+	case <-f.Done():
+	default:
+		t.Fatal("expected the future to be resolved")
+	}
+	if _, errW := f.Wait(); !errors.Is(errW, ErrClosed) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errW, ErrClosed)
+	}
+}
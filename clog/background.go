@@ -0,0 +1,82 @@
+package clog
+
+import (
+	"errors"
+	"time"
+)
+
+// schedule is how often the commitlog does some work in the background, & who is told of the errors of that work.
+// see WithPollInterval & WithCleanInterval
+type schedule struct {
+	interval time.Duration
+	onErr    func(error)
+}
+
+// every calls work every s.interval, until the commitlog is closed. The caller should have added it to l.background
+func (l *Clog) every(s schedule, work func() error) {
+	defer l.background.Done()
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+			err := work()
+			if err != nil && !errors.Is(err, ErrClosed) && s.onErr != nil {
+				s.onErr(err)
+			}
+		}
+	}
+}
+
+// cleanInBackground calls Clean every l.autoClean.interval, until the commitlog is closed. It accesses the disk as
+// configured by WithBackgroundIO; waiting longer than the interval whenever that is needed to stay within maxBytesPerSec.
+// The caller should have added it to l.background
+func (l *Clog) cleanInBackground() {
+	defer l.background.Done()
+
+	onErr := func(err error) {
+		if err != nil && !errors.Is(err, ErrClosed) && l.autoClean.onErr != nil {
+			l.autoClean.onErr(err)
+		}
+	}
+	onErr(l.bgIO.apply())
+
+	wait := l.autoClean.interval
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-l.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		n, err := l.cleanCounted()
+		onErr(err)
+
+		wait = l.autoClean.interval
+		if p := l.bgIO.pause(n); p > wait {
+			wait = p
+		}
+	}
+}
+
+// cleanCounted is like Clean, but also returns the number of bytes held by the segments that it deleted.
+func (l *Clog) cleanCounted() (uint64, error) {
+	l.mu.RLock()
+	before := append([]*segment(nil), l.segments...)
+	l.mu.RUnlock()
+
+	err := l.Clean()
+
+	n := uint64(0)
+	for _, seg := range before {
+		if seg.isDeleted() {
+			n = n + seg.size()
+		}
+	}
+	return n, err
+}
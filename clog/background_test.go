@@ -0,0 +1,98 @@
+package clog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithCleanInterval(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each segment holds a single record; & the commitlog retains about three of them.
+	l, err := New(path, 10, 60, time.Hour, WithCleanInterval(time.Millisecond, func(e error) { t.Error("\n\t", e) }))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for i := 0; i < 10; i++ {
+		errA := l.Append([]byte("order # 1"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	segments := func() int {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return len(l.segments)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for segments() > 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := segments(); got > 4 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "the commitlog to be cleaned down to its retention")
+	}
+
+	// Close stops the cleaner; goleak checks that it exited.
+	errC := l.Close()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+}
+
+func TestWithCleanIntervalBackgroundIO(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// at 1byte/sec, a clean that deletes a few segments is followed by a pause of many seconds.
+	l, err := New(path, 10, 60, time.Hour,
+		WithCleanInterval(time.Millisecond, func(e error) { t.Error("\n\t", e) }),
+		WithBackgroundIO(IOPriorityIdle, 1),
+	)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	appendN := func(n int) {
+		for i := 0; i < n; i++ {
+			errA := l.Append([]byte("order # 1"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+	}
+	segments := func() int {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return len(l.segments)
+	}
+
+	appendN(10)
+	cleaned := func() bool {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return l.lowWatermark != 0
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !cleaned() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !cleaned() {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", segments(), "the commitlog to be cleaned")
+	}
+
+	// the cleaner is pausing; so these segments are not deleted yet.
+	want := segments() + 10
+	appendN(10)
+	time.Sleep(50 * time.Millisecond)
+	if got := segments(); got != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	errC := l.Close()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+}
@@ -0,0 +1,60 @@
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// BeyondPolicy decides what a read does when its offset is beyond the end of the commitlog; ie after the baseOffset of
+// its latest segment. Such an offset was not handed out by the commitlog, eg it is a lastReadOffset of another
+// commitlog, or a time in the future. see Clog.ReadBeyond
+type BeyondPolicy uint8
+
+const (
+	// BeyondEmpty reads no data, as Read does.
+	BeyondEmpty BeyondPolicy = iota
+	// BeyondFail fails the read with an *OutOfRangeError, whose Beyond is set.
+	BeyondFail
+	// BeyondWait blocks the read until data is appended after the offset, or until its context is done.
+	BeyondWait
+)
+
+// ReadBeyond is Read; but policy decides what it does if offset is beyond the end of the commitlog, rather than it
+// silently reading nothing. ctx is only used by BeyondWait.
+//
+// Unlike Read, if no data is read, lastReadOffset is offset rather than 0; so that it can always be read from next.
+//
+// usage:
+//
+//	data, lastReadOffset, err := l.ReadBeyond(ctx, offset, 0, BeyondWait)
+func (l *Clog) ReadBeyond(ctx context.Context, offset uint64, maxToRead uint64, policy BeyondPolicy) (dataRead []byte, lastReadOffset uint64, err error) {
+	defer func(start time.Time) { l.tracer.add("read-beyond", start, len(dataRead), err) }(time.Now())
+
+	l.mu.RLock()
+	end := uint64(0)
+	if segs := l.segmentRead(); len(segs) > 0 {
+		end = segs[len(segs)-1].baseOffset
+	}
+	lw := l.lowWatermark
+	l.mu.RUnlock()
+
+	if offset > end {
+		switch policy {
+		case BeyondFail:
+			return nil, offset, &OutOfRangeError{Offset: offset, LowWatermark: lw, Beyond: true, End: end}
+		case BeyondWait:
+			// the data after offset is in a segment whose baseOffset is larger than offset.
+			errW := l.WaitForOffset(ctx, offset+1)
+			if errW != nil {
+				return nil, offset, errW
+			}
+		}
+	}
+
+	dataRead, spans, err := l.read(ctx, offset, maxToRead)
+	lastReadOffset = offset
+	if len(spans) > 0 {
+		lastReadOffset = spans[len(spans)-1].BaseOffset
+	}
+	return dataRead, lastReadOffset, err
+}
@@ -0,0 +1,74 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadBeyond(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each append fills a segment.
+	l, err := New(path, 10, 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	errA := l.Append([]byte("order # 1"))
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	end := l.segmentRead()[len(l.segmentRead())-1].baseOffset
+	// an offset that the commitlog has not handed out.
+	beyond := uint64(time.Now().UnixNano())
+	if beyond <= end {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", beyond, "an offset beyond the latest segment")
+	}
+
+	data, lastReadOffset, errB := l.ReadBeyond(context.Background(), beyond, 0, BeyondEmpty)
+	if errB != nil || len(data) != 0 || lastReadOffset != beyond {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, beyond)
+	}
+
+	_, _, errC := l.ReadBeyond(context.Background(), beyond, 0, BeyondFail)
+	var oe *OutOfRangeError
+	if !errors.As(errC, &oe) || !oe.Beyond || oe.End != end || !errors.Is(errC, ErrOffsetOutOfRange) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, "an *OutOfRangeError beyond the end")
+	}
+	// offsets that were handed out are not beyond the end.
+	_, _, errD := l.ReadBeyond(context.Background(), end, 0, BeyondFail)
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, errE := l.ReadBeyond(ctx, beyond, 0, BeyondWait)
+	if !errors.Is(errE, context.DeadlineExceeded) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errE, context.DeadlineExceeded)
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	read := make(chan result, 1)
+	go func() {
+		d, _, errF := l.ReadBeyond(context.Background(), beyond, 0, BeyondWait)
+		read <- result{data: d, err: errF}
+	}()
+	errG := l.Append([]byte("order # 2"))
+	if errG != nil {
+		t.Fatal("\n\t", errG)
+	}
+	res := <-read
+	if res.err != nil {
+		t.Fatal("\n\t", res.err)
+	}
+	if recordsForTests(t, res.data) != "order # 2" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, res.data), "order # 2")
+	}
+}
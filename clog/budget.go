@@ -0,0 +1,99 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errBadMemoryBudget = errors.New("memory budget cannot be zero")
+
+// MemoryBudget caps the memory that concurrent reads can use at any one time.
+// A single budget can be shared by many commitlogs; see WithMemoryBudget.
+//
+// A read that would exceed the budget either blocks until memory is released by other reads,
+// or is trimmed to return less data than it otherwise would have.
+// The budget only accounts for memory while a read is in progress; once data is returned to the caller,
+// it is the caller's responsibility.
+//
+// To create a memory budget, use the NewMemoryBudget method.
+type MemoryBudget struct {
+	max uint64
+
+	// mu protects used & released
+	mu   sync.Mutex
+	used uint64
+	// released, if not nil, is closed by the next release; callers of acquire that are waiting for memory wait on it.
+	released chan struct{}
+}
+
+// NewMemoryBudget creates a memory budget that allows upto maxBytes to be used at any one time.
+func NewMemoryBudget(maxBytes uint64) (*MemoryBudget, error) {
+	if maxBytes <= 0 {
+		return nil, errBadMemoryBudget
+	}
+	return &MemoryBudget{max: maxBytes}, nil
+}
+
+// acquire blocks until n bytes are available and then reserves them; or until ctx is done, in which case nothing is
+// reserved & the error of ctx is returned.
+// A request larger than the whole budget is clamped to the budget, so that it can eventually proceed.
+// It returns the number of bytes reserved, which should be handed back using release.
+func (m *MemoryBudget) acquire(ctx context.Context, n uint64) (uint64, error) {
+	if m == nil {
+		return 0, nil
+	}
+	if n > m.max {
+		n = m.max
+	}
+
+	for {
+		m.mu.Lock()
+		if m.used+n <= m.max {
+			m.used = m.used + n
+			m.mu.Unlock()
+			return n, nil
+		}
+		if m.released == nil {
+			m.released = make(chan struct{})
+		}
+		released := m.released
+		m.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// tryAcquire reserves n bytes if they are available, without blocking.
+func (m *MemoryBudget) tryAcquire(n uint64) bool {
+	if m == nil {
+		return true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.used+n > m.max {
+		return false
+	}
+	m.used = m.used + n
+	return true
+}
+
+// release hands back n bytes that were reserved by acquire or tryAcquire.
+func (m *MemoryBudget) release(n uint64) {
+	if m == nil || n == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.used = m.used - n
+	if m.released != nil {
+		close(m.released)
+		m.released = nil
+	}
+}
@@ -0,0 +1,256 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("zero budget errors", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewMemoryBudget(0)
+		if !errors.Is(err, errBadMemoryBudget) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadMemoryBudget)
+		}
+	})
+
+	t.Run("acquire and release", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := NewMemoryBudget(100)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		got, errA := m.acquire(context.Background(), 60)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if got != 60 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 60)
+		}
+		if m.tryAcquire(50) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", true, false)
+		}
+		if !m.tryAcquire(40) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", false, true)
+		}
+		m.release(100)
+		if m.used != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.used, 0)
+		}
+
+		// requests larger than the budget are clamped.
+		gotB, errB := m.acquire(context.Background(), 700)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if gotB != 100 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", gotB, 100)
+		}
+		m.release(100)
+	})
+
+	t.Run("acquire blocks until released", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := NewMemoryBudget(100)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		_, errA := m.acquire(context.Background(), 100)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		acquired := make(chan struct{})
+		go func() {
+			_, errB := m.acquire(context.Background(), 10)
+			if errB != nil {
+				t.Error("\n\t", errB)
+			}
+			close(acquired)
+		}()
+
+		select {
+		case <-acquired:
+			t.Fatal("\n\t acquire should have blocked")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		m.release(100)
+		<-acquired
+		m.release(10)
+	})
+
+	t.Run("acquire gives up once ctx is done", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := NewMemoryBudget(100)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		_, errA := m.acquire(context.Background(), 100)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		got, errB := m.acquire(ctx, 10)
+		if !errors.Is(errB, context.DeadlineExceeded) || got != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, context.DeadlineExceeded)
+		}
+		// nothing was reserved.
+		m.release(100)
+		if m.used != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.used, 0)
+		}
+	})
+
+	t.Run("nil budget is unlimited", func(t *testing.T) {
+		t.Parallel()
+
+		var m *MemoryBudget
+		got, errA := m.acquire(context.Background(), 700)
+		if errA != nil || got != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 0)
+		}
+		if !m.tryAcquire(700) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", false, true)
+		}
+		m.release(700)
+	})
+}
+
+func TestReadWithMemoryBudget(t *testing.T) {
+	t.Parallel()
+
+	t.Run("read is trimmed", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := NewMemoryBudget(500)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, errA := New(path, 100, 1, time.Nanosecond, WithMemoryBudget(m))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		msg := []byte(strings.Repeat("a", 200))
+		for i := 0; i < 5; i++ {
+			errB := l.Append(msg)
+			if errB != nil {
+				t.Fatal("\n\t", errB)
+			}
+		}
+
+		blob, lastReadOffset, errC := l.Read(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if got := recordsForTests(t, blob); len(got) != 400 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 400)
+		}
+		if lastReadOffset != l.segments[1].baseOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, l.segments[1].baseOffset)
+		}
+		if m.used != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.used, 0)
+		}
+
+		// the rest can be read in subsequent calls.
+		blob2, _, errD := l.Read(lastReadOffset, 0)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if got := recordsForTests(t, blob2); len(got) != 400 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 400)
+		}
+	})
+
+	t.Run("budget is shared by concurrent reads", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := NewMemoryBudget(250)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, errA := New(path, 100, 1, time.Nanosecond, WithMemoryBudget(m))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		msg := []byte(strings.Repeat("a", 200))
+		for i := 0; i < 5; i++ {
+			errB := l.Append(msg)
+			if errB != nil {
+				t.Fatal("\n\t", errB)
+			}
+		}
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				blob, _, errC := l.Read(0, 0)
+				if errC != nil {
+					panic(errC)
+				}
+				if len(blob) != recordHeaderSize+200 {
+					panic("read was not trimmed")
+				}
+			}()
+		}
+		wg.Wait()
+		if m.used != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.used, 0)
+		}
+	})
+
+	t.Run("read gives up waiting for an exhausted budget", func(t *testing.T) {
+		t.Parallel()
+
+		m, err := NewMemoryBudget(250)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, errA := New(path, 100, 1, time.Nanosecond, WithMemoryBudget(m))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		errB := l.Append([]byte(strings.Repeat("a", 200)))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		// another read holds all of the budget.
+		_, errC := m.acquire(context.Background(), 250)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		defer m.release(250)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		blob, _, errD := l.ReadCtx(ctx, 0, 0)
+		if !errors.Is(errD, context.DeadlineExceeded) || len(blob) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, context.DeadlineExceeded)
+		}
+	})
+}
@@ -0,0 +1,46 @@
+package clog
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrChecksumMismatch is matched, using errors.Is, by the error that AppendChecksummed returns when a record does not
+// match the checksum that its producer computed.
+var ErrChecksumMismatch = errors.New("record does not match its checksum")
+
+// RecordChecksum returns the checksum that b is framed with when it is appended to a commitlog; the crc32(Castagnoli) of
+// its length & data. see Records
+// Producers compute it before the record leaves them, & pass it to AppendChecksummed.
+func RecordChecksum(b []byte) uint32 {
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+	return recordChecksum(length[:], b)
+}
+
+// AppendChecksummed is AppendOffset, for a record whose producer computed its checksum, using RecordChecksum, before
+// the record left it; eg before it was sent over the network, or buffered in memory for a while. It catches the
+// corruption of records by the network, or in RAM, & not only on disk.
+//
+// The record is checked against checksum before it is appended; a record that does not match it is not appended, & an
+// error matching ErrChecksumMismatch is returned. The record is then framed with checksum; so consumers re-verify
+// it, as they do all records, when they read it. see Records
+//
+// usage:
+//
+//	// producer
+//	sum := clog.RecordChecksum(b)
+//	send(b, sum)
+//	// server
+//	o, err := l.AppendChecksummed(b, sum)
+func (l *Clog) AppendChecksummed(b []byte, checksum uint32) (offset RecordOffset, err error) {
+	defer func(start time.Time) { l.tracer.add("append", start, len(b), err) }(time.Now())
+
+	if got := RecordChecksum(b); got != checksum {
+		return RecordOffset{}, fmt.Errorf("%w: the checksum of the record is %d, its producer computed %d", ErrChecksumMismatch, got, checksum)
+	}
+	return l.appendBulk(context.Background(), [][]byte{b}, []uint32{checksum}, l.syncPolicy == SyncAlways)
+}
@@ -0,0 +1,52 @@
+package clog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppendChecksummed(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 1_000, 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer l.Close()
+
+	b := []byte("order # 1")
+	sum := RecordChecksum(b)
+	_, errA := l.AppendChecksummed(b, sum)
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	// the record is framed with the checksum of its producer.
+	data, _, errR := l.Read(0, 0)
+	if errR != nil {
+		t.Fatal("\n\t", errR)
+	}
+	if got, want := data, encodeRecord(b); !cmp.Equal(got, want) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	// a record that was corrupted after its checksum was computed is not appended.
+	corrupted := []byte("order # 2")
+	sum = RecordChecksum(corrupted)
+	corrupted[0] = 'O'
+	_, errB := l.AppendChecksummed(corrupted, sum)
+	if !errors.Is(errB, ErrChecksumMismatch) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, ErrChecksumMismatch)
+	}
+	records, _, errC := l.ReadRecords(0, 0)
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	if len(records) != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 1)
+	}
+}
@@ -7,9 +7,27 @@ import (
 
 var errBadCleaner = errors.New("cleaner cannot have negative or zero maxLogBytes/maxLogAge ")
 
+// AgeRetention decides how Clean enforces the maxLogAge of a commitlog. see WithAgeRetention
+type AgeRetention uint8
+
+const (
+	// AgeRetentionTotal deletes the oldest segments once the ages of the segments, added up, exceed maxLogAge.
+	// It is the default.
+	AgeRetentionTotal AgeRetention = iota
+	// AgeRetentionPerSegment deletes each segment whose own age exceeds maxLogAge; like the retention.ms of Kafka.
+	// The age of a sealed segment is the time since it was sealed, ie since the segment after it was created; so it
+	// survives the commitlog being reopened. The active segment is always retained.
+	AgeRetentionPerSegment
+)
+
 type cleaner struct {
 	maxLogBytes uint64
 	maxLogAge   time.Duration
+	// ageRetention decides how maxLogAge is enforced. see WithAgeRetention
+	ageRetention AgeRetention
+	// maxSegments, if not 0, is the number of segments the log can have; once reached, the oldest segments are deleted.
+	// see WithMaxSegments
+	maxSegments int
 }
 
 func newCleaner(maxLogBytes uint64, maxLogAge time.Duration) (*cleaner, error) {
@@ -41,6 +59,12 @@ func (c *cleaner) clean(segs []*segment) ([]*segment, error) {
 		return nil, errA
 	}
 
+	// by number of segments.
+	segs, errB := c.cleanByCount(segs)
+	if errB != nil {
+		return nil, errB
+	}
+
 	// TODO: check that the latest segment should be at the end of list
 	return segs, nil
 }
@@ -89,6 +113,9 @@ func (c *cleaner) cleanByAge(segs []*segment) ([]*segment, error) {
 	if len(segs) <= 1 {
 		return segs, nil
 	}
+	if c.ageRetention == AgeRetentionPerSegment {
+		return c.cleanBySegmentAge(segs)
+	}
 
 	var total uint64
 	cleanedSegs := []*segment{}
@@ -124,6 +151,47 @@ func (c *cleaner) cleanByAge(segs []*segment) ([]*segment, error) {
 	return segs, nil
 }
 
+// cleanBySegmentAge deletes the sealed segments that were sealed more than maxLogAge ago. see AgeRetentionPerSegment
+// A segment is sealed when the segment after it in segs is created; if segments exempt from retention were left out
+// of segs, the segment after it may have been created later, & it is then retained for longer.
+func (c *cleaner) cleanBySegmentAge(segs []*segment) ([]*segment, error) {
+	active := segs[len(segs)-1]
+	expired := 0
+	for i := 0; i < len(segs)-1; i++ {
+		sealedAt := segs[i+1].baseOffset
+		if active.clock.age(sealedAt) <= uint64(c.maxLogAge.Nanoseconds()) {
+			// the segments after it were sealed later still.
+			break
+		}
+		expired = i + 1
+	}
+
+	for _, s := range segs[:expired] {
+		err := s.Delete()
+		if err != nil {
+			return segs, err
+		}
+	}
+	return segs[expired:], nil
+}
+
+// cleanByCount deletes the oldest segments, such that no more than maxSegments are retained.
+// Some filesystems degrade badly with huge directories, regardless of the total bytes in them.
+func (c *cleaner) cleanByCount(segs []*segment) ([]*segment, error) {
+	if c.maxSegments <= 0 || len(segs) <= c.maxSegments {
+		return segs, nil
+	}
+
+	excess := len(segs) - c.maxSegments
+	for _, s := range segs[:excess] {
+		err := s.Delete()
+		if err != nil {
+			return segs, err
+		}
+	}
+	return segs[excess:], nil
+}
+
 // contains tells whether a contains x.
 func contains(a []int, x int) bool {
 	for _, n := range a {
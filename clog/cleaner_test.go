@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestCleaner(t *testing.T) {
@@ -42,7 +44,8 @@ func TestCleanByBytes(t *testing.T) {
 	t.Run("total log size is equal to cleaner.maxLogBytes", func(t *testing.T) {
 		t.Parallel()
 
-		maxLogBytes := uint64(10)
+		// a record of 1byte is recordHeaderSize+1 bytes once framed.
+		maxLogBytes := uint64(10 * (recordHeaderSize + 1))
 		cl, errI := newCleaner(maxLogBytes, 1)
 		if errI != nil {
 			t.Fatal("\n\t", errI)
@@ -55,7 +58,7 @@ func TestCleanByBytes(t *testing.T) {
 			defer removePath()
 			segs = append(segs, s)
 
-			// each segment will store 1byte.
+			// each segment will store a record of 1byte.
 			// so size of all segments == maxLogBytes
 			msg := []byte("a")
 			err := s.Append(msg)
@@ -80,7 +83,8 @@ func TestCleanByBytes(t *testing.T) {
 	t.Run("total log size is less than cleaner.maxLogBytes", func(t *testing.T) {
 		t.Parallel()
 
-		maxLogBytes := uint64(10)
+		// a record of 1byte is recordHeaderSize+1 bytes once framed.
+		maxLogBytes := uint64(10 * (recordHeaderSize + 1))
 		cl, errI := newCleaner(maxLogBytes, 1)
 		if errI != nil {
 			t.Fatal("\n\t", errI)
@@ -116,7 +120,8 @@ func TestCleanByBytes(t *testing.T) {
 	t.Run("total log size is greater than cleaner.maxLogBytes", func(t *testing.T) {
 		t.Parallel()
 
-		maxLogBytes := uint64(10)
+		// a record of 1byte is recordHeaderSize+1 bytes once framed.
+		maxLogBytes := uint64(10 * (recordHeaderSize + 1))
 		cl, errI := newCleaner(maxLogBytes, 1)
 		if errI != nil {
 			t.Fatal("\n\t", errI)
@@ -152,7 +157,8 @@ func TestCleanByBytes(t *testing.T) {
 	t.Run("latest/active segment should be preserved", func(t *testing.T) {
 		t.Parallel()
 
-		maxLogBytes := uint64(10)
+		// room for two and a half framed records of 4bytes.
+		maxLogBytes := uint64(5 * (recordHeaderSize + 4) / 2)
 		cl, errI := newCleaner(maxLogBytes, 1)
 		if errI != nil {
 			t.Fatal("\n\t", errI)
@@ -399,3 +405,164 @@ func TestCleanByAge(t *testing.T) {
 		}
 	})
 }
+
+func TestCleanBySegmentAge(t *testing.T) {
+	t.Parallel()
+
+	// the segments were created every 100ns, & it is now 1000ns.
+	now := func() time.Time { return time.Unix(0, 1000) }
+	segsForTests := func(t *testing.T, n int) []*segment {
+		t.Helper()
+		segs := []*segment{}
+		for i := 0; i < n; i++ {
+			s, removePath := createSegmentForTests(t)
+			t.Cleanup(removePath)
+			s.baseOffset = uint64(i * 100)
+			s.clock = now
+			// the sum of their ages is way beyond maxLogAge; it does not matter.
+			s.age = 1_000
+			segs = append(segs, s)
+		}
+		return segs
+	}
+
+	t.Run("segments sealed more than maxLogAge ago are deleted", func(t *testing.T) {
+		t.Parallel()
+
+		cl, errI := newCleaner(1, time.Duration(350))
+		if errI != nil {
+			t.Fatal("\n\t", errI)
+		}
+		cl.ageRetention = AgeRetentionPerSegment
+
+		segs := segsForTests(t, 10)
+		cleanedSegs, errB := cl.cleanByAge(segs)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		// segment 500 was sealed, when segment 600 was created, 400ns ago; segment 600 only 300ns ago.
+		got := []uint64{}
+		for _, s := range cleanedSegs {
+			got = append(got, s.baseOffset)
+		}
+		if want := []uint64{600, 700, 800, 900}; !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+		for _, s := range segs[:6] {
+			if !s.isDeleted() {
+				t.Errorf("segment %d should have been deleted", s.baseOffset)
+			}
+		}
+	})
+
+	t.Run("latest/active segment should be preserved", func(t *testing.T) {
+		t.Parallel()
+
+		cl, errI := newCleaner(1, time.Duration(1))
+		if errI != nil {
+			t.Fatal("\n\t", errI)
+		}
+		cl.ageRetention = AgeRetentionPerSegment
+
+		segs := segsForTests(t, 3)
+		cleanedSegs, errB := cl.cleanByAge(segs)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(cleanedSegs) != 1 || cleanedSegs[0].baseOffset != 200 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", cleanedSegs, "only the active segment")
+		}
+	})
+}
+
+func TestCleanByCount(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no cap", func(t *testing.T) {
+		t.Parallel()
+
+		cl, errI := newCleaner(1, 1)
+		if errI != nil {
+			t.Fatal("\n\t", errI)
+		}
+
+		segs := []*segment{}
+		totalSegments := 5
+		for i := 0; i < totalSegments; i++ {
+			s, removePath := createSegmentForTests(t)
+			defer removePath()
+			segs = append(segs, s)
+		}
+
+		cleanedSegs, errB := cl.cleanByCount(segs)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(cleanedSegs) != totalSegments {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(cleanedSegs), totalSegments)
+		}
+	})
+
+	t.Run("oldest segments are dropped first", func(t *testing.T) {
+		t.Parallel()
+
+		cl, errI := newCleaner(1, 1)
+		if errI != nil {
+			t.Fatal("\n\t", errI)
+		}
+		cl.maxSegments = 3
+
+		segs := []*segment{}
+		totalSegments := 8
+		for i := 0; i < totalSegments; i++ {
+			s, removePath := createSegmentForTests(t)
+			defer removePath()
+			s.baseOffset = uint64(i)
+			segs = append(segs, s)
+		}
+
+		cleanedSegs, errB := cl.cleanByCount(segs)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(cleanedSegs) != 3 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(cleanedSegs), 3)
+		}
+		for i, want := range []uint64{5, 6, 7} {
+			if cleanedSegs[i].baseOffset != want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", cleanedSegs[i].baseOffset, want)
+			}
+		}
+		for _, s := range segs[:5] {
+			if !s.isDeleted() {
+				t.Errorf("segment %d should have been deleted", s.baseOffset)
+			}
+		}
+	})
+
+	t.Run("clog", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		l, err := New(path, 1, 1_000_000, time.Hour, WithMaxSegments(2))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 0; i < 5; i++ {
+			errA := l.Append([]byte("a"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		errB := l.Clean()
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(l.segments) != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segments), 2)
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package clog
+
+import "time"
+
+// clock tells the time; the baseOffsets of new segments, the ages of segments for retention & the times of journal events
+// are all taken from it. A nil clock tells the time of the operating system. see WithClock
+type clock func() time.Time
+
+// time returns the current time, in utc.
+func (c clock) time() time.Time {
+	if c == nil {
+		return time.Now().UTC()
+	}
+	return c().UTC()
+}
+
+// now returns the number of nanoseconds elapsed since January 1, 1970 UTC. see tNow
+func (c clock) now() uint64 {
+	if c == nil {
+		return tNow()
+	}
+	return uint64(c().UnixNano())
+}
+
+// age returns the number of nanoseconds elapsed since baseOffset.
+func (c clock) age(baseOffset uint64) uint64 {
+	now := c.now()
+	if baseOffset > now {
+		// The segment appears to have been created in the future. Is that you Einstein?
+		// Although it would be pleasing to Albert, we are not amused.
+		// Set age to 0; as if the segment has just been created.
+		//
+		// uint64(7) - uint64(12) == 18446744073709551611
+		// because of overflow. So we have to prevent that
+		return 0
+	}
+	return now - baseOffset
+}
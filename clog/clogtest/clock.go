@@ -0,0 +1,45 @@
+package clogtest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock, for use with clog.WithClock, that only moves when told to.
+// Each call to Now moves it forward by a step, so that segments created one after the other have distinct baseOffsets.
+//
+// To create a Clock, use the NewClock method.
+type Clock struct {
+	// mu protects now
+	mu   sync.Mutex
+	now  time.Time
+	step time.Duration
+}
+
+// NewClock returns a Clock that starts at start & moves forward by step each time it is read.
+//
+// usage:
+//
+//	clk := clogtest.NewClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)
+//	l, errN := clog.New("/orders", 100, 5, time.Hour*3, clog.WithClock(clk.Now))
+//	clk.Advance(4 * time.Hour) // the segments of l are now old enough to be cleaned.
+func NewClock(start time.Time, step time.Duration) *Clock {
+	return &Clock{now: start, step: step}
+}
+
+// Now returns the time of the clock, then moves it forward by its step.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := c.now
+	c.now = c.now.Add(c.step)
+	return t
+}
+
+// Advance moves the clock forward by d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
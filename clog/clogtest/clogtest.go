@@ -0,0 +1,290 @@
+// Package clogtest provides utilities for testing code that uses a clog commitlog.
+//
+// FaultyFS is a clog.FileSystem that injects realistic storage faults; short writes, failed fsyncs, delayed IO etc.
+// It allows users embedding a commitlog to test how their own code handles such failures.
+//
+// MemFS, an in-memory clog.FileSystem that can Crash, & Clock, a clock to pass to clog.WithClock, allow them to also
+// run deterministic replays of crash & recovery scenarios; a scripted schedule of faults is made with Rule.Nth
+package clogtest
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+)
+
+// ErrInjected is the error returned by operations into which a fault has been injected,
+// unless the Rule that injected the fault specifies a different error.
+var ErrInjected = errors.New("clogtest: injected fault")
+
+// Op is a filesystem operation into which faults can be injected.
+type Op uint8
+
+const (
+	// OpOpen is the opening of a file; FileSystem.OpenFile
+	OpOpen Op = iota
+	// OpRead is the reading of a file; FileSystem.ReadFile & File.Read
+	OpRead
+	// OpWrite is the writing to a file; File.Write
+	OpWrite
+	// OpSync is the syncing of a file; File.Sync
+	OpSync
+	// OpTruncate is the truncating of a file; File.Truncate
+	OpTruncate
+	// OpRemove is the removal of a file; FileSystem.Remove
+	OpRemove
+	// OpRename is the renaming of a file; FileSystem.Rename. Rules are matched against the old name.
+	OpRename
+)
+
+// Rule describes a fault and when it should be injected.
+type Rule struct {
+	// Op is the operation into which the fault is injected.
+	Op Op
+	// Pattern, if not empty, restricts the rule to files whose base name matches it. see filepath.Match
+	Pattern string
+	// Probability is the chance, in the range (0, 1], that the fault is injected into a matching operation.
+	// A Probability of 0 means that the fault is always injected.
+	Probability float64
+	// Nth, if not 0, scripts the fault; it is only injected into the Nth matching operation, counting from 1,
+	// after the rule was set. Probability is then ignored.
+	Nth int
+
+	// Delay is how long the operation is delayed by.
+	Delay time.Duration
+	// Err is the error returned by the operation. If it is nil, and neither Delay nor ShortWrite is set, ErrInjected is returned.
+	Err error
+	// ShortWrite makes an OpWrite only write half of the data before failing.
+	// The error returned is Err if set, otherwise io.ErrShortWrite.
+	ShortWrite bool
+}
+
+// FaultyFS is a clog.FileSystem that wraps another clog.FileSystem and injects faults into it according to rules.
+//
+// To create a FaultyFS, use the NewFaultyFS method.
+type FaultyFS struct {
+	fsys clog.FileSystem
+
+	// mu protects rules, matched, rand & injected
+	mu    sync.Mutex
+	rules []Rule
+	// matched is the number of operations that each of the rules has matched. see Rule.Nth
+	matched  []int
+	rand     *rand.Rand
+	injected uint64
+}
+
+// NewFaultyFS returns a FaultyFS that wraps fsys and injects faults into it according to rules.
+// The same seed always produces the same sequence of faults for the same sequence of operations.
+//
+// usage:
+//
+//	fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1, clogtest.Rule{Op: clogtest.OpSync, Probability: 0.1})
+//	l, errN := clog.New("/tmp/orders", 100, 5, time.Hour*3, clog.WithFileSystem(fsys))
+func NewFaultyFS(fsys clog.FileSystem, seed int64, rules ...Rule) *FaultyFS {
+	return &FaultyFS{
+		fsys:    fsys,
+		rules:   rules,
+		matched: make([]int, len(rules)),
+		// #nosec G404 -- faults do not need a cryptographically secure source of randomness.
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// SetRules replaces the rules of the FaultyFS. Calling it with no rules stops all fault injection.
+func (f *FaultyFS) SetRules(rules ...Rule) {
+	f.mu.Lock()
+	f.rules = rules
+	f.matched = make([]int, len(rules))
+	f.mu.Unlock()
+}
+
+// Injected returns the number of faults that have been injected so far.
+func (f *FaultyFS) Injected() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.injected
+}
+
+// fault returns the rule that should be applied to the operation op on the file name, if any.
+func (f *FaultyFS) fault(op Op, name string) *Rule {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, r := range f.rules {
+		if r.Op != op {
+			continue
+		}
+		if r.Pattern != "" {
+			matched, err := filepath.Match(r.Pattern, filepath.Base(name))
+			if err != nil || !matched {
+				continue
+			}
+		}
+		if r.Nth > 0 {
+			f.matched[i]++
+			if f.matched[i] != r.Nth {
+				continue
+			}
+		} else if r.Probability > 0 && f.rand.Float64() >= r.Probability {
+			continue
+		}
+
+		f.injected = f.injected + 1
+		r := r
+		return &r
+	}
+	return nil
+}
+
+// inject delays the operation and returns the error, if any, that it should fail with.
+func (r *Rule) inject() error {
+	if r.Delay > 0 {
+		time.Sleep(r.Delay)
+	}
+	if r.Err != nil {
+		return r.Err
+	}
+	if r.Delay > 0 || r.ShortWrite {
+		return nil
+	}
+	return ErrInjected
+}
+
+// MkdirAll implements clog.FileSystem
+func (f *FaultyFS) MkdirAll(path string, perm fs.FileMode) error {
+	return f.fsys.MkdirAll(path, perm)
+}
+
+// ReadDir implements clog.FileSystem
+func (f *FaultyFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return f.fsys.ReadDir(name)
+}
+
+// Stat implements clog.FileSystem
+func (f *FaultyFS) Stat(name string) (fs.FileInfo, error) {
+	return f.fsys.Stat(name)
+}
+
+// OpenFile implements clog.FileSystem
+func (f *FaultyFS) OpenFile(name string, flag int, perm fs.FileMode) (clog.File, error) {
+	r := f.fault(OpOpen, name)
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := f.fsys.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyFile{File: file, fs: f}, nil
+}
+
+// ReadFile implements clog.FileSystem
+func (f *FaultyFS) ReadFile(name string) ([]byte, error) {
+	r := f.fault(OpRead, name)
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.fsys.ReadFile(name)
+}
+
+// Remove implements clog.FileSystem
+func (f *FaultyFS) Remove(name string) error {
+	r := f.fault(OpRemove, name)
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return err
+		}
+	}
+	return f.fsys.Remove(name)
+}
+
+// Rename implements clog.FileSystem
+func (f *FaultyFS) Rename(oldpath, newpath string) error {
+	r := f.fault(OpRename, oldpath)
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return err
+		}
+	}
+	return f.fsys.Rename(oldpath, newpath)
+}
+
+// faultyFile is a clog.File into which a FaultyFS injects faults.
+type faultyFile struct {
+	clog.File
+	fs *FaultyFS
+}
+
+func (f *faultyFile) Read(p []byte) (int, error) {
+	r := f.fs.fault(OpRead, f.Name())
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return f.File.Read(p)
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	r := f.fs.fault(OpWrite, f.Name())
+	if r == nil {
+		return f.File.Write(p)
+	}
+
+	err := r.inject()
+	if !r.ShortWrite {
+		if err != nil {
+			return 0, err
+		}
+		return f.File.Write(p)
+	}
+
+	// write some of the data, like a disk that ran out of space half way through would.
+	n, errW := f.File.Write(p[:len(p)/2])
+	if errW != nil {
+		return n, errW
+	}
+	if err == nil {
+		return n, io.ErrShortWrite
+	}
+	return n, err
+}
+
+func (f *faultyFile) Sync() error {
+	r := f.fs.fault(OpSync, f.Name())
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return err
+		}
+	}
+	return f.File.Sync()
+}
+
+func (f *faultyFile) Truncate(size int64) error {
+	r := f.fs.fault(OpTruncate, f.Name())
+	if r != nil {
+		err := r.inject()
+		if err != nil {
+			return err
+		}
+	}
+	return f.File.Truncate(size)
+}
@@ -0,0 +1,244 @@
+package clogtest_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+	"github.com/komuw/shifta/clog/clogtest"
+)
+
+func createClogForTests(t *testing.T, fsys clog.FileSystem) (*clog.Clog, func()) {
+	path, err := ioutil.TempDir("/tmp", "clogtest")
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+
+	l, errA := clog.New(path, 100, 1_000_000, time.Hour, clog.WithFileSystem(fsys))
+	if errA != nil {
+		os.RemoveAll(path)
+		t.Fatal("\n\t", errA)
+	}
+	return l, func() { os.RemoveAll(path) }
+}
+
+func TestFaultyFS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no rules", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		err := l.Append([]byte("hello"))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if fsys.Injected() != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.Injected(), 0)
+		}
+	})
+
+	t.Run("failed fsync", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		fsys.SetRules(clogtest.Rule{Op: clogtest.OpSync})
+		err := l.Append([]byte("hello"))
+		if !errors.Is(err, clogtest.ErrInjected) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, clogtest.ErrInjected)
+		}
+		if fsys.Injected() != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.Injected(), 1)
+		}
+	})
+
+	t.Run("failed fsync of a segment being sealed", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		path, err := ioutil.TempDir("/tmp", "clogtest")
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer os.RemoveAll(path)
+		// each segment holds two records; the third append seals the segment of the first two.
+		l, errA := clog.New(path, 2*(5+8), 1_000_000, time.Hour, clog.WithFileSystem(fsys))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		fsys.SetRules(
+			// the sync of the first append is slow; so the second append waits for the sync after it.
+			clogtest.Rule{Op: clogtest.OpSync, Pattern: "1*.log", Nth: 1, Delay: 100 * time.Millisecond},
+			// the sync of the segment, as it is sealed by the third append, fails.
+			clogtest.Rule{Op: clogtest.OpSync, Pattern: "1*.log", Nth: 1},
+		)
+		first := l.AppendAsync([]byte("hello"))
+		time.Sleep(20 * time.Millisecond)
+		second := l.AppendAsync([]byte("hello"))
+		errB := l.Append([]byte("hello"))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		// the appends that were waiting for their syncs, when their segment failed to be sealed, fail; the second one
+		// would otherwise only have been synced by that seal.
+		for _, f := range []*clog.AppendFuture{first, second} {
+			if _, errC := f.Wait(); !errors.Is(errC, clogtest.ErrInjected) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, clogtest.ErrInjected)
+			}
+		}
+	})
+
+	t.Run("failed fsync of a segment sealed during an ingest", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		// the records fill more than one segment; the sync of the one that is sealed fails.
+		fsys.SetRules(clogtest.Rule{Op: clogtest.OpSync, Pattern: "1*.log", Nth: 1})
+		lines := strings.Repeat(strings.Repeat("a", 40)+"\n", 5)
+		_, err := l.AppendFrom(strings.NewReader(lines), nil)
+		if !errors.Is(err, clogtest.ErrInjected) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, clogtest.ErrInjected)
+		}
+	})
+
+	t.Run("short write leaves the log consistent", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		fsys.SetRules(clogtest.Rule{Op: clogtest.OpWrite, ShortWrite: true})
+		errB := l.Append([]byte("world"))
+		if !errors.Is(errB, io.ErrShortWrite) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, io.ErrShortWrite)
+		}
+
+		fsys.SetRules()
+		data, _, errC := l.Read(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		records, errD := clog.Records(data)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if len(records) != 1 || string(records[0]) != "hello" {
+			t.Errorf("\ngot \n\t%q \nwanted \n\t%q", records, []string{"hello"})
+		}
+	})
+
+	t.Run("custom error and pattern", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		errDisk := errors.New("disk on fire")
+		fsys.SetRules(
+			clogtest.Rule{Op: clogtest.OpRead, Pattern: "*.nope", Err: errDisk},
+		)
+		_, _, errA := l.Read(0, 0)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		fsys.SetRules(
+			clogtest.Rule{Op: clogtest.OpRead, Pattern: "*.log", Err: errDisk},
+		)
+		_, _, errB := l.Read(0, 0)
+		if !errors.Is(errB, errDisk) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, errDisk)
+		}
+	})
+
+	t.Run("delayed io", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		delay := 30 * time.Millisecond
+		fsys.SetRules(clogtest.Rule{Op: clogtest.OpSync, Delay: delay})
+		start := time.Now()
+		err := l.Append([]byte("hello"))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if time.Since(start) < delay {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", time.Since(start), delay)
+		}
+	})
+
+	t.Run("probability is deterministic for a seed", func(t *testing.T) {
+		t.Parallel()
+
+		run := func() []bool {
+			fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 42)
+			l, removePath := createClogForTests(t, fsys)
+			defer removePath()
+
+			fsys.SetRules(clogtest.Rule{Op: clogtest.OpSync, Probability: 0.5})
+			res := []bool{}
+			for i := 0; i < 20; i++ {
+				err := l.Append([]byte("hello"))
+				res = append(res, err == nil)
+			}
+			return res
+		}
+
+		a, b := run(), run()
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", a, b)
+			}
+		}
+	})
+
+	t.Run("nth scripts a fault", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, 1)
+		l, removePath := createClogForTests(t, fsys)
+		defer removePath()
+
+		fsys.SetRules(clogtest.Rule{Op: clogtest.OpSync, Nth: 3})
+		res := []bool{}
+		for i := 0; i < 5; i++ {
+			err := l.Append([]byte("hello"))
+			res = append(res, err == nil)
+		}
+		want := []bool{true, true, false, true, true}
+		for i := range want {
+			if res[i] != want[i] {
+				t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", res, want)
+			}
+		}
+		if fsys.Injected() != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.Injected(), 1)
+		}
+	})
+}
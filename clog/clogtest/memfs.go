@@ -0,0 +1,341 @@
+package clogtest
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+)
+
+// MemFS is an in-memory clog.FileSystem that models what survives a crash.
+//
+// Data written to a file is only durable once the file is synced; Crash drops all the data that has not been synced,
+// like a machine that lost power would. Creating, removing & renaming files, as well as creating directories,
+// are durable as soon as they return.
+// Together with a FaultyFS wrapping it & clog.WithClock, it allows applications to run deterministic replays of
+// crash & recovery scenarios that involve a commitlog, without touching the disk.
+//
+// To create a MemFS, use the NewMemFS method.
+type MemFS struct {
+	// mu protects all the fields of MemFS & of its nodes.
+	mu    sync.Mutex
+	dirs  map[string]bool
+	files map[string]*memNode
+	// crashes is the number of times Crash has been called. Files opened before a crash can no longer be used.
+	crashes uint64
+}
+
+// memNode is the content of a file in a MemFS.
+type memNode struct {
+	data   []byte
+	synced []byte
+}
+
+// NewMemFS returns an empty MemFS.
+//
+// usage:
+//
+//	fsys := clogtest.NewMemFS()
+//	l, errN := clog.New("/orders", 100, 5, time.Hour*3, clog.WithFileSystem(fsys))
+func NewMemFS() *MemFS {
+	return &MemFS{dirs: map[string]bool{"/": true, ".": true}, files: map[string]*memNode{}}
+}
+
+// Crash simulates a crash of the machine. The data of every file is reset to what it was when the file was last synced,
+// & files that were open before the crash return fs.ErrClosed from then on.
+// A commitlog that was using the MemFS should be abandoned; open a new one with clog.New to recover.
+func (m *MemFS) Crash() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, n := range m.files {
+		n.data = append([]byte(nil), n.synced...)
+	}
+	m.crashes++
+}
+
+// MkdirAll implements clog.FileSystem
+func (m *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for p := filepath.Clean(path); !m.dirs[p]; p = filepath.Dir(p) {
+		if _, ok := m.files[p]; ok {
+			return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+		}
+		m.dirs[p] = true
+	}
+	return nil
+}
+
+// ReadDir implements clog.FileSystem
+func (m *MemFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if !m.dirs[name] {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	entries := []fs.DirEntry{}
+	for d := range m.dirs {
+		if d != name && filepath.Dir(d) == name {
+			entries = append(entries, memInfo{name: filepath.Base(d), dir: true})
+		}
+	}
+	for f, n := range m.files {
+		if filepath.Dir(f) == name {
+			entries = append(entries, memInfo{name: filepath.Base(f), size: int64(len(n.data))})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// OpenFile implements clog.FileSystem
+// The flags os.O_CREATE, os.O_EXCL, os.O_TRUNC & os.O_APPEND are honoured; files are always opened for reading & writing.
+func (m *MemFS) OpenFile(name string, flag int, perm fs.FileMode) (clog.File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if m.dirs[name] {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	n, ok := m.files[name]
+	switch {
+	case ok && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case !ok && flag&os.O_CREATE == 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case !ok:
+		if !m.dirs[filepath.Dir(name)] {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+		n = &memNode{}
+		m.files[name] = n
+	}
+	if flag&os.O_TRUNC != 0 {
+		n.data = n.data[:0]
+	}
+	return &memFile{fs: m, node: n, name: name, append: flag&os.O_APPEND != 0, crashes: m.crashes}, nil
+}
+
+// Stat implements clog.FileSystem
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if m.dirs[name] {
+		return memInfo{name: filepath.Base(name), dir: true}, nil
+	}
+	n, ok := m.files[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	return memInfo{name: filepath.Base(name), size: int64(len(n.data))}, nil
+}
+
+// ReadFile implements clog.FileSystem
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n, ok := m.files[filepath.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), n.data...), nil
+}
+
+// Remove implements clog.FileSystem
+// Like os.Remove, it only removes directories that are empty.
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	if !m.dirs[name] {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	prefix := name + string(filepath.Separator)
+	for d := range m.dirs {
+		if strings.HasPrefix(d, prefix) {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+		}
+	}
+	for f := range m.files {
+		if strings.HasPrefix(f, prefix) {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+		}
+	}
+	delete(m.dirs, name)
+	return nil
+}
+
+// Rename implements clog.FileSystem
+// Only files can be renamed.
+func (m *MemFS) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	n, ok := m.files[oldpath]
+	if !ok {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if m.dirs[newpath] || !m.dirs[filepath.Dir(newpath)] {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrInvalid}
+	}
+	delete(m.files, oldpath)
+	m.files[newpath] = n
+	return nil
+}
+
+// memFile is a file, opened from a MemFS.
+type memFile struct {
+	fs      *MemFS
+	node    *memNode
+	name    string
+	append  bool
+	crashes uint64
+
+	// offset & closed are only used by the goroutine that uses the file; like an *os.File, a memFile is not safe
+	// for concurrent use.
+	offset int64
+	closed bool
+}
+
+// usable reports whether the file can still be used; it can not once it is closed, or the MemFS has crashed.
+// It should be called with f.fs.mu held.
+func (f *memFile) usable() bool {
+	return !f.closed && f.crashes == f.fs.crashes
+}
+
+func (f *memFile) Name() string { return f.name }
+
+func (f *memFile) Read(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if !f.usable() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.offset >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[f.offset:])
+	f.offset = f.offset + int64(n)
+	return n, nil
+}
+
+// ReadAt makes memFile an io.ReaderAt; like an *os.File.
+func (f *memFile) ReadAt(p []byte, off int64) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if !f.usable() {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrClosed}
+	}
+	if off < 0 {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if off >= int64(len(f.node.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, f.node.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if !f.usable() {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrClosed}
+	}
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	if end := f.offset + int64(len(p)); end > int64(len(f.node.data)) {
+		f.node.data = append(f.node.data, make([]byte, end-int64(len(f.node.data)))...)
+	}
+	n := copy(f.node.data[f.offset:], p)
+	f.offset = f.offset + int64(n)
+	return n, nil
+}
+
+func (f *memFile) Sync() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if !f.usable() {
+		return &fs.PathError{Op: "sync", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.node.synced = append(f.node.synced[:0], f.node.data...)
+	return nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if !f.usable() {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrClosed}
+	}
+	if size < 0 {
+		return &fs.PathError{Op: "truncate", Path: f.name, Err: fs.ErrInvalid}
+	}
+	if size <= int64(len(f.node.data)) {
+		f.node.data = f.node.data[:size]
+	} else {
+		f.node.data = append(f.node.data, make([]byte, size-int64(len(f.node.data)))...)
+	}
+	return nil
+}
+
+func (f *memFile) Close() error {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return &fs.PathError{Op: "close", Path: f.name, Err: fs.ErrClosed}
+	}
+	f.closed = true
+	return nil
+}
+
+// memInfo describes a file, or directory, in a MemFS. It is both an fs.FileInfo & an fs.DirEntry
+type memInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.dir }
+func (i memInfo) Sys() interface{}   { return nil }
+func (i memInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o740
+	}
+	return 0o640
+}
+func (i memInfo) Type() fs.FileMode          { return i.Mode().Type() }
+func (i memInfo) Info() (fs.FileInfo, error) { return i, nil }
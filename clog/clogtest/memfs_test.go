@@ -0,0 +1,127 @@
+package clogtest_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+	"github.com/komuw/shifta/clog/clogtest"
+)
+
+func TestMemFS(t *testing.T) {
+	t.Parallel()
+
+	t.Run("crash drops unsynced data", func(t *testing.T) {
+		t.Parallel()
+
+		fsys := clogtest.NewMemFS()
+		errA := fsys.MkdirAll("/data", 0o740)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		f, errB := fsys.OpenFile("/data/a.log", 0o102 /* O_RDWR|O_CREATE */, 0o640)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		_, _ = f.Write([]byte("hello"))
+		if errC := f.Sync(); errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		_, _ = f.Write([]byte("world"))
+
+		fsys.Crash()
+		b, errD := fsys.ReadFile("/data/a.log")
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if string(b) != "hello" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(b), "hello")
+		}
+		_, errE := f.Write([]byte("again"))
+		if !errors.Is(errE, fs.ErrClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errE, fs.ErrClosed)
+		}
+	})
+
+	t.Run("commitlog recovers after a crash", func(t *testing.T) {
+		t.Parallel()
+
+		mem := clogtest.NewMemFS()
+		fsys := clogtest.NewFaultyFS(mem, 1)
+		clk := clogtest.NewClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)
+		open := func() *clog.Clog {
+			l, err := clog.New("/orders", 100, 1_000_000, time.Hour, clog.WithFileSystem(fsys), clog.WithClock(clk.Now))
+			if err != nil {
+				t.Fatal("\n\t", err)
+			}
+			return l
+		}
+
+		l := open()
+		errA := l.Append([]byte("order # 1"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		// the fsync of the next append fails, so its data is lost in the crash.
+		fsys.SetRules(clogtest.Rule{Op: clogtest.OpSync, Pattern: "*.log", Nth: 1})
+		errB := l.Append([]byte("order # 2"))
+		if !errors.Is(errB, clogtest.ErrInjected) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, clogtest.ErrInjected)
+		}
+		mem.Crash()
+
+		l = open()
+		data, _, errC := l.Read(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		records, errD := clog.Records(data)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if len(records) != 1 || string(records[0]) != "order # 1" {
+			t.Errorf("\ngot \n\t%q \nwanted \n\t%q", records, []string{"order # 1"})
+		}
+	})
+
+	t.Run("clock decides retention", func(t *testing.T) {
+		t.Parallel()
+
+		clk := clogtest.NewClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)
+		l, err := clog.New("/orders", 20, 1_000_000, time.Hour, clog.WithFileSystem(clogtest.NewMemFS()), clog.WithClock(clk.Now))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 0; i < 3; i++ {
+			errA := l.Append([]byte("0123456789"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		// the active segment is aged by the next append to it.
+		clk.Advance(2 * time.Hour)
+		errB := l.Append([]byte("0123456789"))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		errC := l.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		data, _, errD := l.Read(0, 0)
+		if errD != nil && !errors.Is(errD, clog.ErrOffsetOutOfRange) {
+			t.Fatal("\n\t", errD)
+		}
+		// only the segment, of two records, that was aged by the clock is left.
+		records, errE := clog.Records(data)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		if len(records) != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 2)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// ErrClosed is returned by the operations on a commitlog that has been closed. see Clog.Close
+var ErrClosed = errors.New("commitLog is closed")
+
+var errSyncDir = func(err error) error { return fmt.Errorf("sync dir failed: %w", err) }
+
+// Close shuts the commitlog down cleanly; it syncs & closes the files of all its segments, & syncs the directories that
+// hold them, so that all the data appended so far is on stable storage. Operations on the commitlog after Close,
+// including a second Close, fail with ErrClosed; appends that are blocked on paused appends, & callers of WaitForOffset,
+// are woken up and fail with it too.
+//
+// Reads that are in progress when Close is called complete. Segments that were deleted while being read are removed
+// once those reads finish, as usual.
+//
+// If syncing a segment, or a directory, fails, Close still closes the rest of them; & returns the first error.
+// Close returns once the goroutines that the commitlog runs in the background, eg to poll for the appends of the writer
+// of a commitlog opened with OpenReadOnly, have exited.
+func (l *Clog) Close() (err error) {
+	defer func(start time.Time) { l.tracer.add("close", start, 0, err) }(time.Now())
+
+	err = l.close()
+	l.background.Wait()
+	return err
+}
+
+// close is like Close, except that it does not wait for the background goroutines to exit.
+func (l *Clog) close() (err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+	l.closed = true
+	if l.stop != nil {
+		close(l.stop)
+	}
+	// wake up those that wait on the commitlog, so that they find it closed.
+	l.notifyAppended()
+	if l.pause.paused {
+		l.pause.paused = false
+		close(l.pause.resumed)
+	}
+
+	segs := l.segmentRead()
+	for _, seg := range append(append([]*segment{}, segs...), l.pendingDeletes...) {
+		errC := seg.closeFile()
+		if errC != nil && err == nil {
+			err = errC
+		}
+	}
+	errD := l.syncDirs(segs)
+	if errD != nil && err == nil {
+		err = errD
+	}
+	l.journal.record(OpsClose, "segments=%d err=%v", len(segs), err)
+
+	return err
+}
+
+// checkOpen returns ErrClosed if the commitlog has been closed. The caller should hold l.mu
+func (l *Clog) checkOpen() error {
+	if l.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// syncDirs syncs the directory of the commitlog, & the directories that hold segs, to stable storage; if the
+// filesystem of the commitlog can sync directories. see dirSyncer
+// A commitlog opened with OpenReadOnly has nothing of its own to sync.
+func (l *Clog) syncDirs(segs []*segment) error {
+	ds, ok := l.fs.(dirSyncer)
+	if !ok || l.readOnlyOpen {
+		return nil
+	}
+	dirs := []string{l.path}
+	seen := map[string]bool{l.path: true}
+	for _, seg := range segs {
+		d := filepath.Dir(seg.filePath)
+		if !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	for _, d := range dirs {
+		err := ds.SyncDir(d)
+		if err != nil {
+			return errSyncDir(err)
+		}
+	}
+	return nil
+}
+
+// closeFile syncs & closes the file of the segment; unless it has already been closed, or removed.
+func (s *segment) closeFile() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+	return s.close()
+}
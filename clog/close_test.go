@@ -0,0 +1,107 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestClogClose(t *testing.T) {
+	t.Parallel()
+
+	t.Run("operations after close", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		errC := l.Close()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+
+		errA := l.Append([]byte("order # 3"))
+		if !errors.Is(errA, ErrClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, ErrClosed)
+		}
+		_, _, errR := l.Read(0, 0)
+		if !errors.Is(errR, ErrClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errR, ErrClosed)
+		}
+		errL := l.Clean()
+		if !errors.Is(errL, ErrClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errL, ErrClosed)
+		}
+		errC2 := l.Close()
+		if !errors.Is(errC2, ErrClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC2, ErrClosed)
+		}
+
+		// all the data appended before Close is there when the commitlog is opened again.
+		l2, errN := New(path, 10, 100_000, time.Hour)
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		defer l2.Close()
+		records, _, errR2 := l2.ReadRecords(0, 0)
+		if errR2 != nil {
+			t.Fatal("\n\t", errR2)
+		}
+		got := []string{}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		want := []string{"order # 1", "order # 2"}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("waiters are woken up", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour, WithPauseMode(PauseBlock))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errP := l.PauseAppends()
+		if errP != nil {
+			t.Fatal("\n\t", errP)
+		}
+
+		appendErr := make(chan error, 1)
+		go func() { appendErr <- l.Append([]byte("order # 1")) }()
+		waitErr := make(chan error, 1)
+		go func() { waitErr <- l.WaitForOffset(context.Background(), tNow()+uint64(time.Hour)) }()
+		time.Sleep(20 * time.Millisecond)
+
+		errC := l.Close()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		for _, ch := range []chan error{appendErr, waitErr} {
+			select {
+			case e := <-ch:
+				if !errors.Is(e, ErrClosed) {
+					t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", e, ErrClosed)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("waiter was not woken up by Close")
+			}
+		}
+	})
+}
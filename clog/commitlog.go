@@ -7,13 +7,12 @@
 package clog
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -39,9 +38,57 @@ const (
 	ownerReadableWritable fs.FileMode = 0o740
 )
 
+// ErrOffsetOutOfRange is matched, using errors.Is, by the *OutOfRangeError returned by Read.
+var ErrOffsetOutOfRange = errors.New("offset is out of range")
+
+// OutOfRangeError is returned by Read when the requested offset falls in a gap;
+// some of the data that came after the offset has since been deleted, eg by Clean.
+//
+// Rather than silently skipping the deleted data, the consumer can choose to either resume from LowWatermark,
+// which is the earliest offset from which reads have no gap, or to skip ahead to the latest data.
+//
+// It is also returned by ReadBeyond, with BeyondFail, when the requested offset is beyond the end of the commitlog;
+// Beyond is then set.
+type OutOfRangeError struct {
+	// Offset is the offset that was requested.
+	Offset uint64
+	// LowWatermark is the earliest offset that can be read from without a gap.
+	LowWatermark uint64
+	// Beyond is whether Offset is beyond the end of the commitlog, rather than in a gap.
+	// End is then the baseOffset of the latest segment; the largest offset that reads can be made from.
+	Beyond bool
+	End    uint64
+}
+
+func (e *OutOfRangeError) Error() string {
+	if e.Beyond {
+		return fmt.Sprintf("offset %d is out of range, it is beyond the latest segment %d", e.Offset, e.End)
+	}
+	return fmt.Sprintf("offset %d is out of range, data after it has been deleted. low watermark is %d", e.Offset, e.LowWatermark)
+}
+
+// Is allows an *OutOfRangeError to match ErrOffsetOutOfRange
+func (e *OutOfRangeError) Is(target error) bool {
+	return target == ErrOffsetOutOfRange
+}
+
+// OffsetResetPolicy decides where a consumer whose offset is out of range should resume reading from.
+// It is the equivalent of Kafka's auto.offset.reset
+type OffsetResetPolicy uint8
+
+const (
+	// ResetFail does not reset the offset; the *OutOfRangeError is returned to the consumer.
+	ResetFail OffsetResetPolicy = iota
+	// ResetEarliest resumes from the earliest data that is still available.
+	ResetEarliest
+	// ResetLatest skips all the data that is currently in the commitlog.
+	ResetLatest
+)
+
 var (
 	errNoActiveSegment   = errors.New("commitLog has no active segment")
 	errLogNotInitialized = errors.New("commitLog has not been initialized. use New method")
+	errBadRange          = errors.New("commitLog read range must have from < to")
 	errMkDir             = func(err error) error { return fmt.Errorf("mkdir failed: %w", err) }
 	errReadDir           = func(err error) error { return fmt.Errorf("read dir failed: %w", err) }
 	errParseToInt64      = func(err error) error { return fmt.Errorf("parse file to uint64 failed: %w", err) }
@@ -54,6 +101,27 @@ func tNow() uint64 {
 	return uint64(time.Now().In(time.UTC).UnixNano())
 }
 
+// CommitLog is the behaviour shared by commitlog implementations.
+// Code against it, rather than against a concrete implementation like Clog, to keep implementations interchangeable.
+type CommitLog interface {
+	// Path returns the directory, in the filesystem, of the commitlog.
+	Path() string
+	// Append adds an item to the commitlog.
+	Append(b []byte) error
+	// AppendBulk adds multiple items to the commitlog.
+	AppendBulk(bbs [][]byte) error
+	// Read reads upto maxToRead bytes from the commitlog starting at offset(exclusive).
+	Read(offset uint64, maxToRead uint64) (dataRead []byte, lastReadOffset uint64, err error)
+	// Clean deletes the segments that are beyond the commitlog's retention.
+	Clean() error
+	// Stats returns an overview of the state of the commitlog.
+	Stats() (Stats, error)
+	// Close shuts the commitlog down cleanly; operations after it fail.
+	Close() error
+}
+
+var _ CommitLog = (*Clog)(nil)
+
 // Clog is a commitLog.
 //
 // To create a commitlog, use the New method.
@@ -63,6 +131,80 @@ type Clog struct {
 
 	cl          *cleaner
 	maxSegBytes uint64
+	// sizer, if not nil, picks the maxSegBytes of new segments; instead of maxSegBytes. see WithAdaptiveSegmentSize
+	sizer *segmentSizer
+	// maxSegAge, if not 0, is the age at which the active segment is rolled, even if it is not full. see WithMaxSegmentAge
+	maxSegAge time.Duration
+	// fs is the filesystem in which the commitlog is stored. see WithFileSystem
+	fs FileSystem
+	// layout decides where, within path, segment files live. see WithLayout
+	layout Layout
+	// stripes are the directories, besides path, that new segments are spread across. see WithStripes
+	stripes []string
+	// knownStripes are all the directories, besides path, that may hold segments;
+	// stripes, and the ones recorded in the manifest by earlier opens of the commitlog.
+	knownStripes []string
+	// nextStripe is the index, in [path, stripes...], of the directory in which the next segment is created.
+	nextStripe int
+
+	// onCorruption is called whenever corruption is detected. see WithCorruptionHandler
+	onCorruption func(*CorruptionError)
+	// bgIO configures how background work accesses the disk. see WithBackgroundIO
+	bgIO backgroundIO
+	// budget caps the memory used by concurrent reads. see WithMemoryBudget
+	budget *MemoryBudget
+	// retentionExempt decides, from its tags, whether a segment is exempt from retention. see WithRetentionExempt
+	retentionExempt func(tags map[string]string) bool
+	// fsyncMon tracks fsyncs that stall. see WithFsyncStallHandler
+	fsyncMon *fsyncMonitor
+	// syncPolicy decides when appends are synced to stable storage. see WithSyncPolicy
+	syncPolicy SyncPolicy
+	// journal records the maintenance done on the commitlog. see ReadOpsJournal
+	journal *opsJournal
+	// guard puts the commitlog into read-only mode after repeated append failures. see WithReadOnlyAfter
+	guard *writeGuard
+	// tracer keeps the latest operations on the commitlog. see WithTrace
+	tracer *tracer
+	// tailCache is the size up to which the latest segments cache their data in memory. see WithTailCache
+	tailCache uint64
+	// clock tells the time. see WithClock
+	clock clock
+	// pause holds appends while they are paused. see PauseAppends
+	pause pauser
+	// growth warns when the commitlog has too many segments. see WithSegmentGrowthGuard
+	growth *growthGuard
+	// appendedMu protects appended; it is separate from mu so that waiting for appends only needs mu to be read locked.
+	appendedMu sync.Mutex
+	// appended, if not nil, is closed by the next append; callers of WaitForOffset wait on it.
+	appended chan struct{}
+	// pendingDeletes are the segments that have been deleted, but whose removal is pending on reads that use them.
+	// see PendingDeletion
+	pendingDeletes []*segment
+	// relocating is set while the files of the commitlog are copied to another filesystem. see Relocate
+	relocating bool
+	// appends is the number of records appended since the commitlog was opened. see Stats
+	appends uint64
+	// lastClean is when Clean last ran; the zero time if it has not run since the commitlog was opened. see Stats
+	lastClean time.Time
+	// closed is set once the commitlog has been closed. see Close
+	closed bool
+	// commits coalesces the syncs of concurrent appends. see groupCommit
+	commits *groupCommit
+	// readOnlyOpen is set if the commitlog was opened with OpenReadOnly; another process writes to it.
+	readOnlyOpen bool
+	// generation is the generation of the manifest that was last written; or, if readOnlyOpen, last loaded.
+	// see manifest.Generation
+	generation uint64
+	// refreshed is set once the segments of a commitlog opened with OpenReadOnly have been loaded. see Refresh
+	refreshed bool
+	// poll configures how often a commitlog opened with OpenReadOnly refreshes. see WithPollInterval
+	poll schedule
+	// autoClean configures how often the commitlog cleans itself. see WithCleanInterval
+	autoClean schedule
+	// stop is closed when the commitlog is closed; the goroutines it runs in the background then exit.
+	stop chan struct{}
+	// background tracks the goroutines that the commitlog runs in the background.
+	background sync.WaitGroup
 
 	// mu protects the []segment slice
 	// whenever a method of clog needs to read from clog.segments take a mu.RLock
@@ -71,6 +213,9 @@ type Clog struct {
 	// The latest segment is at the end of list
 	// ie; clog.segments[ len(clog.segments)-1 ] should give us the latest segment.
 	segments []*segment
+	// lowWatermark is the baseOffset of the latest segment that has been deleted since the commitlog was opened.
+	// Reads from offsets below it would skip deleted data.
+	lowWatermark uint64
 	// TODO: maybe the latest segment should be at index 0.
 	// This would make append easier, see cleaner.go
 }
@@ -84,12 +229,13 @@ type Clog struct {
 // When creating a commitlog, you should choose values of maxSegBytes, maxLogBytes & maxLogAge
 // that are appropriate for your usecase.
 // For comparison purposes, the Kafka default values for maxLogBytes & maxLogAge is 1GB and 7days respectively.
+// Optional behaviour of the commitlog can be configured by passing opts.
 //
 // usage:
 //   l, errN := New("/tmp/orders", 100, 5, time.Hour*3 )
 //   errA := l.Append([]byte("order # 1"))
 //
-func New(path string, maxSegBytes uint64, maxLogBytes uint64, maxLogAge time.Duration) (*Clog, error) {
+func New(path string, maxSegBytes uint64, maxLogBytes uint64, maxLogAge time.Duration, opts ...Option) (*Clog, error) {
 	// maxSegBytes is a property of segment.
 	//   It is size in bytes each segment can be, before been considered full & a new one created in its place.
 	// maxLogBytes is a property of clog.
@@ -106,7 +252,19 @@ func New(path string, maxSegBytes uint64, maxLogBytes uint64, maxLogAge time.Dur
 		cl:          c,
 		initialized: true,
 		maxSegBytes: maxSegBytes,
+		fs:          OSFileSystem{},
+		layout:      FlatLayout{},
+		commits:     newGroupCommit(),
+		stop:        make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if z := l.sizer; z != nil && (z.minBytes == 0 || z.minBytes > z.maxBytes || z.target <= 0) {
+		return nil, errBadSegmentSizer
+	}
+	l.journal = newOpsJournal(l.fs, l.path)
+	l.journal.clock = l.clock
 
 	errA := l.createPath()
 	if errA != nil {
@@ -117,18 +275,28 @@ func New(path string, maxSegBytes uint64, maxLogBytes uint64, maxLogAge time.Dur
 	if errB != nil {
 		return nil, errB
 	}
+	l.journal.record(OpsOpen, "maxSegBytes=%d maxLogBytes=%d maxLogAge=%s syncPolicy=%d segments=%d",
+		maxSegBytes, maxLogBytes, maxLogAge, l.syncPolicy, len(l.segments))
 
+	if l.autoClean.interval > 0 {
+		l.background.Add(1)
+		go l.cleanInBackground()
+	}
 	return l, nil
 }
 
 func (l *Clog) String() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return fmt.Sprintf("clog{path:%s, segments: %s}", l.path, l.segments)
 }
 
 func (l *Clog) createPath() error {
-	err := os.MkdirAll(l.path, ownerReadableWritable)
-	if err != nil {
-		return errMkDir(err)
+	for _, dir := range append([]string{l.path}, l.stripes...) {
+		err := l.fs.MkdirAll(dir, ownerReadableWritable)
+		if err != nil {
+			return errMkDir(err)
+		}
 	}
 	return nil
 }
@@ -138,36 +306,69 @@ func (l *Clog) open() error {
 		return errLogNotInitialized
 	}
 
-	files, err := os.ReadDir(l.path)
+	m, err := readManifest(l.fs, l.path)
 	if err != nil {
-		return errReadDir(err)
+		return err
 	}
+	if m.version() > FormatVersion {
+		return &FormatVersionError{Path: l.path, Version: m.version(), Supported: FormatVersion}
+	}
+	// a stripe that is no longer configured may still hold segments; so it is never forgotten.
+	l.knownStripes = unionStripes(m.Stripes, l.stripes)
+	l.generation = m.Generation
 
 	segs := []*segment{}
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == lFileSuffix {
-			// files are given names that have the timestamp in utc before the suffix, see tNow()
-			fNoExt := strings.TrimSuffix(file.Name(), lFileSuffix)
-			n, errA := strconv.ParseUint(fNoExt, 10, 64)
-			if errA != nil {
-				return errParseToInt64(errA)
+	for _, root := range append([]string{l.path}, l.knownStripes...) {
+		files, errA := l.listFiles(root, "")
+		if errA != nil {
+			return errA
+		}
+		for _, file := range files {
+			if root == l.path && isMetadataFile(file) {
+				continue
 			}
-			seg, errB := newSegment(l.path, n, l.maxSegBytes)
+			n, ok, errB := l.layout.ParseSegmentPath(file)
 			if errB != nil {
 				return errB
 			}
+			if !ok {
+				continue
+			}
+			seg, errC := l.createSegment(filepath.Join(root, file), n)
+			if errC != nil {
+				return errC
+			}
+			seg.tags = m.Segments[n].Tags
+			seg.firstSeq = m.Segments[n].FirstSequence
+			seg.seal = m.Segments[n].Seal
 			segs = append(segs, seg)
 		}
 	}
 
+	if m.version() < FormatVersion {
+		// the data of an older format can not be read as records. A commitlog that has no data can be upgraded, though.
+		for _, seg := range segs {
+			if seg.size() > 0 {
+				for _, s := range segs {
+					_ = s.close()
+				}
+				return &FormatVersionError{Path: l.path, Version: m.version(), Supported: FormatVersion}
+			}
+		}
+	}
+
+	derived := false
 	if len(segs) == 0 {
 		// the directory is empty. create a new file/segment
-		t := tNow()
-		seg, errC := newSegment(l.path, t, l.maxSegBytes)
-		if errC != nil {
-			return errC
+		t := l.clock.now()
+		seg, errD := l.createSegment(l.newSegmentPath(t), t)
+		if errD != nil {
+			return errD
 		}
+		seg.firstSeq = firstSequence
+		seg.startCache(l.tailCache)
 		l.segmentWrite([]*segment{seg}, nil)
+		derived = true
 	} else {
 		// sort: the latest segment should be at the end of list
 		sort.Slice(segs,
@@ -175,13 +376,118 @@ func (l *Clog) open() error {
 				return segs[i].baseOffset < segs[j].baseOffset
 			},
 		)
+		var errE error
+		derived, errE = deriveSequences(segs)
+		if errE != nil {
+			for _, s := range segs {
+				_ = s.close()
+			}
+			return errE
+		}
+		// the latest segment is the active one; it is appended to, so it is not sealed. eg if the segment that
+		// was created after it was lost.
+		segs[len(segs)-1].seal = nil
 		l.segmentWrite(segs, nil)
 	}
 
 	segs = nil // gc
+
+	if m.Version != FormatVersion || len(m.Stripes) != len(l.knownStripes) || derived {
+		// stamp the format version; so that other versions of this package know which format the commitlog is in.
+		// And record the stripes; so that their segments are found even if they are not configured the next time.
+		// And the sequence numbers of the segments that the manifest did not have.
+		return l.writeManifest()
+	}
 	return nil
 }
 
+// unionStripes returns the stripes in a, followed by those in b that are not in a.
+func unionStripes(a, b []string) []string {
+	u := append([]string{}, a...)
+	for _, s := range b {
+		found := false
+		for _, t := range a {
+			if filepath.Clean(s) == filepath.Clean(t) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			u = append(u, s)
+		}
+	}
+	return u
+}
+
+// createSegment opens, creating it if need be, the segment at filePath with the settings of the commitlog.
+func (l *Clog) createSegment(filePath string, baseOffset uint64) (*segment, error) {
+	seg, err := newSegment(l.fs, filePath, baseOffset, l.maxSegBytes)
+	if err != nil {
+		return nil, err
+	}
+	seg.fsyncMon = l.fsyncMon
+	seg.journal = l.journal
+	if l.clock != nil {
+		seg.clock = l.clock
+		seg.age = l.clock.age(baseOffset)
+	}
+	return seg, nil
+}
+
+// listFiles returns the paths, relative to root, of all the files in the directory dir and its subdirectories.
+// dir is itself relative to root
+func (l *Clog) listFiles(root string, dir string) ([]string, error) {
+	entries, err := l.fs.ReadDir(filepath.Join(root, dir))
+	if err != nil {
+		return nil, errReadDir(err)
+	}
+
+	files := []string{}
+	for _, e := range entries {
+		rel := filepath.Join(dir, e.Name())
+		if !e.IsDir() {
+			files = append(files, rel)
+			continue
+		}
+		sub, errA := l.listFiles(root, rel)
+		if errA != nil {
+			return nil, errA
+		}
+		files = append(files, sub...)
+	}
+	return files, nil
+}
+
+// newSegmentPath returns the path, in the filesystem, for a new segment with baseOffset.
+// It picks the directories of the commitlog, path and its stripes, in turn; so that segments are spread across them.
+// The caller should hold l.mu, if the commitlog is open.
+func (l *Clog) newSegmentPath(baseOffset uint64) string {
+	roots := append([]string{l.path}, l.stripes...)
+	root := roots[l.nextStripe%len(roots)]
+	l.nextStripe = (l.nextStripe + 1) % len(roots)
+	return filepath.Join(root, l.layout.SegmentPath(baseOffset))
+}
+
+// removeEmptyDirs removes the directory of the deleted segment seg, and its parents, if they are empty.
+// This prevents layouts that use subdirectories from leaving behind a growing number of empty directories.
+func (l *Clog) removeEmptyDirs(seg *segment) {
+	root := filepath.Clean(l.path)
+	for _, stripe := range l.knownStripes {
+		if strings.HasPrefix(seg.filePath, filepath.Clean(stripe)+string(filepath.Separator)) {
+			root = filepath.Clean(stripe)
+		}
+	}
+	dir := filepath.Dir(seg.filePath)
+	for strings.HasPrefix(dir, root) && dir != root {
+		err := l.fs.Remove(dir)
+		if err != nil {
+			// the directory is not empty.
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
 func (l *Clog) segmentWrite(segs []*segment, seg *segment) {
 	// all synchronizations should be in one method
 
@@ -202,6 +508,27 @@ func (l *Clog) segmentRead() []*segment {
 	return segs
 }
 
+// acquireAfter acquires, and returns, the segments in segs whose baseOffset is greater than offset.
+// Reads work on these, outside of l.mu, so that a concurrent Clean can neither block them nor delete data from under them.
+// The caller should hold l.mu and, once done, call releaseSegments.
+func acquireAfter(segs []*segment, offset uint64) []*segment {
+	acquired := []*segment{}
+	for _, seg := range segs {
+		if seg.baseOffset > offset && seg.acquire() {
+			acquired = append(acquired, seg)
+		}
+	}
+	return acquired
+}
+
+// releaseSegments releases segments acquired by acquireAfter, or otherwise acquired for a read.
+func releaseSegments(segs []*segment) {
+	for _, seg := range segs {
+		// we do not care about this error; as with Clean, a segment that could not be removed is left behind.
+		_ = seg.release()
+	}
+}
+
 func (l *Clog) activeSegment() (*segment, error) {
 	_len := len(l.segmentRead())
 	if _len <= 0 {
@@ -212,43 +539,163 @@ func (l *Clog) activeSegment() (*segment, error) {
 
 // Path returns the directory, in the filesystem, of the commitlog.
 func (l *Clog) Path() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.path
 }
 
-// Append adds an item to the commitLog.
-// To append more items at once use AppendBulk
+// Append adds an item to the commitLog, as a record. see Records
+// Whether the item is synced to stable storage before Append returns depends on the commitlog's SyncPolicy.
+// To append more items at once use AppendBulk. To get the offset of the item use AppendOffset
 func (l *Clog) Append(b []byte) error {
+	_, err := l.append(b, l.syncPolicy == SyncAlways)
+	return err
+}
+
+// AppendDurable adds an item to the commitLog and, regardless of the commitlog's SyncPolicy, syncs it to stable storage
+// before returning. It allows critical records to pay for durability while the rest of the records do not.
+// see WithSyncPolicy
+func (l *Clog) AppendDurable(b []byte) error {
+	_, err := l.append(b, true)
+	return err
+}
+
+func (l *Clog) append(b []byte, sync bool) (offset RecordOffset, err error) {
+	defer func(start time.Time) { l.tracer.add("append", start, len(b), err) }(time.Now())
+
+	return l.appendBulk(context.Background(), [][]byte{b}, nil, sync)
+}
+
+// AppendBulk adds multiple items to the commitLog, in order, as records. see Records
+// The items are written to the active segment all at once, with a single write that is synced once; rather than
+// paying for a write, & an fsync, per item. Either all of them are appended, or none is.
+// Whether they are synced to stable storage before AppendBulk returns depends on the commitlog's SyncPolicy.
+// To append one item at a time use Append. To get the offsets of the items use AppendBulkOffsets
+func (l *Clog) AppendBulk(bbs [][]byte) error {
+	_, err := l.appendBulkTraced(bbs)
+	return err
+}
+
+// appendBulkTraced is appendBulk, traced as an AppendBulk.
+func (l *Clog) appendBulkTraced(bbs [][]byte) (first RecordOffset, err error) {
+	size := 0
+	for _, b := range bbs {
+		size = size + len(b)
+	}
+	defer func(start time.Time) { l.tracer.add("append-bulk", start, size, err) }(time.Now())
+
+	return l.appendBulk(context.Background(), bbs, nil, l.syncPolicy == SyncAlways)
+}
+
+// appendBulk adds items to the commitlog and returns the offset of the first one; the rest follow it in the same segment.
+// If sync is false, they are left for the operating system to flush. Otherwise they are synced once they have been
+// written, & l.mu released; the sync is shared with the appends that are made concurrently. see groupCommit
+// ctx bounds how long it waits for appends to be resumed, if they are paused. see WithPauseMode
+// sums, if not nil, are the checksums that the items are framed with. see AppendChecksummed
+func (l *Clog) appendBulk(ctx context.Context, bbs [][]byte, sums []uint32, sync bool) (first RecordOffset, err error) {
+	first, ticket, err := l.writeBulk(ctx, bbs, sums, sync)
+	if err != nil || !sync {
+		return first, err
+	}
+
+	errS := l.commit(ticket, first.BaseOffset)
+	if errS != nil {
+		return RecordOffset{}, errS
+	}
+	return first, nil
+}
+
+// commit returns once the write with ticket, to the segment with baseOffset, has been synced; & lets the write guard
+// observe the outcome of the append. see groupCommit
+// The sync that covers the write may have been of a later segment; if the segment was sealed meanwhile, the write is
+// only durable if the segment was synced when it was sealed.
+func (l *Clog) commit(ticket uint64, baseOffset uint64) error {
+	err := l.commits.wait(ticket, l.syncActive)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err == nil {
+		if seg := l.segmentByBaseOffset(baseOffset); seg != nil {
+			err = seg.sealError()
+		}
+	}
+	l.observeAppend(err)
+	return err
+}
+
+// writeBulk writes items to the commitlog, without syncing them, and returns the offset of the first one & the ticket of
+// the write. see groupCommit
+// The outcome of the append is left for the caller to observe if it is to be synced, & the write succeeded.
+func (l *Clog) writeBulk(ctx context.Context, bbs [][]byte, sums []uint32, sync bool) (first RecordOffset, ticket uint64, err error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
 	if !l.initialized {
-		return errLogNotInitialized
+		return RecordOffset{}, 0, errLogNotInitialized
+	}
+	if errP := l.waitResumed(ctx); errP != nil {
+		return RecordOffset{}, 0, errP
+	}
+	if errC := l.checkOpen(); errC != nil {
+		return RecordOffset{}, 0, errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return RecordOffset{}, 0, errC
+	}
+	if l.guard != nil && l.guard.readOnly {
+		return RecordOffset{}, 0, ErrReadOnly
+	}
+	if l.relocating {
+		return RecordOffset{}, 0, ErrRelocating
 	}
 
+	first, err = l.appendActive(bbs, sums, false)
+	if err == nil {
+		ticket = l.commits.wrote()
+		l.notifyAppended()
+	}
+	if err != nil || !sync {
+		l.observeAppend(err)
+	}
+	return first, ticket, err
+}
+
+// observeAppend lets the write guard observe the outcome of an append, putting the commitlog into read-only mode
+// if need be. The caller should hold l.mu
+func (l *Clog) observeAppend(err error) {
+	if l.guard.observe(err) {
+		l.journal.record(OpsReadOnly, "read-only after %d consecutive append failures, last error: %v", l.guard.failures, err)
+		if l.guard.onReadOnly != nil {
+			l.guard.onReadOnly(ReadOnlyEvent{Path: l.path, Failures: l.guard.failures, LastErr: err, At: l.clock.time()})
+		}
+	}
+}
+
+// appendActive adds items to the active segment, splitting first if need be. It returns the offset of the first item.
+// sums, if not nil, are the checksums that the items are framed with.
+// The caller should hold l.mu
+func (l *Clog) appendActive(bbs [][]byte, sums []uint32, sync bool) (RecordOffset, error) {
 	if l.toSplit() {
 		err := l.split()
 		if err != nil {
-			return err
+			return RecordOffset{}, err
 		}
 	}
 
 	a, errA := l.activeSegment()
 	if errA != nil {
-		return errA
+		return RecordOffset{}, errA
 	}
-	return a.Append(b)
-}
-
-// AppendBulk adds multiple items to the commitLog.
-// To append one item at a time use Append
-func (l *Clog) AppendBulk(bbs [][]byte) error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if !l.initialized {
-		return errLogNotInitialized
+	// the sequence numbers of the items follow the records already in the segment; which thus have to be known.
+	_, errB := a.recordCount()
+	if errB != nil {
+		return RecordOffset{}, errB
+	}
+	pos, seq, errC := a.appendBulk(bbs, sums, sync)
+	if errC != nil {
+		return RecordOffset{}, errC
 	}
-	return errors.New("TODO: implement appendBulk")
+	l.appends = l.appends + uint64(len(bbs))
+	return RecordOffset{BaseOffset: a.baseOffset, Position: pos, Sequence: seq}, nil
 }
 
 func (l *Clog) toSplit() bool {
@@ -257,7 +704,16 @@ func (l *Clog) toSplit() bool {
 		// we have no active segment, we thus need to create one
 		return true
 	}
-	return a.IsFull()
+	return a.IsFull() || l.segmentExpired(a)
+}
+
+// segmentExpired reports whether the active segment, a, holds data & is older than maxSegAge; it is then rolled, so that
+// the segments of a commitlog with few appends still become old enough to be cleaned. see WithMaxSegmentAge
+func (l *Clog) segmentExpired(a *segment) bool {
+	if l.maxSegAge <= 0 || a.size() == 0 {
+		return false
+	}
+	return l.clock.age(a.baseOffset) >= uint64(l.maxSegAge.Nanoseconds())
 }
 
 func (l *Clog) split() error {
@@ -270,21 +726,48 @@ func (l *Clog) split() error {
 	// we do not care if l.activeSegment() has an error.
 	// we just want the active segment before we split and form a new active seg.
 
-	t := tNow()
-	seg, errA := newSegment(l.path, t, l.maxSegBytes)
+	firstSeq := uint64(firstSequence)
+	if earlierActive != nil {
+		n, errC := earlierActive.recordCount()
+		if errC != nil {
+			return errC
+		}
+		firstSeq = earlierActive.firstSeq + n
+	}
+
+	t := l.clock.now()
+	seg, errA := l.createSegment(l.newSegmentPath(t), t)
 	if errA != nil {
 		return errA
 	}
+	seg.firstSeq = firstSeq
+	seg.maxSegBytes = l.nextSegmentSize(earlierActive)
+	seg.startCache(l.tailCache)
+	if segs := l.segmentRead(); len(segs) >= 2 {
+		// only the active segment, & the one sealed just now, are part of the hot tail.
+		segs[len(segs)-2].dropCache()
+	}
 
 	// TODO: do we need to maintain all the segments in a list or just the active one?
 	// maybe we do for fast reads??
 	l.segmentWrite(l.segmentRead(), seg)
+	if earlierActive != nil {
+		l.journal.record(OpsSplit, "sealed segment %d, created segment %d", earlierActive.baseOffset, seg.baseOffset)
+	} else {
+		l.journal.record(OpsSplit, "created segment %d", seg.baseOffset)
+	}
 
 	if earlierActive != nil {
-		// we do not care about this error.
-		// because the log now has a new active segment
-		_ = earlierActive.close()
+		earlierActive.sealData()
+		// the log now has a new active segment; so this error does not fail the split. It fails the appends that are
+		// waiting for their writes to the segment to be synced, instead. see commit
+		if errC := earlierActive.close(); errC != nil {
+			earlierActive.failSeal(errC)
+		}
 	}
+	// we do not care about this error either; open derives the sequence numbers of segments that the manifest lacks.
+	_ = l.writeManifest()
+	l.checkGrowth()
 	return nil
 }
 
@@ -292,19 +775,119 @@ func (l *Clog) split() error {
 // (a) larger than maxLogBytes
 // and/or
 // (b) older than maxLogAge
-func (l *Clog) Clean() error {
+func (l *Clog) Clean() (err error) {
+	defer func(start time.Time) { l.tracer.add("clean", start, 0, err) }(time.Now())
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	cleaned, err := l.cl.clean(l.segments)
+	if errC := l.checkOpen(); errC != nil {
+		return errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return errC
+	}
+	if l.relocating {
+		return ErrRelocating
+	}
+	return l.clean()
+}
+
+// clean is like Clean. The caller should hold l.mu
+func (l *Clog) clean() error {
+	l.lastClean = l.clock.time()
+	before := l.segments
+	exempt, candidates := l.splitExempt(before)
+	cleaned, err := l.cl.clean(candidates)
 	if err != nil {
 		return err
 	}
-	l.segments = cleaned
+	if len(exempt) == 0 {
+		l.segments = cleaned
+	} else {
+		l.segments = append(exempt, cleaned...)
+		// the latest segment should be at the end of list
+		sort.Slice(l.segments,
+			func(i, j int) bool {
+				return l.segments[i].baseOffset < l.segments[j].baseOffset
+			},
+		)
+	}
+
+	deleted := []uint64{}
+	for _, seg := range before {
+		if seg.isDeleted() {
+			deleted = append(deleted, seg.baseOffset)
+			if _, _, ok := seg.pendingDeletion(); ok {
+				l.pendingDeletes = append(l.pendingDeletes, seg)
+			}
+			l.removeEmptyDirs(seg)
+			if seg.baseOffset > l.lowWatermark {
+				l.lowWatermark = seg.baseOffset
+			}
+		}
+	}
+	if len(deleted) > 0 {
+		l.journal.record(OpsClean, "deleted segments %v", deleted)
+		// do not leave behind metadata of segments that no longer exist; & let readers in other processes know that
+		// they are gone. see OpenReadOnly
+		return l.writeManifest()
+	}
 
 	return nil
 }
 
+// splitExempt separates the segments that are exempt from retention from those that are not.
+// The active segment is never exempt; the cleaner always retains it anyway.
+func (l *Clog) splitExempt(segs []*segment) (exempt []*segment, candidates []*segment) {
+	if l.retentionExempt == nil || len(segs) == 0 {
+		return nil, segs
+	}
+
+	for _, seg := range segs[:len(segs)-1] {
+		if l.retentionExempt(seg.tagsCopy()) {
+			exempt = append(exempt, seg)
+		} else {
+			candidates = append(candidates, seg)
+		}
+	}
+	return exempt, append(candidates, segs[len(segs)-1])
+}
+
+// ResolveOffset returns the offset from which a consumer, that wants to read from offset, should read.
+// If offset is in range, it is returned as is. Otherwise policy decides the offset to resume from;
+// with ResetFail, the *OutOfRangeError that Read would have returned is returned.
+//
+// usage:
+//   data, lastReadOffset, err := l.Read(offset, 0)
+//   if errors.Is(err, ErrOffsetOutOfRange) {
+//       offset, err = l.ResolveOffset(offset, ResetEarliest)
+//   }
+//
+func (l *Clog) ResolveOffset(offset uint64, policy OffsetResetPolicy) (uint64, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if offset == 0 || offset >= l.lowWatermark {
+		return offset, nil
+	}
+
+	switch policy {
+	case ResetEarliest:
+		return l.lowWatermark, nil
+	case ResetLatest:
+		// Reads exclude the offset they are given, and whole segments are read at a time.
+		// So resuming from the active segment's baseOffset only reads segments that are created from now on.
+		a, err := l.activeSegment()
+		if err != nil {
+			return offset, err
+		}
+		return a.baseOffset, nil
+	default:
+		return offset, &OutOfRangeError{Offset: offset, LowWatermark: l.lowWatermark}
+	}
+}
+
 const internalMaxToRead = (64 * 1000 * 1000) // 64Mb
 
 // Read reads upto maxToRead bytes from the commitlog starting at offset(exclusive).
@@ -312,11 +895,51 @@ const internalMaxToRead = (64 * 1000 * 1000) // 64Mb
 // The value of maxToRead should be significantly smaller than RAM.
 // If maxToRead == 0 then a default value will be chosen.
 //
+// Reading from an offset of 0 always reads from the earliest data available.
+// Reading from any other offset that some deleted data came after returns an *OutOfRangeError, rather than
+// silently skipping the deleted data. Note that only segments deleted since the commitlog was opened are detected.
+//
+// If the commitlog has a memory budget(see WithMemoryBudget), Read blocks until the budget has room for at least one segment.
+// Further segments are only read while the budget has room for them; otherwise less data than maxToRead is returned.
+//
+// Read sees the commitlog as it was when it started; a snapshot of its segments.
+// A concurrent Clean neither blocks it nor fails it: segments that Clean deletes are still read in full,
+// and are only removed from the filesystem once no read is using them.
+// Segments created after Read started are not read; Read again from lastReadOffset to get them.
+//
+// The data read is the records of the segments read, framed; use Records to split it into the records that were appended.
+//
 // If it encounters an error, it will still return all the data read so far,
 // its offset and an error.
 func (l *Clog) Read(offset uint64, maxToRead uint64) (dataRead []byte, lastReadOffset uint64, err error) {
+	defer func(start time.Time) { l.tracer.add("read", start, len(dataRead), err) }(time.Now())
+
+	dataRead, spans, err := l.read(context.Background(), offset, maxToRead)
+	if len(spans) > 0 {
+		lastReadOffset = spans[len(spans)-1].BaseOffset
+	}
+	return dataRead, lastReadOffset, err
+}
+
+// read is Read; but rather than the lastReadOffset, it returns the spans of dataRead that each segment contributed.
+// It stops, between segments, once ctx is done; returning the data read so far and ctx.Err().
+func (l *Clog) read(ctx context.Context, offset uint64, maxToRead uint64) (dataRead []byte, spans []ReadSpan, err error) {
 	l.mu.RLock()
-	defer l.mu.RUnlock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, nil, errC
+	}
+	if offset != 0 && offset < l.lowWatermark {
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, nil, &OutOfRangeError{Offset: offset, LowWatermark: lw}
+	}
+	segs := acquireAfter(l.segmentRead(), offset)
+	l.mu.RUnlock()
+	defer releaseSegments(segs)
+
+	var reserved uint64
+	defer func() { l.budget.release(reserved) }()
 
 	var max int = int(maxToRead)
 	if max <= 0 {
@@ -328,28 +951,299 @@ func (l *Clog) Read(offset uint64, maxToRead uint64) (dataRead []byte, lastReadO
 	}
 
 	var sizeReadSofar int
-	for _, seg := range l.segments {
-		if seg.baseOffset > offset {
-			// We exclude the offset from reads.
-			// This allows people to use lastReadOffset in subsequent calls to l.Read
-			b, errR := seg.Read()
-			if errR != nil {
-				return dataRead, lastReadOffset, errR
-				// TODO: test that if error occurs, we still return whatever has been read so far.
+	// We exclude the offset from reads; acquireAfter only returns segments after it.
+	// This allows people to use lastReadOffset in subsequent calls to l.Read
+	for _, seg := range segs {
+		if errC := ctx.Err(); errC != nil {
+			return dataRead, spans, errC
+		}
+		segSize := seg.size()
+		if reserved == 0 {
+			// make sure that we can always make progress.
+			r, errA := l.budget.acquire(ctx, segSize)
+			if errA != nil {
+				return dataRead, spans, errA
 			}
-			dataRead = append(dataRead, b...)
-			lastReadOffset = seg.baseOffset
-			sizeReadSofar = sizeReadSofar + len(b)
+			reserved = r
+		} else if l.budget.tryAcquire(segSize) {
+			reserved = reserved + segSize
+		} else {
+			// the budget is exhausted, trim the read.
+			break
+		}
 
-			if sizeReadSofar >= max {
-				break
-			}
+		b, errR := seg.Read()
+		if errR != nil {
+			l.reportCorruption(errR)
+			return dataRead, spans, errR
+			// TODO: test that if error occurs, we still return whatever has been read so far.
+		}
+		spans = append(spans, ReadSpan{
+			BaseOffset:    seg.baseOffset,
+			FirstSequence: seg.firstSeq,
+			Start:         uint64(len(dataRead)),
+			End:           uint64(len(dataRead) + len(b)),
+		})
+		dataRead = append(dataRead, b...)
+		sizeReadSofar = sizeReadSofar + len(b)
+
+		if sizeReadSofar >= max {
+			break
 		}
 	}
 
 	// clog reads the whole data from a segment, even if the individual segment
 	// has data greater than maxToRead.
-	// Thus, the returned lastReadOffset is safe to be used in subsequent l.Read calls
-	// since the segment it belongs to wont be read again.
+	// Thus, the baseOffset of the last span, which Read returns as lastReadOffset, is safe to be used in subsequent
+	// l.Read calls since the segment it belongs to wont be read again.
+	return dataRead, spans, nil
+}
+
+// ReadAligned reads whole sealed segments from the commitlog, starting at offset(exclusive),
+// such that the data read is as close to targetSize as possible without going over it.
+// It is meant for shipping the commitlog to object storage in parts of about targetSize each, eg; S3 multipart uploads.
+//
+// Since each part starts and ends at segment boundaries, parts can later be re-imported losslessly.
+// Like in Read, the data read is framed records; so each part can also be split into records. see Records
+// Unlike Read, the active segment is never read, because it may still be appended to; parts are thus immutable.
+// If a single segment is larger than targetSize, it is read on its own; so that progress is always made.
+// If targetSize == 0 then a default value will be chosen.
+// A nil dataRead, with a nil error, means that there are no sealed segments after offset.
+//
+// usage:
+//
+//	part, lastReadOffset, err := l.ReadAligned(offset, 8*1024*1024)
+//	upload(part)
+//	offset = lastReadOffset
+//
+// Like Read, it sees a snapshot of the segments as they were when it started.
+func (l *Clog) ReadAligned(offset uint64, targetSize uint64) (dataRead []byte, lastReadOffset uint64, err error) {
+	defer func(start time.Time) { l.tracer.add("read-aligned", start, len(dataRead), err) }(time.Now())
+
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, 0, errC
+	}
+	if offset != 0 && offset < l.lowWatermark {
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, 0, &OutOfRangeError{Offset: offset, LowWatermark: lw}
+	}
+	all := l.segmentRead()
+	if len(all) == 0 {
+		l.mu.RUnlock()
+		return nil, 0, nil
+	}
+	// the latest segment is the active one.
+	sealed := acquireAfter(all[:len(all)-1], offset)
+	l.mu.RUnlock()
+	defer releaseSegments(sealed)
+
+	target := targetSize
+	if target == 0 {
+		target = internalMaxToRead
+	} else if target > (internalMaxToRead * 10) {
+		// prevent OOM. see Read
+		target = internalMaxToRead * 10
+	}
+
+	var reserved uint64
+	defer func() { l.budget.release(reserved) }()
+
+	var sizeReadSofar uint64
+	// We exclude the offset from reads. see Read
+	for _, seg := range sealed {
+		segSize := seg.size()
+		if dataRead != nil && sizeReadSofar+segSize > target {
+			// the next segment would take us past targetSize.
+			break
+		}
+		if reserved == 0 {
+			// make sure that we can always make progress.
+			// acquire only fails once its ctx is done.
+			reserved, _ = l.budget.acquire(context.Background(), segSize)
+		} else if l.budget.tryAcquire(segSize) {
+			reserved = reserved + segSize
+		} else {
+			// the budget is exhausted, trim the part.
+			break
+		}
+
+		b, errR := seg.Read()
+		if errR != nil {
+			l.reportCorruption(errR)
+			return dataRead, lastReadOffset, errR
+		}
+		if dataRead == nil {
+			dataRead = []byte{}
+		}
+		dataRead = append(dataRead, b...)
+		lastReadOffset = seg.baseOffset
+		sizeReadSofar = sizeReadSofar + uint64(len(b))
+	}
+
 	return dataRead, lastReadOffset, nil
 }
+
+// ReadRange reads the data of the segments whose baseOffset is within [from, to); from is inclusive and to is exclusive.
+// It lets exporters & debuggers extract a precise window of the commitlog instead of over-fetching and trimming.
+// Whole segments are read; like in Read, the data read is framed records. see Records
+// The records of a segment all share its baseOffset, so offsets can not tell them apart; to read a window of individual
+// records, use ReadRecordRange.
+//
+// Unlike Read, from is inclusive; a from of 0 reads from the earliest data available.
+// If some data within the range has been deleted, an *OutOfRangeError is returned.
+// A nil dataRead, with a nil error, means that there are no segments within the range.
+//
+// If the commitlog has a memory budget(see WithMemoryBudget), the read may be trimmed; as in Read.
+// To carry on from a trimmed read, call ReadRange(lastReadOffset+1, to).
+// Like Read, it sees a snapshot of the segments as they were when it started.
+//
+// usage:
+//
+//	window, _, err := l.ReadRange(from, to)
+func (l *Clog) ReadRange(from, to uint64) (dataRead []byte, lastReadOffset uint64, err error) {
+	defer func(start time.Time) { l.tracer.add("read-range", start, len(dataRead), err) }(time.Now())
+
+	if from >= to {
+		return nil, 0, errBadRange
+	}
+
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, 0, errC
+	}
+	if from != 0 && l.lowWatermark != 0 && from <= l.lowWatermark {
+		// the segment at lowWatermark has itself been deleted; from is inclusive.
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, 0, &OutOfRangeError{Offset: from, LowWatermark: lw}
+	}
+	segs := []*segment{}
+	for _, seg := range l.segmentRead() {
+		if seg.baseOffset >= from && seg.baseOffset < to && seg.acquire() {
+			segs = append(segs, seg)
+		}
+	}
+	l.mu.RUnlock()
+	defer releaseSegments(segs)
+
+	var reserved uint64
+	defer func() { l.budget.release(reserved) }()
+
+	for _, seg := range segs {
+		segSize := seg.size()
+		if reserved == 0 {
+			// make sure that we can always make progress.
+			// acquire only fails once its ctx is done.
+			reserved, _ = l.budget.acquire(context.Background(), segSize)
+		} else if l.budget.tryAcquire(segSize) {
+			reserved = reserved + segSize
+		} else {
+			// the budget is exhausted, trim the read.
+			break
+		}
+
+		b, errR := seg.Read()
+		if errR != nil {
+			l.reportCorruption(errR)
+			return dataRead, lastReadOffset, errR
+		}
+		if dataRead == nil {
+			dataRead = []byte{}
+		}
+		dataRead = append(dataRead, b...)
+		lastReadOffset = seg.baseOffset
+	}
+
+	return dataRead, lastReadOffset, nil
+}
+
+// ReadRecordRange reads the records whose sequence numbers are within [from, to); from is inclusive and to is
+// exclusive. see RecordOffset
+// Unlike ReadRange, which reads whole segments, it splits the segments that straddle the range along the framing of their
+// records; & returns only the records within the range, in order, alongside their offsets.
+//
+// A from of 0 reads from the earliest record available.
+// If some records within the range have been deleted, an *OutOfRangeError, whose Offset is from, is returned.
+// No records, with a nil error, means that there are no records within the range.
+//
+// If the commitlog has a memory budget(see WithMemoryBudget), the read may be trimmed; as in Read.
+// To carry on from a trimmed read, call ReadRecordRange(offsets[len(offsets)-1].Sequence+1, to).
+// Like Read, it sees a snapshot of the segments as they were when it started.
+//
+// usage:
+//
+//	records, offsets, err := l.ReadRecordRange(from, to)
+func (l *Clog) ReadRecordRange(from, to uint64) (records [][]byte, offsets []RecordOffset, err error) {
+	size := 0
+	defer func(start time.Time) { l.tracer.add("read-record-range", start, size, err) }(time.Now())
+
+	if from >= to {
+		return nil, nil, errBadRange
+	}
+
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, nil, errC
+	}
+	all := l.segmentRead()
+	if from != 0 && l.lowWatermark != 0 && len(all) > 0 && from < all[0].firstSeq {
+		// the records before the earliest segment have been deleted.
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, nil, &OutOfRangeError{Offset: from, LowWatermark: lw}
+	}
+	segs := []*segment{}
+	for i, seg := range all {
+		// the records of a segment are those up to the first record of the segment after it.
+		if seg.firstSeq >= to || (i+1 < len(all) && all[i+1].firstSeq <= from) {
+			continue
+		}
+		if seg.acquire() {
+			segs = append(segs, seg)
+		}
+	}
+	l.mu.RUnlock()
+	defer releaseSegments(segs)
+
+	var reserved uint64
+	defer func() { l.budget.release(reserved) }()
+
+	records, offsets = [][]byte{}, []RecordOffset{}
+	for _, seg := range segs {
+		segSize := seg.size()
+		if reserved == 0 {
+			// make sure that we can always make progress.
+			// acquire only fails once its ctx is done.
+			reserved, _ = l.budget.acquire(context.Background(), segSize)
+		} else if l.budget.tryAcquire(segSize) {
+			reserved = reserved + segSize
+		} else {
+			// the budget is exhausted, trim the read.
+			break
+		}
+
+		b, errR := seg.Read()
+		if errR != nil {
+			l.reportCorruption(errR)
+			return records, offsets, errR
+		}
+		size = size + len(b)
+		rs, offs, errS := spanRecords(b, ReadSpan{BaseOffset: seg.baseOffset, FirstSequence: seg.firstSeq, End: uint64(len(b))})
+		for i, o := range offs {
+			if o.Sequence >= from && o.Sequence < to {
+				records = append(records, rs[i])
+				offsets = append(offsets, o)
+			}
+		}
+		if errS != nil {
+			return records, offsets, errS
+		}
+	}
+
+	return records, offsets, nil
+}
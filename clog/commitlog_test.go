@@ -1,6 +1,7 @@
 package clog
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -23,6 +24,16 @@ func createPathForTests(t *testing.T) (string, func()) {
 	return path, func() { os.RemoveAll(path) }
 }
 
+// recordsForTests returns the records in data, as read from a commitlog, joined back together.
+func recordsForTests(t *testing.T, data []byte) string {
+	t.Helper()
+	records, err := Records(data)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	return string(bytes.Join(records, nil))
+}
+
 type createLogConfig struct {
 	maxSegBytes uint64
 	maxLogBytes uint64
@@ -55,7 +66,7 @@ func TestCreatePath(t *testing.T) {
 	path := "/tmp/TestClogTestCreatePath"
 	defer os.RemoveAll(path)
 
-	l := &Clog{path: path}
+	l := &Clog{path: path, fs: OSFileSystem{}, layout: FlatLayout{}}
 
 	err := l.createPath()
 	if err != nil {
@@ -74,7 +85,7 @@ func TestOpen(t *testing.T) {
 			t.Fatal("\n\t", errI)
 		}
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path, initialized: true, cl: cl}
+		l := &Clog{path: path, initialized: true, cl: cl, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		err := l.open()
@@ -96,7 +107,7 @@ func TestOpen(t *testing.T) {
 			t.Fatal("\n\t", errI)
 		}
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path, initialized: true, cl: cl}
+		l := &Clog{path: path, initialized: true, cl: cl, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		segs := l.segmentRead()
@@ -130,7 +141,7 @@ func TestOpen(t *testing.T) {
 			t.Fatal("\n\t", errI)
 		}
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path, initialized: true, cl: cl}
+		l := &Clog{path: path, initialized: true, cl: cl, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		segs := l.segmentRead()
@@ -139,8 +150,12 @@ func TestOpen(t *testing.T) {
 		}
 
 		// create other log files in l.path directory
-		// and write to them
-		msg := []byte("Hope springs eternal in the human breast.")
+		// and write to them, in the current format.
+		errM := (&manifest{Version: FormatVersion}).write(l.fs, l.path)
+		if errM != nil {
+			t.Fatal("\n\t", errM)
+		}
+		msg := encodeRecord([]byte("Hope springs eternal in the human breast."))
 		for i := 100; i < 109; i++ {
 			f, err := os.Create(filepath.Join(l.path, fmt.Sprintf("%d.log", i)))
 			if err != nil {
@@ -180,7 +195,7 @@ func TestOpen(t *testing.T) {
 			t.Fatal("\n\t", errI)
 		}
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path, initialized: true, cl: cl}
+		l := &Clog{path: path, initialized: true, cl: cl, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		segs := l.segmentRead()
@@ -215,7 +230,7 @@ func TestOpen(t *testing.T) {
 			t.Fatal("\n\t", errI)
 		}
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path, initialized: true, cl: cl}
+		l := &Clog{path: path, initialized: true, cl: cl, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		segs := l.segmentRead()
@@ -224,8 +239,12 @@ func TestOpen(t *testing.T) {
 		}
 
 		// create other log files in l.path directory
-		// and write to them
-		msg := []byte("Hope springs eternal in the human breast.")
+		// and write to them, in the current format.
+		errM := (&manifest{Version: FormatVersion}).write(l.fs, l.path)
+		if errM != nil {
+			t.Fatal("\n\t", errM)
+		}
+		msg := encodeRecord([]byte("Hope springs eternal in the human breast."))
 		for i := 100; i < 109; i++ {
 			f, err := os.Create(filepath.Join(l.path, fmt.Sprintf("%d.log", i)))
 			if err != nil {
@@ -321,7 +340,7 @@ func TestLogAppend(t *testing.T) {
 		t.Parallel()
 
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path}
+		l := &Clog{path: path, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		msg := []byte("hello")
@@ -412,6 +431,45 @@ func TestLogAppend(t *testing.T) {
 	})
 }
 
+func TestLogAppendBulk(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 10, 10_000, time.Hour, WithTrace(10))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+
+	// the items all go to the active segment; even though they do not fit in it.
+	errA := l.AppendBulk([][]byte{[]byte("order # 1"), []byte("order # 2"), []byte("order # 3")})
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	if len(l.segmentRead()) != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segmentRead()), 1)
+	}
+	// the next append goes to a new segment.
+	errB := l.AppendBulk([][]byte{[]byte("order # 4")})
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	if len(l.segmentRead()) != 2 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segmentRead()), 2)
+	}
+
+	data, _, errC := l.Read(0, 0)
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	if recordsForTests(t, data) != "order # 1order # 2order # 3order # 4" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, data), "order # 1order # 2order # 3order # 4")
+	}
+	if e := l.Trace()[0]; e.Op != "append-bulk" || e.Bytes != 27 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", e, "an append-bulk of 27 bytes")
+	}
+}
+
 func TestLogSplit(t *testing.T) {
 	t.Parallel()
 
@@ -419,7 +477,7 @@ func TestLogSplit(t *testing.T) {
 		t.Parallel()
 
 		path, removePath := createPathForTests(t)
-		l := &Clog{path: path}
+		l := &Clog{path: path, fs: OSFileSystem{}, layout: FlatLayout{}}
 		defer removePath()
 
 		if l.toSplit() == false {
@@ -634,8 +692,8 @@ func TestLogRead(t *testing.T) {
 		if lastReadOffset != l.segments[0].baseOffset {
 			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, l.segments[0].baseOffset)
 		}
-		if string(blob) != oneMsg {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(blob), oneMsg)
+		if recordsForTests(t, blob) != oneMsg {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, blob), oneMsg)
 		}
 	})
 
@@ -665,14 +723,15 @@ func TestLogRead(t *testing.T) {
 		if lastReadOffset != l.segments[22].baseOffset {
 			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, l.segments[22].baseOffset)
 		}
-		if len(blob) != 16100 {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(blob), 16100)
+		data := recordsForTests(t, blob)
+		if len(data) != 16100 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(data), 16100)
 		}
-		if !cmp.Equal(blob[0], blob[22]) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(blob[0]), string(blob[22]))
+		if !cmp.Equal(data[0], data[22]) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(data[0]), string(data[22]))
 		}
-		if !cmp.Equal(blob[0], msg[0]) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", blob[0], msg[0])
+		if !cmp.Equal(data[0], msg[0]) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", data[0], msg[0])
 		}
 	})
 
@@ -703,14 +762,15 @@ func TestLogRead(t *testing.T) {
 		if lastReadOffset != l.segments[22].baseOffset {
 			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, l.segments[22].baseOffset)
 		}
-		if len(blob) != 6300 {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(blob), 6300)
+		data := recordsForTests(t, blob)
+		if len(data) != 6300 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(data), 6300)
 		}
-		if !cmp.Equal(blob[0], blob[8]) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(blob[0]), string(blob[8]))
+		if !cmp.Equal(data[0], data[8]) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(data[0]), string(data[8]))
 		}
-		if !cmp.Equal(blob[0], msg[0]) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", blob[0], msg[0])
+		if !cmp.Equal(data[0], msg[0]) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", data[0], msg[0])
 		}
 
 		b, lo, errC := l.Read(lastReadOffset, 0)
@@ -774,8 +834,8 @@ func TestLogRead(t *testing.T) {
 		if errB != nil {
 			t.Fatal("\n\t", errB)
 		}
-		if len(blob) != internalMaxToRead*2 {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(blob), internalMaxToRead*3)
+		if got := recordsForTests(t, blob); len(got) != internalMaxToRead*2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), internalMaxToRead*2)
 		}
 	})
 
@@ -893,7 +953,8 @@ func TestCommitLogRaceDetection(t *testing.T) {
 				defer wg.Done()
 				for j := 0; j < 6; j++ {
 					_, _, errF := l.Read(3, 0)
-					if errF != nil {
+					if errF != nil && !errors.Is(errF, ErrOffsetOutOfRange) {
+						// offset 3 is below the low watermark once Clean has deleted some segments.
 						panic(errF)
 					}
 				}
@@ -902,4 +963,650 @@ func TestCommitLogRaceDetection(t *testing.T) {
 
 		wg.Wait()
 	})
+
+	t.Run("reads see a snapshot while clean runs", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t, createLogConfig{
+			maxSegBytes: uint64(50),
+			maxLogBytes: uint64(200),
+			maxLogAge:   time.Hour,
+		})
+		defer removePath()
+		msg := []byte(strings.Repeat("r", 50)) // each record fills a segment.
+		wg := sync.WaitGroup{}
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					errA := l.Append(msg)
+					if errA != nil {
+						panic(errA)
+					}
+					errB := l.Clean()
+					if errB != nil {
+						panic(errB)
+					}
+				}
+			}()
+		}
+
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					b, _, errC := l.Read(0, 0)
+					if errC != nil {
+						// segments deleted by Clean, mid-read, are still read in full.
+						panic(errC)
+					}
+					records, errR := Records(b)
+					if errR != nil {
+						panic(errR)
+					}
+					for _, r := range records {
+						if len(r) != len(msg) {
+							panic(fmt.Sprintf("read a record of %d bytes; not a whole record of %d bytes", len(r), len(msg)))
+						}
+					}
+					_, _, errD := l.ReadAligned(0, 0)
+					if errD != nil {
+						panic(errD)
+					}
+				}
+			}()
+		}
+
+		wg.Wait()
+	})
+}
+
+func TestLogReadDuringClean(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 100, 250, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	msg := []byte(strings.Repeat("a", 200))
+	for i := 0; i < 5; i++ {
+		errA := l.Append(msg)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	// take the snapshot that a read, which has started but not finished, holds.
+	l.mu.RLock()
+	snapshot := acquireAfter(l.segmentRead(), 0)
+	l.mu.RUnlock()
+
+	errB := l.Clean()
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	if len(l.segmentRead()) >= len(snapshot) {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segmentRead()), "fewer segments after Clean")
+	}
+
+	deleted := []*segment{}
+	for _, seg := range snapshot {
+		if !seg.isDeleted() {
+			continue
+		}
+		deleted = append(deleted, seg)
+		// the segment is deleted as far as the commitlog is concerned, but the read can still finish.
+		b, errC := seg.Read()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if recordsForTests(t, b) != string(msg) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, b), string(msg))
+		}
+		if seg.acquire() {
+			t.Error("a deleted segment should not be acquired by new reads")
+		}
+	}
+	if len(deleted) == 0 {
+		t.Fatal("Clean did not delete any of the segments")
+	}
+
+	releaseSegments(snapshot)
+	for _, seg := range deleted {
+		_, errD := os.Stat(seg.filePath)
+		if !errors.Is(errD, os.ErrNotExist) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, os.ErrNotExist)
+		}
+	}
+}
+
+func TestLogReadOffsetGap(t *testing.T) {
+	t.Parallel()
+
+	createCleanedClog := func(t *testing.T) (*Clog, []uint64, func()) {
+		path, removePath := createPathForTests(t)
+		l, err := New(path, 100, 250, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		msg := []byte(strings.Repeat("a", 200))
+		for i := 0; i < 5; i++ {
+			errA := l.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		bases := []uint64{}
+		for _, seg := range l.segments {
+			bases = append(bases, seg.baseOffset)
+		}
+
+		errB := l.Clean()
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(l.segments) != 2 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segments), 2)
+		}
+		return l, bases, removePath
+	}
+
+	t.Run("reading from a gap errors", func(t *testing.T) {
+		t.Parallel()
+
+		l, bases, removePath := createCleanedClog(t)
+		defer removePath()
+
+		// the consumer had only read the first segment; the next two have since been deleted.
+		_, _, err := l.Read(bases[0], 0)
+		if !errors.Is(err, ErrOffsetOutOfRange) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, ErrOffsetOutOfRange)
+		}
+		var oe *OutOfRangeError
+		if !errors.As(err, &oe) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, &OutOfRangeError{})
+		}
+		if oe.Offset != bases[0] {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", oe.Offset, bases[0])
+		}
+		if oe.LowWatermark != bases[2] {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", oe.LowWatermark, bases[2])
+		}
+
+		// resuming from the low watermark reads the earliest data available.
+		data, lastReadOffset, errA := l.Read(oe.LowWatermark, 0)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if got := recordsForTests(t, data); len(got) != 400 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 400)
+		}
+		if lastReadOffset != bases[4] {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, bases[4])
+		}
+	})
+
+	t.Run("no gap", func(t *testing.T) {
+		t.Parallel()
+
+		l, bases, removePath := createCleanedClog(t)
+		defer removePath()
+
+		// offset 0 always reads from the earliest data.
+		data, _, err := l.Read(0, 0)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if got := recordsForTests(t, data); len(got) != 400 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 400)
+		}
+
+		// the consumer had read all the segments that have since been deleted.
+		dataA, _, errA := l.Read(bases[2], 0)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if got := recordsForTests(t, dataA); len(got) != 400 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 400)
+		}
+	})
+}
+
+func TestResolveOffset(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 100, 250, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+
+	msg := []byte(strings.Repeat("a", 200))
+	for i := 0; i < 5; i++ {
+		errA := l.Append(msg)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	bases := []uint64{}
+	for _, seg := range l.segments {
+		bases = append(bases, seg.baseOffset)
+	}
+	errB := l.Clean()
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+
+	tt := []struct {
+		name    string
+		offset  uint64
+		policy  OffsetResetPolicy
+		want    uint64
+		wantErr error
+	}{
+		{name: "in range offsets are not reset", offset: bases[3], policy: ResetEarliest, want: bases[3]},
+		{name: "offset 0 is in range", offset: 0, policy: ResetLatest, want: 0},
+		{name: "earliest", offset: bases[0], policy: ResetEarliest, want: bases[2]},
+		{name: "latest", offset: bases[0], policy: ResetLatest, want: bases[4]},
+		{name: "fail", offset: bases[0], policy: ResetFail, want: bases[0], wantErr: ErrOffsetOutOfRange},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, errC := l.ResolveOffset(v.offset, v.policy)
+			if !errors.Is(errC, v.wantErr) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, v.wantErr)
+			}
+			if got != v.want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestLogReadAligned(t *testing.T) {
+	t.Parallel()
+
+	createFullClog := func(t *testing.T) (*Clog, func()) {
+		path, removePath := createPathForTests(t)
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// each append fills a segment; so we end up with 5 sealed segments and an active one.
+		for i := 0; i < 6; i++ {
+			errA := l.Append([]byte(strings.Repeat(fmt.Sprint(i), 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		if len(l.segments) != 6 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segments), 6)
+		}
+		return l, removePath
+	}
+
+	t.Run("parts are aligned to segments", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createFullClog(t)
+		defer removePath()
+
+		all := []byte{}
+		sizes := []int{}
+		offset := uint64(0)
+		for {
+			part, lastReadOffset, err := l.ReadAligned(offset, 250)
+			if err != nil {
+				t.Fatal("\n\t", err)
+			}
+			if part == nil {
+				break
+			}
+			all = append(all, part...)
+			sizes = append(sizes, len(part))
+			offset = lastReadOffset
+		}
+
+		// each segment holds a record of 100 bytes, that is framed.
+		size := 100 + recordHeaderSize
+		if !cmp.Equal(sizes, []int{2 * size, 2 * size, size}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", sizes, []int{2 * size, 2 * size, size})
+		}
+		want := ""
+		for i := 0; i < 5; i++ {
+			// the active segment, with the 6th append, is never read.
+			want = want + strings.Repeat(fmt.Sprint(i), 100)
+		}
+		if got := recordsForTests(t, all); got != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("segments larger than targetSize", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createFullClog(t)
+		defer removePath()
+
+		part, lastReadOffset, err := l.ReadAligned(0, 10)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if got := recordsForTests(t, part); len(got) != 100 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 100)
+		}
+		if lastReadOffset != l.segments[0].baseOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, l.segments[0].baseOffset)
+		}
+	})
+
+	t.Run("no sealed segments", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t, createLogConfig{maxSegBytes: 100, maxLogBytes: 10_000, maxLogAge: time.Hour})
+		defer removePath()
+
+		part, lastReadOffset, err := l.ReadAligned(0, 0)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if part != nil || lastReadOffset != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", part, nil)
+		}
+	})
+}
+
+func TestLogReadRange(t *testing.T) {
+	t.Parallel()
+
+	createFullClog := func(t *testing.T) (*Clog, []uint64, func()) {
+		path, removePath := createPathForTests(t)
+		l, err := New(path, 100, 250, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// each append fills a segment.
+		for i := 0; i < 5; i++ {
+			errA := l.Append([]byte(strings.Repeat(fmt.Sprint(i), 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		bases := []uint64{}
+		for _, seg := range l.segments {
+			bases = append(bases, seg.baseOffset)
+		}
+		return l, bases, removePath
+	}
+
+	t.Run("reads only the segments within the range", func(t *testing.T) {
+		t.Parallel()
+
+		l, bases, removePath := createFullClog(t)
+		defer removePath()
+
+		tt := []struct {
+			name     string
+			from, to uint64
+			want     string
+			wantLast uint64
+		}{
+			{name: "middle", from: bases[1], to: bases[3], want: strings.Repeat("1", 100) + strings.Repeat("2", 100), wantLast: bases[2]},
+			{name: "from is inclusive", from: bases[4], to: bases[4] + 1, want: strings.Repeat("4", 100), wantLast: bases[4]},
+			{name: "from earliest", from: 0, to: bases[1], want: strings.Repeat("0", 100), wantLast: bases[0]},
+			{name: "between segments", from: bases[1] + 1, to: bases[2], want: "", wantLast: 0},
+		}
+		for _, v := range tt {
+			got, last, err := l.ReadRange(v.from, v.to)
+			if err != nil {
+				t.Fatal(v.name, "\n\t", err)
+			}
+			if recordsForTests(t, got) != v.want {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, recordsForTests(t, got), v.want)
+			}
+			if last != v.wantLast {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, last, v.wantLast)
+			}
+		}
+	})
+
+	t.Run("reads only the records within the range", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// each segment holds two records.
+		l, err := New(path, 20, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 1; i <= 6; i++ {
+			errA := l.Append([]byte(fmt.Sprintf("order # %d", i)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		tt := []struct {
+			name     string
+			from, to uint64
+			want     []string
+		}{
+			{name: "straddles segments", from: 2, to: 5, want: []string{"order # 2", "order # 3", "order # 4"}},
+			{name: "within a segment", from: 3, to: 4, want: []string{"order # 3"}},
+			{name: "from earliest", from: 0, to: 2, want: []string{"order # 1"}},
+			{name: "beyond the end", from: 7, to: 10, want: []string{}},
+		}
+		for _, v := range tt {
+			records, offsets, errR := l.ReadRecordRange(v.from, v.to)
+			if errR != nil {
+				t.Fatal(v.name, "\n\t", errR)
+			}
+			got := []string{}
+			for i, r := range records {
+				got = append(got, string(r))
+				if want := fmt.Sprintf("order # %d", offsets[i].Sequence); string(r) != want {
+					t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, string(r), want)
+				}
+			}
+			if !cmp.Equal(got, v.want) {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, got, v.want)
+			}
+		}
+
+		_, _, errB := l.ReadRecordRange(3, 3)
+		if !errors.Is(errB, errBadRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, errBadRange)
+		}
+	})
+
+	t.Run("bad range", func(t *testing.T) {
+		t.Parallel()
+
+		l, bases, removePath := createFullClog(t)
+		defer removePath()
+
+		_, _, err := l.ReadRange(bases[2], bases[2])
+		if !errors.Is(err, errBadRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadRange)
+		}
+	})
+
+	t.Run("range with deleted data", func(t *testing.T) {
+		t.Parallel()
+
+		l, bases, removePath := createFullClog(t)
+		defer removePath()
+		errA := l.Clean()
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		_, _, err := l.ReadRange(bases[0], bases[4])
+		if !errors.Is(err, ErrOffsetOutOfRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, ErrOffsetOutOfRange)
+		}
+		// ranges that start at, or after, the earliest remaining segment are fine.
+		got, _, errB := l.ReadRange(bases[3], bases[4]+1)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		want := strings.Repeat("3", 100) + strings.Repeat("4", 100)
+		if recordsForTests(t, got) != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, got), want)
+		}
+
+		// as are records.
+		_, _, errC := l.ReadRecordRange(1, 5)
+		if !errors.Is(errC, ErrOffsetOutOfRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, ErrOffsetOutOfRange)
+		}
+		records, _, errD := l.ReadRecordRange(4, 6)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if len(records) != 2 || string(records[0]) != strings.Repeat("3", 100) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 2)
+		}
+	})
+}
+
+func TestStripes(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	stripeA, stripeB := filepath.Join(path, "..", filepath.Base(path)+"-a"), filepath.Join(path, "..", filepath.Base(path)+"-b")
+	defer os.RemoveAll(stripeA)
+	defer os.RemoveAll(stripeB)
+
+	l, err := New(path, 100, 10_000, time.Hour, WithStripes(stripeA, stripeB))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	want := ""
+	// each append fills a segment.
+	for i := 0; i < 6; i++ {
+		msg := strings.Repeat(fmt.Sprint(i), 100)
+		want = want + msg
+		errA := l.Append([]byte(msg))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	// segments are spread across the directories in turn.
+	roots := []string{path, stripeA, stripeB}
+	for i, seg := range l.segmentRead() {
+		if filepath.Dir(seg.filePath) != roots[i%3] {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", filepath.Dir(seg.filePath), roots[i%3])
+		}
+	}
+
+	// the global order is reassembled from all the directories; even when a stripe is no longer configured.
+	for _, opts := range [][]Option{{WithStripes(stripeA, stripeB)}, {WithStripes(stripeB)}, nil} {
+		l2, errB := New(path, 100, 10_000, time.Hour, opts...)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		got, _, errC := l2.Read(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if recordsForTests(t, got) != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, got), want)
+		}
+	}
+
+	// cleaning deletes segments from all the directories.
+	l3, errD := New(path, 100, 150, time.Hour, WithStripes(stripeA, stripeB))
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	errE := l3.Clean()
+	if errE != nil {
+		t.Fatal("\n\t", errE)
+	}
+	segFiles := 0
+	for _, dir := range roots {
+		files, errF := l3.listFiles(dir, "")
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+		for _, f := range files {
+			if _, ok, _ := l3.layout.ParseSegmentPath(f); ok {
+				segFiles++
+			}
+		}
+	}
+	if segFiles != len(l3.segmentRead()) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", segFiles, len(l3.segmentRead()))
+	}
+}
+
+func TestWithMaxSegmentAge(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	mu := sync.Mutex{}
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	l, err := New(path, 1_000_000, 100_000_000, 24*time.Hour, WithClock(clk), WithMaxSegmentAge(time.Hour))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer l.Close()
+
+	segments := func() int {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return len(l.segments)
+	}
+	for i := 0; i < 3; i++ {
+		errA := l.Append([]byte("order # 1"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	if got := segments(); got != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 1)
+	}
+
+	// the first append after the active segment expires rolls it.
+	advance(time.Hour)
+	errA := l.Append([]byte("order # 2"))
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	if got := segments(); got != 2 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 2)
+	}
+	records, _, errR := l.ReadRecords(0, 0)
+	if errR != nil {
+		t.Fatal("\n\t", errR)
+	}
+	if len(records) != 4 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 4)
+	}
 }
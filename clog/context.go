@@ -0,0 +1,61 @@
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// AppendCtx is Append, but it honors the cancellation & deadline of ctx.
+// It fails with ctx.Err() if ctx is done before the item is appended; eg while it is blocked waiting for paused appends
+// to be resumed(see WithPauseMode). Once the item is being written to the active segment, the write runs to completion.
+func (l *Clog) AppendCtx(ctx context.Context, b []byte) (err error) {
+	defer func(start time.Time) { l.tracer.add("append", start, len(b), err) }(time.Now())
+
+	if errC := ctx.Err(); errC != nil {
+		return errC
+	}
+	_, err = l.appendBulk(ctx, [][]byte{b}, nil, l.syncPolicy == SyncAlways)
+	return err
+}
+
+// ReadCtx is Read, but it honors the cancellation & deadline of ctx.
+// ctx is checked before each segment is read; so a long read across many segments is aborted midway, returning the
+// data read so far, the lastReadOffset of that data & ctx.Err(). As with Read, a subsequent read can carry on from the
+// lastReadOffset. A read that is blocked waiting for its memory budget(see WithMemoryBudget) gives up once ctx is done.
+func (l *Clog) ReadCtx(ctx context.Context, offset uint64, maxToRead uint64) (dataRead []byte, lastReadOffset uint64, err error) {
+	defer func(start time.Time) { l.tracer.add("read", start, len(dataRead), err) }(time.Now())
+
+	dataRead, spans, err := l.read(ctx, offset, maxToRead)
+	if len(spans) > 0 {
+		lastReadOffset = spans[len(spans)-1].BaseOffset
+	}
+	return dataRead, lastReadOffset, err
+}
+
+// CleanCtx is Clean, but it honors the cancellation & deadline of ctx.
+// It fails with ctx.Err() if ctx is done before it starts deleting segments; eg while it waits for an append, or
+// another Clean, to finish. Once segments are being deleted, it runs to completion; so that the commitlog, & its
+// manifest, are never left half cleaned.
+func (l *Clog) CleanCtx(ctx context.Context) (err error) {
+	defer func(start time.Time) { l.tracer.add("clean", start, 0, err) }(time.Now())
+
+	if errC := ctx.Err(); errC != nil {
+		return errC
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errC := ctx.Err(); errC != nil {
+		return errC
+	}
+	if errC := l.checkOpen(); errC != nil {
+		return errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return errC
+	}
+	if l.relocating {
+		return ErrRelocating
+	}
+	return l.clean()
+}
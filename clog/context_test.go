@@ -0,0 +1,125 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestContextVariants(t *testing.T) {
+	t.Parallel()
+
+	t.Run("read aborts midway", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// each append fills a segment.
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		data, lastReadOffset, errR := l.ReadCtx(ctx, 0, 0)
+		if !errors.Is(errR, context.Canceled) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errR, context.Canceled)
+		}
+		if len(data) != 0 || lastReadOffset != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lastReadOffset, "nothing read")
+		}
+
+		// with a live context, it is Read.
+		want, wantOffset, errW := l.Read(0, 0)
+		if errW != nil {
+			t.Fatal("\n\t", errW)
+		}
+		got, gotOffset, errG := l.ReadCtx(context.Background(), 0, 0)
+		if errG != nil {
+			t.Fatal("\n\t", errG)
+		}
+		if string(got) != string(want) || gotOffset != wantOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", gotOffset, wantOffset)
+		}
+	})
+
+	t.Run("append gives up waiting for resume", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour, WithPauseMode(PauseBlock))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errP := l.PauseAppends()
+		if errP != nil {
+			t.Fatal("\n\t", errP)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		errA := l.AppendCtx(ctx, []byte("order # 1"))
+		if !errors.Is(errA, context.DeadlineExceeded) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, context.DeadlineExceeded)
+		}
+
+		l.ResumeAppends()
+		errB := l.AppendCtx(context.Background(), []byte("order # 2"))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		records, _, errR := l.ReadRecords(0, 0)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		if len(records) != 1 || string(records[0]) != "order # 2" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 1)
+		}
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// retention is of about one record.
+		l, err := New(path, 10, 9+recordHeaderSize, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		before := len(l.segmentRead())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		errC := l.CleanCtx(ctx)
+		if !errors.Is(errC, context.Canceled) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, context.Canceled)
+		}
+		if got := len(l.segmentRead()); got != before {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, before)
+		}
+
+		errD := l.CleanCtx(context.Background())
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if got := len(l.segmentRead()); got >= before {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "segments to be deleted")
+		}
+	})
+}
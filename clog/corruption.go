@@ -0,0 +1,130 @@
+package clog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const remediateCorruption = "re-fetch the segment from a replica, or restore it from an archive"
+
+// The checks, of a CorruptionError, that compare a sealed segment with its seal.
+const (
+	checkSealSize     = "sealed size"
+	checkSealChecksum = "sealed checksum"
+)
+
+// ErrExternallyModified is matched, using errors.Is, by the *CorruptionError returned when the file of a sealed segment
+// has been changed, eg by an operator or another program, since the segment was sealed. Rather than returning data
+// that may be silently corrupted, reads of the segment fail.
+var ErrExternallyModified = errors.New("segment file has been modified since it was sealed")
+
+// CorruptionError is returned whenever the data in a segment is not what the commitlog expects.
+// It carries enough context for an operator to act on it.
+type CorruptionError struct {
+	// Path is the path, in the filesystem, of the corrupt segment.
+	Path string
+	// Start & End are the byte range, [Start, End), within the segment that is corrupt.
+	Start uint64
+	End   uint64
+	// Check is the name of the check that failed. eg; "size"
+	Check string
+	// Expected & Actual are the values of Check that were expected and found respectively.
+	Expected uint64
+	Actual   uint64
+	// Remediation is a suggestion of what can be done about the corruption.
+	Remediation string
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf(
+		"segment %s is corrupt in byte range [%d, %d): expected %s %d, found %d. suggested remediation: %s",
+		e.Path, e.Start, e.End, e.Check, e.Expected, e.Actual, e.Remediation,
+	)
+}
+
+// Is allows a *CorruptionError, whose segment was modified after it was sealed, to match ErrExternallyModified.
+func (e *CorruptionError) Is(target error) bool {
+	return target == ErrExternallyModified && (e.Check == checkSealSize || e.Check == checkSealChecksum)
+}
+
+// newSealCorruption returns a corruption error for a sealed segment, at path, whose data b does not match its seal.
+func newSealCorruption(path string, seal segmentSeal, b []byte) *CorruptionError {
+	if uint64(len(b)) != seal.Size {
+		e := newSizeCorruption(path, seal.Size, uint64(len(b)))
+		e.Check = checkSealSize
+		return e
+	}
+	return &CorruptionError{
+		Path:        path,
+		Start:       0,
+		End:         seal.Size,
+		Check:       checkSealChecksum,
+		Expected:    uint64(seal.Checksum),
+		Actual:      uint64(crc32.Checksum(b, crcTable)),
+		Remediation: remediateCorruption,
+	}
+}
+
+// newSizeCorruption returns a corruption error for a segment whose size is not what is expected.
+func newSizeCorruption(path string, want, got uint64) *CorruptionError {
+	start, end := want, got
+	if got < want {
+		// data has gone missing.
+		start, end = got, want
+	}
+	return &CorruptionError{
+		Path:        path,
+		Start:       start,
+		End:         end,
+		Check:       "size",
+		Expected:    want,
+		Actual:      got,
+		Remediation: remediateCorruption,
+	}
+}
+
+// newRecordCorruption returns a corruption error for a segment, at path, that has a torn record at byte pos.
+// tail is the data of the segment from pos onwards.
+func newRecordCorruption(path string, pos uint64, tail []byte) *CorruptionError {
+	want, got := uint64(recordHeaderSize), uint64(len(tail))
+	if len(tail) >= recordHeaderSize {
+		// the header is whole, but the data it frames is not.
+		want, got = uint64(binary.BigEndian.Uint32(tail)), uint64(len(tail)-recordHeaderSize)
+	}
+	return &CorruptionError{
+		Path:        path,
+		Start:       pos,
+		End:         pos + uint64(len(tail)),
+		Check:       "record length",
+		Expected:    want,
+		Actual:      got,
+		Remediation: remediateCorruption,
+	}
+}
+
+// newChecksumCorruption returns a corruption error for a segment, at path, whose record at byte pos does not match its checksum.
+// record is the whole record, header included, & sum is the checksum it was framed with.
+func newChecksumCorruption(path string, pos uint64, record []byte, sum uint32) *CorruptionError {
+	return &CorruptionError{
+		Path:        path,
+		Start:       pos,
+		End:         pos + uint64(len(record)),
+		Check:       "record checksum",
+		Expected:    uint64(sum),
+		Actual:      uint64(recordChecksum(record[:4], record[recordHeaderSize:])),
+		Remediation: remediateCorruption,
+	}
+}
+
+// reportCorruption calls the registered corruption handler, if any, when err is a corruption error.
+func (l *Clog) reportCorruption(err error) {
+	if l.onCorruption == nil {
+		return
+	}
+	var ce *CorruptionError
+	if errors.As(err, &ce) {
+		l.onCorruption(ce)
+	}
+}
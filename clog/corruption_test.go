@@ -0,0 +1,218 @@
+package clog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCorruptionError(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing data", func(t *testing.T) {
+		t.Parallel()
+
+		e := newSizeCorruption("/tmp/1.log", 10, 4)
+		if e.Start != 4 || e.End != 10 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{e.Start, e.End}, []uint64{4, 10})
+		}
+		if !strings.Contains(e.Error(), "/tmp/1.log") {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", e.Error(), "segment path in error")
+		}
+		if !strings.Contains(e.Error(), remediateCorruption) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", e.Error(), "remediation in error")
+		}
+	})
+
+	t.Run("extra data", func(t *testing.T) {
+		t.Parallel()
+
+		e := newSizeCorruption("/tmp/1.log", 10, 14)
+		if e.Start != 10 || e.End != 14 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{e.Start, e.End}, []uint64{10, 14})
+		}
+		if e.Expected != 10 || e.Actual != 14 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{e.Expected, e.Actual}, []uint64{10, 14})
+		}
+	})
+
+	t.Run("torn record", func(t *testing.T) {
+		t.Parallel()
+
+		// a record of 10 bytes, of which only 6 made it to the segment.
+		tail := encodeRecord([]byte("0123456789"))[:recordHeaderSize+6]
+		e := newRecordCorruption("/tmp/1.log", 20, tail)
+		if e.Start != 20 || e.End != 20+recordHeaderSize+6 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{e.Start, e.End}, []uint64{20, 20 + recordHeaderSize + 6})
+		}
+		if e.Expected != 10 || e.Actual != 6 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{e.Expected, e.Actual}, []uint64{10, 6})
+		}
+
+		// only part of the header made it.
+		e2 := newRecordCorruption("/tmp/1.log", 20, tail[:2])
+		if e2.Expected != recordHeaderSize || e2.Actual != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{e2.Expected, e2.Actual}, []uint64{recordHeaderSize, 2})
+		}
+	})
+}
+
+func TestCorruptionHandler(t *testing.T) {
+	t.Parallel()
+
+	createCorruptClog := func(t *testing.T, fn func(*CorruptionError)) (*Clog, func()) {
+		path, removePath := createPathForTests(t)
+		l, err := New(path, 100, 1, time.Nanosecond, WithCorruptionHandler(fn))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		msg := []byte(strings.Repeat("a", int(l.maxSegBytes*2)))
+		for i := 0; i < 2; i++ {
+			errA := l.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		// truncate a sealed segment behind the commitlog's back.
+		errB := os.Truncate(l.segments[0].filePath, 3)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		return l, removePath
+	}
+
+	t.Run("called on read", func(t *testing.T) {
+		t.Parallel()
+
+		var got *CorruptionError
+		l, removePath := createCorruptClog(t, func(e *CorruptionError) { got = e })
+		defer removePath()
+
+		_, _, err := l.Read(0, 0)
+		var ce *CorruptionError
+		if !errors.As(err, &ce) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, &CorruptionError{})
+		}
+		if got == nil {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "handler to be called")
+		}
+		if got.Path != l.segments[0].filePath {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got.Path, l.segments[0].filePath)
+		}
+		if want := 200 + recordHeaderSize; got.Start != 3 || got.End != uint64(want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{got.Start, got.End}, []uint64{3, uint64(want)})
+		}
+	})
+
+	t.Run("called on scrub", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var got []*CorruptionError
+		l, removePath := createCorruptClog(t, func(e *CorruptionError) {
+			mu.Lock()
+			got = append(got, e)
+			mu.Unlock()
+		})
+		defer removePath()
+
+		s, err := NewScrubber(l, time.Millisecond, func(error) {})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		s.Start()
+		time.Sleep(50 * time.Millisecond)
+		s.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(got) < 1 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), ">=1")
+		}
+		if got[0].Path != l.segments[0].filePath {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got[0].Path, l.segments[0].filePath)
+		}
+	})
+}
+
+func TestExternallyModified(t *testing.T) {
+	t.Parallel()
+
+	t.Run("same size, different data", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// each append fills a segment.
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		// a valid record of the same length; only the seal can tell it apart.
+		sealed := l.segmentRead()[0].filePath
+		errW := os.WriteFile(sealed, encodeRecord([]byte("order # 9")), 0o600)
+		if errW != nil {
+			t.Fatal("\n\t", errW)
+		}
+
+		_, _, errR := l.Read(0, 0)
+		if !errors.Is(errR, ErrExternallyModified) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errR, ErrExternallyModified)
+		}
+
+		// the seal is kept in the manifest.
+		l2, errN := New(path, 10, 100_000, time.Hour)
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		_, _, errR2 := l2.Read(0, 0)
+		if !errors.Is(errR2, ErrExternallyModified) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errR2, ErrExternallyModified)
+		}
+	})
+
+	t.Run("appended to", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		f, errO := os.OpenFile(l.segmentRead()[0].filePath, os.O_APPEND|os.O_WRONLY, 0o600)
+		if errO != nil {
+			t.Fatal("\n\t", errO)
+		}
+		_, errW := f.Write(encodeRecord([]byte("order # 9")))
+		_ = f.Close()
+		if errW != nil {
+			t.Fatal("\n\t", errW)
+		}
+
+		_, _, errR := l.Read(0, 0)
+		var ce *CorruptionError
+		if !errors.As(errR, &ce) || !errors.Is(errR, ErrExternallyModified) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", errR, ErrExternallyModified)
+		}
+		if ce.Check != checkSealSize {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ce.Check, checkSealSize)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package clog
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// debugState is the in-memory state of a commitlog, as written by DebugDump.
+type debugState struct {
+	Path          string         `json:"path"`
+	Config        debugConfig    `json:"config"`
+	LowWatermark  uint64         `json:"lowWatermark"`
+	HighWatermark uint64         `json:"highWatermark"`
+	Paused        bool           `json:"paused"`
+	ReadOnly      bool           `json:"readOnly"`
+	Relocating    bool           `json:"relocating"`
+	Segments      []debugSegment `json:"segments"`
+	// PendingDeletes are the deleted segments whose removal waits on the readers that use them. see PendingDeletion
+	PendingDeletes []debugSegment `json:"pendingDeletes"`
+	// RecentErrors are the latest operations that failed; only kept if WithTrace was used.
+	RecentErrors []debugError `json:"recentErrors"`
+}
+
+type debugConfig struct {
+	MaxSegBytes uint64 `json:"maxSegBytes"`
+	MaxLogBytes uint64 `json:"maxLogBytes"`
+	MaxLogAge   string `json:"maxLogAge"`
+	MaxSegments int    `json:"maxSegments"`
+	SyncPolicy  int    `json:"syncPolicy"`
+	TailCache   uint64 `json:"tailCache"`
+}
+
+type debugSegment struct {
+	BaseOffset    uint64 `json:"baseOffset"`
+	FirstSequence uint64 `json:"firstSequence"`
+	Path          string `json:"path"`
+	Size          uint64 `json:"size"`
+	// Records is -1 if the records of the segment have not been counted yet.
+	Records int64 `json:"records"`
+	// Readers is the number of reads, iterators & readers that are using the segment; it is where consumers are.
+	Readers       int          `json:"readers"`
+	Cached        uint64       `json:"cached"`
+	Seal          *segmentSeal `json:"seal,omitempty"`
+	DeletePending bool         `json:"deletePending"`
+}
+
+type debugError struct {
+	Op    string    `json:"op"`
+	Start time.Time `json:"start"`
+	Err   string    `json:"err"`
+}
+
+// DebugDump writes the in-memory state of the commitlog, as JSON, to w; for attaching to bug reports.
+// The state is its configuration, its segments(with their sizes, record counts & the number of readers using each of
+// them), its watermarks, whether it is paused or read-only, & the errors of its latest operations(see WithTrace).
+//
+// It neither reads, nor syncs, segment files; & it only holds the read lock of the commitlog while it copies the list
+// of segments, so the state of each segment is taken a moment apart from the others. It does wait for those locks
+// though; so on a commitlog whose disk has stalled it blocks behind the append, or sync, that is stuck. Call it from a
+// goroutine of its own to bound how long to wait for it.
+func (l *Clog) DebugDump(w io.Writer) error {
+	l.mu.RLock()
+	st := debugState{
+		Path: l.path,
+		Config: debugConfig{
+			MaxSegBytes: l.maxSegBytes,
+			MaxLogBytes: l.cl.maxLogBytes,
+			MaxLogAge:   l.cl.maxLogAge.String(),
+			MaxSegments: l.cl.maxSegments,
+			SyncPolicy:  int(l.syncPolicy),
+			TailCache:   l.tailCache,
+		},
+		LowWatermark: l.lowWatermark,
+		Paused:       l.pause.paused,
+		ReadOnly:     l.guard != nil && l.guard.readOnly,
+		Relocating:   l.relocating,
+	}
+	segs := append([]*segment{}, l.segmentRead()...)
+	pending := append([]*segment{}, l.pendingDeletes...)
+	l.mu.RUnlock()
+
+	st.Segments = make([]debugSegment, 0, len(segs))
+	for _, seg := range segs {
+		d := seg.debugState()
+		if d.Size > 0 {
+			st.HighWatermark = d.BaseOffset
+		}
+		st.Segments = append(st.Segments, d)
+	}
+	st.PendingDeletes = make([]debugSegment, 0, len(pending))
+	for _, seg := range pending {
+		st.PendingDeletes = append(st.PendingDeletes, seg.debugState())
+	}
+	st.RecentErrors = []debugError{}
+	for _, e := range l.tracer.entries() {
+		if e.Err != nil {
+			st.RecentErrors = append(st.RecentErrors, debugError{Op: e.Op, Start: e.Start, Err: e.Err.Error()})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(st)
+}
+
+// debugState returns the in-memory state of the segment. see Clog.DebugDump
+func (s *segment) debugState() debugSegment {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	d := debugSegment{
+		BaseOffset:    s.baseOffset,
+		FirstSequence: s.firstSeq,
+		Path:          s.filePath,
+		Size:          s.currentSegBytes,
+		Records:       -1,
+		Readers:       s.refs,
+		Cached:        uint64(len(s.cache)),
+		DeletePending: s.deletePending,
+	}
+	if s.recordsKnown {
+		d.Records = int64(s.records)
+	}
+	if s.seal != nil {
+		c := *s.seal
+		d.Seal = &c
+	}
+	return d
+}
@@ -0,0 +1,60 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDebugDump(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each append fills a segment.
+	l, err := New(path, 10, 100_000, time.Hour, WithTrace(10))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for _, r := range []string{"order # 1", "order # 2"} {
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	r, errB := l.Reader(0)
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	defer r.Close()
+
+	buf := &bytes.Buffer{}
+	errD := l.DebugDump(buf)
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	got := debugState{}
+	errU := json.Unmarshal(buf.Bytes(), &got)
+	if errU != nil {
+		t.Fatal("\n\t", errU)
+	}
+
+	if got.Path != path || got.Config.MaxSegBytes != 10 || got.Config.MaxLogBytes != 100_000 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got.Config, "the configuration of the commitlog")
+	}
+	segs := l.segmentRead()
+	if len(got.Segments) != len(segs) {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got.Segments), len(segs))
+	}
+	if got.HighWatermark != l.HighWatermark() {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got.HighWatermark, l.HighWatermark())
+	}
+	// the reader holds the segments it is yet to read.
+	if got.Segments[0].Readers != 1 || got.Segments[0].Records != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got.Segments[0], "a segment with one record & one reader")
+	}
+	if got.Segments[0].Seal == nil {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got.Segments[0].Seal, "the sealed segment to have a seal")
+	}
+}
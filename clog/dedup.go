@@ -0,0 +1,138 @@
+package clog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"sync"
+)
+
+var (
+	errBadDedupWindow  = errors.New("dedup window cannot have a negative or zero size")
+	errReadCheckpoint  = func(err error) error { return fmt.Errorf("read consumer checkpoint failed: %w", err) }
+	errWriteCheckpoint = func(err error) error { return fmt.Errorf("write consumer checkpoint failed: %w", err) }
+)
+
+// DedupWindow remembers the IDs of the most recent records that a consumer has processed.
+// Reads from a commitlog are at-least-once; after a crash, a consumer resumes from the offset it last saved and may
+// thus see records that it had already processed. A consumer that checks each record against a DedupWindow, and saves
+// the window together with its offset, can skip those redeliveries instead of repeating their side effects.
+//
+// Only the latest size IDs are remembered, so redeliveries are only suppressed if fewer than size records were
+// processed after the offset was last saved.
+//
+// To create a dedup window, use the NewDedupWindow or LoadDedupWindow methods.
+type DedupWindow struct {
+	size int
+
+	// mu protects ring, next & set
+	mu   sync.Mutex
+	ring []uint64 // the hashes of the remembered IDs, in the order in which they were seen.
+	next int      // the position in ring that is overwritten next.
+	set  map[uint64]struct{}
+}
+
+// dedupCheckpoint is how a DedupWindow, and the offset of its consumer, are persisted.
+type dedupCheckpoint struct {
+	Offset uint64   `json:"offset"`
+	Hashes []uint64 `json:"hashes"` // oldest first.
+}
+
+// NewDedupWindow creates an empty dedup window that remembers upto size IDs.
+func NewDedupWindow(size int) (*DedupWindow, error) {
+	if size <= 0 {
+		return nil, errBadDedupWindow
+	}
+	return &DedupWindow{size: size, ring: make([]uint64, 0, size), set: map[uint64]struct{}{}}, nil
+}
+
+// Seen reports whether id has been seen before and, if not, remembers it.
+// id is whatever identifies a record; an ID carried in the record, or the record itself.
+// IDs are remembered by their 64-bit hash, so distinct IDs collide with a negligible probability.
+func (d *DedupWindow) Seen(id []byte) bool {
+	h := fnv.New64a()
+	_, _ = h.Write(id)
+	sum := h.Sum64()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, ok := d.set[sum]
+	if ok {
+		return true
+	}
+	d.remember(sum)
+	return false
+}
+
+// remember adds sum to the window, forgetting the oldest one if the window is full.
+// The caller should hold d.mu
+func (d *DedupWindow) remember(sum uint64) {
+	if len(d.ring) < d.size {
+		d.ring = append(d.ring, sum)
+	} else {
+		delete(d.set, d.ring[d.next])
+		d.ring[d.next] = sum
+		d.next = (d.next + 1) % d.size
+	}
+	d.set[sum] = struct{}{}
+}
+
+// Save persists, atomically, the window together with offset; the offset from which its consumer will resume.
+// Use LoadDedupWindow to load them back.
+//
+// usage:
+//
+//	data, lastReadOffset, err := l.Read(offset, 0)
+//	// process data, skipping records for which d.Seen(id) is true.
+//	errS := d.Save(OSFileSystem{}, "/tmp/orders-consumer.json", lastReadOffset)
+func (d *DedupWindow) Save(fsys FileSystem, path string, offset uint64) error {
+	d.mu.Lock()
+	c := dedupCheckpoint{Offset: offset, Hashes: make([]uint64, 0, len(d.ring))}
+	c.Hashes = append(c.Hashes, d.ring[d.next:]...)
+	c.Hashes = append(c.Hashes, d.ring[:d.next]...)
+	d.mu.Unlock()
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return errWriteCheckpoint(err)
+	}
+	errA := writeFileAtomic(fsys, path, path+".tmp", b)
+	if errA != nil {
+		return errWriteCheckpoint(errA)
+	}
+	return nil
+}
+
+// LoadDedupWindow loads a window, that remembers upto size IDs, and the offset that were saved at path by Save.
+// If nothing has been saved at path yet, an empty window and an offset of 0 are returned.
+func LoadDedupWindow(fsys FileSystem, path string, size int) (*DedupWindow, uint64, error) {
+	d, err := NewDedupWindow(size)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	b, errA := fsys.ReadFile(path)
+	if errA != nil {
+		if errors.Is(errA, fs.ErrNotExist) {
+			return d, 0, nil
+		}
+		return nil, 0, errReadCheckpoint(errA)
+	}
+	c := dedupCheckpoint{}
+	errB := json.Unmarshal(b, &c)
+	if errB != nil {
+		return nil, 0, errReadCheckpoint(errB)
+	}
+
+	for _, sum := range c.Hashes {
+		// if size has shrunk since the window was saved, the oldest hashes are forgotten.
+		_, ok := d.set[sum]
+		if !ok {
+			d.remember(sum)
+		}
+	}
+	return d, c.Offset, nil
+}
@@ -0,0 +1,94 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestDedupWindow(t *testing.T) {
+	t.Parallel()
+
+	t.Run("seen", func(t *testing.T) {
+		t.Parallel()
+
+		d, err := NewDedupWindow(2)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		tt := []struct {
+			id   string
+			want bool
+		}{
+			{id: "a", want: false},
+			{id: "a", want: true},
+			{id: "b", want: false},
+			{id: "c", want: false}, // "a" is forgotten.
+			{id: "b", want: true},
+			{id: "a", want: false},
+		}
+		for _, v := range tt {
+			got := d.Seen([]byte(v.id))
+			if got != v.want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v for %s", got, v.want, v.id)
+			}
+		}
+	})
+
+	t.Run("save & load", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		checkpoint := filepath.Join(path, "consumer.json")
+
+		d, offset, err := LoadDedupWindow(OSFileSystem{}, checkpoint, 3)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if offset != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", offset, 0)
+		}
+		for i := 0; i < 5; i++ {
+			d.Seen([]byte(fmt.Sprint(i)))
+		}
+		errA := d.Save(OSFileSystem{}, checkpoint, 77)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		// a smaller window only keeps the latest IDs.
+		d2, offset2, errB := LoadDedupWindow(OSFileSystem{}, checkpoint, 2)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if offset2 != 77 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", offset2, 77)
+		}
+		tt := []struct {
+			id   string
+			want bool
+		}{
+			{id: "4", want: true},
+			{id: "3", want: true},
+			{id: "2", want: false},
+		}
+		for _, v := range tt {
+			got := d2.Seen([]byte(v.id))
+			if got != v.want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v for %s", got, v.want, v.id)
+			}
+		}
+	})
+
+	t.Run("bad size", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := NewDedupWindow(0)
+		if !errors.Is(err, errBadDedupWindow) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadDedupWindow)
+		}
+	})
+}
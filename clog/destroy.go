@@ -0,0 +1,101 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var errDestroy = func(err error) error { return fmt.Errorf("destroy failed: %w", err) }
+
+// Destroy closes the commitlog, see Close, & removes it from the filesystem; its segments, its metadata & its directory.
+// The commitlog is then uninitialized; like after a failed New, none of its methods can be used. A commitlog can be
+// created at the same path again with New.
+//
+// The directory of the commitlog is first renamed, if the filesystem can rename directories; so that a crash midway
+// never leaves behind a directory that New would open as a commitlog with only part of its data. Segments in stripes,
+// see WithStripes, are removed after that; the oldest first. Reads that are in progress fail.
+//
+// usage:
+//
+//	l, errN := New("/tmp/orders", 100, 5, time.Hour*3)
+//	errD := l.Destroy()
+func (l *Clog) Destroy() (err error) {
+	defer func(start time.Time) { l.tracer.add("destroy", start, 0, err) }(time.Now())
+
+	// the data is removed; so it does not matter whether it could be synced.
+	_ = l.close()
+	l.background.Wait()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.initialized {
+		return errLogNotInitialized
+	}
+	l.initialized = false
+
+	dir := l.path
+	tomb := fmt.Sprintf("%s.destroyed-%d", filepath.Clean(l.path), tNow())
+	if errN := l.fs.Rename(l.path, tomb); errN == nil {
+		dir = tomb
+	}
+
+	root := filepath.Clean(l.path) + string(filepath.Separator)
+	for _, seg := range append(append([]*segment{}, l.pendingDeletes...), l.segments...) {
+		if strings.HasPrefix(seg.filePath, root) {
+			// removed together with the directory of the commitlog.
+			continue
+		}
+		errR := l.fs.Remove(seg.filePath)
+		if errR != nil && !errors.Is(errR, fs.ErrNotExist) {
+			return errDestroy(errR)
+		}
+		l.removeEmptyDirs(seg)
+	}
+	for _, stripe := range l.knownStripes {
+		// a stripe that still holds other files is left alone.
+		_ = l.fs.Remove(stripe)
+	}
+	l.segments = nil
+	l.pendingDeletes = nil
+
+	errT := removeTree(l.fs, dir)
+	if errT != nil {
+		return errDestroy(errT)
+	}
+	return nil
+}
+
+// removeTree removes dir, & everything in it, from fsys; the files in each directory in the order of their names.
+func removeTree(fsys FileSystem, dir string) error {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			errA := removeTree(fsys, p)
+			if errA != nil {
+				return errA
+			}
+			continue
+		}
+		errB := fsys.Remove(p)
+		if errB != nil && !errors.Is(errB, fs.ErrNotExist) {
+			return errB
+		}
+	}
+	errC := fsys.Remove(dir)
+	if errC != nil && !errors.Is(errC, fs.ErrNotExist) {
+		return errC
+	}
+	return nil
+}
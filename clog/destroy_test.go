@@ -0,0 +1,126 @@
+package clog
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingStripeFS is a FileSystem whose removals of the files in stripe fail.
+type failingStripeFS struct {
+	OSFileSystem
+	stripe string
+}
+
+func (f failingStripeFS) Remove(name string) error {
+	if strings.HasPrefix(name, filepath.Clean(f.stripe)+string(filepath.Separator)) {
+		return errors.New("stripe disk is unreachable")
+	}
+	return f.OSFileSystem.Remove(name)
+}
+
+func TestDestroy(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	stripe, removeStripe := createPathForTests(t)
+	defer removeStripe()
+
+	l, err := New(path, 10, 100_000, time.Hour, WithStripes(stripe))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	errD := l.Destroy()
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	for _, dir := range []string{path, stripe} {
+		if _, errS := os.Stat(dir); !errors.Is(errS, fs.ErrNotExist) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errS, fs.ErrNotExist)
+		}
+	}
+	tombs, errG := filepath.Glob(path + ".destroyed-*")
+	if errG != nil {
+		t.Fatal("\n\t", errG)
+	}
+	if len(tombs) != 0 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", tombs, []string{})
+	}
+
+	if errA := l.Append([]byte("order # 4")); errA == nil {
+		t.Error("expected appends to a destroyed commitlog to fail")
+	}
+	if errD2 := l.Destroy(); !errors.Is(errD2, errLogNotInitialized) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD2, errLogNotInitialized)
+	}
+
+	// a new commitlog can be created in its place.
+	l2, errN := New(path, 10, 100_000, time.Hour)
+	if errN != nil {
+		t.Fatal("\n\t", errN)
+	}
+	defer l2.Close()
+	records, _, errR := l2.ReadRecords(0, 0)
+	if errR != nil {
+		t.Fatal("\n\t", errR)
+	}
+	if len(records) != 0 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 0)
+	}
+}
+
+func TestDestroyRenamesFirst(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	stripe, removeStripe := createPathForTests(t)
+	defer removeStripe()
+	tombs := func() []string {
+		matches, errG := filepath.Glob(path + ".destroyed-*")
+		if errG != nil {
+			t.Fatal("\n\t", errG)
+		}
+		return matches
+	}
+	defer func() {
+		for _, tomb := range tombs() {
+			_ = os.RemoveAll(tomb)
+		}
+	}()
+
+	l, err := New(path, 10, 100_000, time.Hour, WithStripes(stripe), WithFileSystem(failingStripeFS{stripe: stripe}))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	// a destroy that fails midway, as a crash would, does not leave behind a commitlog whose segments are missing.
+	errD := l.Destroy()
+	if errD == nil {
+		t.Fatal("expected the destroy to fail")
+	}
+	if _, errS := os.Stat(path); !errors.Is(errS, fs.ErrNotExist) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errS, fs.ErrNotExist)
+	}
+	if len(tombs()) != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", tombs(), "the directory to be renamed")
+	}
+}
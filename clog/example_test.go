@@ -8,7 +8,7 @@ import (
 	"github.com/komuw/shifta/clog"
 )
 
-func ExampleAppend() {
+func ExampleClog_Append() {
 	l, e := clog.New(
 		"/tmp/customerOrders",
 		80_000_000,     /*80Mb*/
@@ -28,7 +28,7 @@ func ExampleAppend() {
 	// Unordered output:
 }
 
-func ExampleRead() {
+func ExampleClog_Read() {
 	l, e := clog.New(
 		"/tmp/customerOrders",
 		80_000_000,     /*80Mb*/
@@ -46,7 +46,16 @@ func ExampleRead() {
 	}
 
 	dataRead, _, err := l.Read(0, 0)
-	fmt.Print(string(dataRead))
+	if err != nil {
+		panic(err)
+	}
+	records, err := clog.Records(dataRead)
+	if err != nil {
+		panic(err)
+	}
+	for _, r := range records {
+		fmt.Print(string(r))
+	}
 
 	// Unordered output:
 	// Nasir bin Olu Dara Jones ordered 3 shoes.
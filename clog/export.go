@@ -0,0 +1,414 @@
+package clog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// The export format is a stream of:
+//
+//	header:  magic "SHFX" | version uint16
+//	chunk:   'S' | baseOffset uint64 | firstSequence uint64 | length uint64 | data [length]byte | crc32 uint32   (one per segment)
+//	trailer: 'T' | segments uint64 | bytes uint64 | crc32 uint32
+//
+// Integers are big endian. The crc32(Castagnoli) of a chunk covers its baseOffset, firstSequence, length & data.
+// The crc32 of the trailer covers its totals and the crc32s of all the chunks, in order.
+// A stream without a trailer has been truncated. firstSequence is the sequence number of the first record of the
+// segment, see RecordOffset; 0 if it is not known.
+//
+// The data of a chunk is that of a segment, in the on-disk format of the commitlog. So the version of the stream changes
+// whenever FormatVersion does; a version 2 or 3 stream holds segments of that FormatVersion. Version 4 streams hold
+// segments of FormatVersion 3, & are the first whose chunks have a firstSequence.
+// Streams as old as minExportVersion can still be read; their segments are upgraded to the current FormatVersion as they
+// are read. Version 1 streams can not, since their segments are not framed & so can not be split into records.
+const (
+	exportMagic      = "SHFX"
+	exportVersion    = uint16(4)
+	minExportVersion = uint16(2)
+	// sequencedExportVersion is the first version whose chunks have a firstSequence.
+	sequencedExportVersion = uint16(4)
+	// framedExportVersion is the first version whose segments are of the current FormatVersion.
+	framedExportVersion = uint16(3)
+	chunkTag            = 'S'
+	trailerTag          = 'T'
+)
+
+// ErrBadExport is matched, using errors.Is, by the errors returned when an export stream is corrupt, truncated
+// or of an unknown version.
+var ErrBadExport = errors.New("bad export")
+
+var (
+	crcTable     = crc32.MakeTable(crc32.Castagnoli)
+	errBadExport = func(format string, a ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrBadExport, fmt.Sprintf(format, a...))
+	}
+	errRestoreExists = errors.New("restore target already has a segment with that baseOffset")
+)
+
+// Export writes the sealed segments of the commitlog, whose baseOffset comes after offset(exclusive), to w
+// in a checksummed container format. The active segment is not exported, since it may still be appended to.
+// A restore, see Restore & ReadExport, can verify that the export is complete and uncorrupted.
+//
+// It returns the baseOffset of the last segment exported, which can be used as the offset of the next, incremental, export.
+// Like Read, it sees a snapshot of the segments as they were when it started.
+//
+// usage:
+//
+//	lastExportedOffset, err := l.Export(backupFile, 0)
+func (l *Clog) Export(w io.Writer, offset uint64) (lastExportedOffset uint64, err error) {
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return 0, errC
+	}
+	if offset != 0 && offset < l.lowWatermark {
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return 0, &OutOfRangeError{Offset: offset, LowWatermark: lw}
+	}
+	all := l.segmentRead()
+	var sealed []*segment
+	if len(all) > 0 {
+		// the latest segment is the active one.
+		sealed = acquireAfter(all[:len(all)-1], offset)
+	}
+	l.mu.RUnlock()
+	defer releaseSegments(sealed)
+
+	return l.exportSegments(w, sealed)
+}
+
+// ExportRange is like Export, but only exports the sealed segments whose baseOffset is within [from, to);
+// from is inclusive and to is exclusive, as in ReadRange. Since baseOffsets are the times at which segments were created,
+// in nanoseconds since the unix epoch, it can be used to export the segments created within a window of time.
+//
+// usage:
+//
+//	lastExportedOffset, err := l.ExportRange(backupFile, uint64(since.UnixNano()), uint64(until.UnixNano()))
+func (l *Clog) ExportRange(w io.Writer, from, to uint64) (lastExportedOffset uint64, err error) {
+	if from >= to {
+		return 0, errBadRange
+	}
+
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return 0, errC
+	}
+	if from != 0 && l.lowWatermark != 0 && from <= l.lowWatermark {
+		// the segment at lowWatermark has itself been deleted; from is inclusive.
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return 0, &OutOfRangeError{Offset: from, LowWatermark: lw}
+	}
+	all := l.segmentRead()
+	sealed := []*segment{}
+	for i, seg := range all {
+		if i == len(all)-1 {
+			// the latest segment is the active one.
+			break
+		}
+		if seg.baseOffset >= from && seg.baseOffset < to && seg.acquire() {
+			sealed = append(sealed, seg)
+		}
+	}
+	l.mu.RUnlock()
+	defer releaseSegments(sealed)
+
+	return l.exportSegments(w, sealed)
+}
+
+// exportSegments writes segs, which should have been acquired, to w in the export format.
+func (l *Clog) exportSegments(w io.Writer, segs []*segment) (lastExportedOffset uint64, err error) {
+	bw := bufio.NewWriter(w)
+	header := append([]byte(exportMagic), 0, 0)
+	binary.BigEndian.PutUint16(header[len(exportMagic):], exportVersion)
+	_, errA := bw.Write(header)
+	if errA != nil {
+		return 0, errA
+	}
+
+	trailer := crc32.New(crcTable)
+	var segments, total uint64
+	for _, seg := range segs {
+		b, errR := seg.Read()
+		if errR != nil {
+			l.reportCorruption(errR)
+			return lastExportedOffset, errR
+		}
+
+		chunk := make([]byte, 1+8+8+8)
+		chunk[0] = chunkTag
+		binary.BigEndian.PutUint64(chunk[1:], seg.baseOffset)
+		binary.BigEndian.PutUint64(chunk[9:], seg.firstSeq)
+		binary.BigEndian.PutUint64(chunk[17:], uint64(len(b)))
+		c := crc32.Update(crc32.Checksum(chunk[1:], crcTable), crcTable, b)
+		chunk = append(chunk, b...)
+		chunk = appendUint32(chunk, c)
+		_, errB := bw.Write(chunk)
+		if errB != nil {
+			return lastExportedOffset, errB
+		}
+
+		_, _ = trailer.Write(chunk[len(chunk)-4:])
+		segments++
+		total = total + uint64(len(b))
+		lastExportedOffset = seg.baseOffset
+	}
+
+	t := make([]byte, 1+8+8)
+	t[0] = trailerTag
+	binary.BigEndian.PutUint64(t[1:], segments)
+	binary.BigEndian.PutUint64(t[9:], total)
+	_, _ = trailer.Write(t[1:])
+	t = appendUint32(t, trailer.Sum32())
+	_, errC := bw.Write(t)
+	if errC != nil {
+		return lastExportedOffset, errC
+	}
+	return lastExportedOffset, bw.Flush()
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	u := make([]byte, 4)
+	binary.BigEndian.PutUint32(u, v)
+	return append(b, u...)
+}
+
+// ReadExport reads an export stream, written by Clog.Export, from r and calls fn with each segment in it.
+// It returns an error matching ErrBadExport if the stream is corrupt, truncated, or of an unknown version.
+// The segments of a stream of an older version are upgraded to the current FormatVersion before they are passed to fn.
+//
+// Since truncation is only detected at the end of the stream, segments passed to fn are provisional
+// until ReadExport returns a nil error. fn should not keep data after it returns.
+func ReadExport(r io.Reader, fn func(baseOffset uint64, data []byte) error) error {
+	return readExport(r, func(baseOffset, _ uint64, data []byte) error { return fn(baseOffset, data) })
+}
+
+// readExport is ReadExport; but fn is also passed the sequence number of the first record of each segment, 0 if the
+// stream does not have it.
+func readExport(r io.Reader, fn func(baseOffset, firstSeq uint64, data []byte) error) error {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(exportMagic)+2)
+	_, err := io.ReadFull(br, header)
+	if err != nil {
+		return errBadExport("read header: %v", err)
+	}
+	if string(header[:len(exportMagic)]) != exportMagic {
+		return errBadExport("not an export stream")
+	}
+	version := binary.BigEndian.Uint16(header[len(exportMagic):])
+	if version < minExportVersion || version > exportVersion {
+		return errBadExport("unknown version %d", version)
+	}
+
+	trailer := crc32.New(crcTable)
+	var segments, total uint64
+	for {
+		tag, errA := br.ReadByte()
+		if errA != nil {
+			return errBadExport("truncated after %d segments: %v", segments, errA)
+		}
+
+		fields := make([]byte, 16)
+		if tag == chunkTag && version >= sequencedExportVersion {
+			fields = make([]byte, 24)
+		}
+		_, errB := io.ReadFull(br, fields)
+		if errB != nil {
+			return errBadExport("truncated after %d segments: %v", segments, errB)
+		}
+		sum := make([]byte, 4)
+
+		switch tag {
+		case chunkTag:
+			var firstSeq uint64
+			if len(fields) == 24 {
+				firstSeq = binary.BigEndian.Uint64(fields[8:])
+			}
+			n := binary.BigEndian.Uint64(fields[len(fields)-8:])
+			// a corrupt length should not make us allocate an unbounded amount of memory.
+			data, errC := io.ReadAll(io.LimitReader(br, int64(n)))
+			if errC != nil || uint64(len(data)) != n {
+				return errBadExport("truncated in segment %d", binary.BigEndian.Uint64(fields))
+			}
+			_, errD := io.ReadFull(br, sum)
+			if errD != nil {
+				return errBadExport("truncated in segment %d: %v", binary.BigEndian.Uint64(fields), errD)
+			}
+			c := crc32.Update(crc32.Checksum(fields, crcTable), crcTable, data)
+			if c != binary.BigEndian.Uint32(sum) {
+				return errBadExport("checksum mismatch in segment %d", binary.BigEndian.Uint64(fields))
+			}
+
+			segData := data
+			if version < framedExportVersion {
+				var errU error
+				segData, errU = upgradeSegment(data)
+				if errU != nil {
+					return errBadExport("segment %d of version %d: %v", binary.BigEndian.Uint64(fields), version, errU)
+				}
+			}
+			errE := fn(binary.BigEndian.Uint64(fields), firstSeq, segData)
+			if errE != nil {
+				return errE
+			}
+			_, _ = trailer.Write(sum)
+			segments++
+			total = total + n
+		case trailerTag:
+			_, errF := io.ReadFull(br, sum)
+			if errF != nil {
+				return errBadExport("truncated in trailer: %v", errF)
+			}
+			_, _ = trailer.Write(fields)
+			if trailer.Sum32() != binary.BigEndian.Uint32(sum) {
+				return errBadExport("trailer checksum mismatch")
+			}
+			wantSegments, wantTotal := binary.BigEndian.Uint64(fields), binary.BigEndian.Uint64(fields[8:])
+			if segments != wantSegments || total != wantTotal {
+				return errBadExport("has %d segments of %d bytes, trailer says %d segments of %d bytes",
+					segments, total, wantSegments, wantTotal)
+			}
+			return nil
+		default:
+			return errBadExport("unknown tag %q after %d segments", tag, segments)
+		}
+	}
+}
+
+// upgradeSegment returns data, the data of a segment of FormatVersion 2 whose records are framed with their length only,
+// in the current FormatVersion; ie with each record also framed with its checksum.
+func upgradeSegment(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data)+len(data)/8)
+	for pos := 0; pos < len(data); {
+		if len(data)-pos < 4 {
+			return nil, fmt.Errorf("torn record at byte %d of %d", pos, len(data))
+		}
+		n := uint64(binary.BigEndian.Uint32(data[pos:]))
+		if uint64(len(data)-pos-4) < n {
+			return nil, fmt.Errorf("torn record at byte %d of %d", pos, len(data))
+		}
+		out = appendRecord(out, data[pos+4:pos+4+int(n)])
+		pos = pos + 4 + int(n)
+	}
+	return out, nil
+}
+
+// Restore restores an export stream, written by Clog.Export, from r into the commitlog directory dir of fsys.
+// The segments are laid out as decided by layout. The commitlog in dir should not be open while it is restored into.
+//
+// Segments are only moved into place once the whole stream has been verified; a corrupt or truncated export
+// leaves dir as it was. If a segment can not be moved into place, those that were are moved back out; so dir is also
+// left as it was. Restore refuses to overwrite a segment that already exists in dir.
+// If dir has no manifest, one that is stamped with the FormatVersion of the segments is written. The sequence numbers of
+// the records of the restored segments, if the stream has them, are recorded in the manifest; so that records keep their
+// sequence numbers even if the segments before them were not exported. see RecordOffset
+//
+// usage:
+//
+//	errR := clog.Restore(backupFile, clog.OSFileSystem{}, path, clog.FlatLayout{})
+func Restore(r io.Reader, fsys FileSystem, dir string, layout Layout) error {
+	return restore(r, fsys, dir, layout, nil)
+}
+
+// restore is Restore; but if only is not nil, it is called with the baseOffset of each segment in the stream, & a
+// segment for which it returns an error fails the restore.
+func restore(r io.Reader, fsys FileSystem, dir string, layout Layout, only func(baseOffset uint64) error) error {
+	tmps := map[string]string{} // tmp -> final
+	seqs := map[uint64]uint64{}
+	cleanup := func() {
+		for tmp := range tmps {
+			_ = fsys.Remove(tmp)
+		}
+	}
+
+	err := readExport(r, func(baseOffset, firstSeq uint64, data []byte) error {
+		if only != nil {
+			if errO := only(baseOffset); errO != nil {
+				return errO
+			}
+		}
+		if firstSeq != 0 {
+			seqs[baseOffset] = firstSeq
+		}
+		name := filepath.Join(dir, layout.SegmentPath(baseOffset))
+		_, errA := fsys.Stat(name)
+		if errA == nil {
+			return fmt.Errorf("%w: %s", errRestoreExists, name)
+		}
+		errB := fsys.MkdirAll(filepath.Dir(name), ownerReadableWritable)
+		if errB != nil {
+			return errMkDir(errB)
+		}
+
+		tmp := name + ".restore"
+		f, errC := fsys.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, ownerReadableWritable)
+		if errC != nil {
+			return errOpenFile(errC)
+		}
+		tmps[tmp] = name
+		_, errD := f.Write(data)
+		if errD != nil {
+			_ = f.Close()
+			return errD
+		}
+		errE := f.Sync()
+		if errE != nil {
+			_ = f.Close()
+			return errE
+		}
+		return f.Close()
+	})
+	if err != nil {
+		cleanup()
+		return err
+	}
+
+	renamed := map[string]string{} // tmp -> final
+	for tmp, name := range tmps {
+		errF := fsys.Rename(tmp, name)
+		if errF != nil {
+			// undo the renames that were made; the segments are then removed along with the rest.
+			for t, n := range renamed {
+				_ = fsys.Rename(n, t)
+			}
+			cleanup()
+			return errF
+		}
+		renamed[tmp] = name
+	}
+
+	_, errG := fsys.Stat(filepath.Join(dir, manifestName))
+	if errG != nil && !errors.Is(errG, fs.ErrNotExist) {
+		return errStatFile(errG)
+	}
+	if errG == nil && len(seqs) == 0 {
+		return nil
+	}
+	m, errH := readManifest(fsys, dir)
+	if errH != nil {
+		return errH
+	}
+	if errors.Is(errG, fs.ErrNotExist) {
+		// without a stamp, the restored segments would be taken to be of FormatVersion 1.
+		m.Version = FormatVersion
+	}
+	if m.Segments == nil {
+		m.Segments = map[uint64]segmentMeta{}
+	}
+	for baseOffset, firstSeq := range seqs {
+		meta := m.Segments[baseOffset]
+		meta.FirstSequence = firstSeq
+		m.Segments[baseOffset] = meta
+	}
+	m.Generation++
+	return m.write(fsys, dir)
+}
@@ -0,0 +1,269 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// failingRenameFS is a filesystem whose renames, of restored segments, fail after the first n of them.
+type failingRenameFS struct {
+	OSFileSystem
+	n int
+}
+
+func (f *failingRenameFS) Rename(oldpath, newpath string) error {
+	if strings.HasSuffix(oldpath, ".restore") {
+		if f.n == 0 {
+			return errors.New("rename failed")
+		}
+		f.n--
+	}
+	return f.OSFileSystem.Rename(oldpath, newpath)
+}
+
+// exportV2ForTests returns an export stream, of version 2, of a single segment that holds records.
+func exportV2ForTests(baseOffset uint64, records ...string) []byte {
+	data := []byte{}
+	for _, r := range records {
+		data = appendUint32(data, uint32(len(r)))
+		data = append(data, r...)
+	}
+	stream := append([]byte(exportMagic), 0, 2)
+	chunk := make([]byte, 1+8+8)
+	chunk[0] = chunkTag
+	binary.BigEndian.PutUint64(chunk[1:], baseOffset)
+	binary.BigEndian.PutUint64(chunk[9:], uint64(len(data)))
+	c := crc32.Update(crc32.Checksum(chunk[1:], crcTable), crcTable, data)
+	chunk = appendUint32(append(chunk, data...), c)
+	stream = append(stream, chunk...)
+
+	trailer := make([]byte, 1+8+8)
+	trailer[0] = trailerTag
+	binary.BigEndian.PutUint64(trailer[1:], 1)
+	binary.BigEndian.PutUint64(trailer[9:], uint64(len(data)))
+	sum := crc32.Checksum(chunk[len(chunk)-4:], crcTable)
+	sum = crc32.Update(sum, crcTable, trailer[1:])
+	return append(stream, appendUint32(trailer, sum)...)
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	createExport := func(t *testing.T) (*Clog, []byte, func()) {
+		path, removePath := createPathForTests(t)
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// each append fills a segment; so we end up with 3 sealed segments and an active one.
+		for i := 0; i < 4; i++ {
+			errA := l.Append([]byte(strings.Repeat(fmt.Sprint(i), 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		buf := &bytes.Buffer{}
+		_, errB := l.Export(buf, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		return l, buf.Bytes(), removePath
+	}
+
+	t.Run("restore", func(t *testing.T) {
+		t.Parallel()
+
+		l, export, removePath := createExport(t)
+		defer removePath()
+		dir, removeDir := createPathForTests(t)
+		defer removeDir()
+
+		err := Restore(bytes.NewReader(export), OSFileSystem{}, dir, FlatLayout{})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		restored, errA := New(dir, 100, 10_000, time.Hour)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		got, _, errB := restored.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		want, _, errC := l.ReadAligned(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if string(got) != string(want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(got), string(want))
+		}
+
+		// restoring again would overwrite segments.
+		errD := Restore(bytes.NewReader(export), OSFileSystem{}, dir, FlatLayout{})
+		if !errors.Is(errD, errRestoreExists) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, errRestoreExists)
+		}
+	})
+
+	t.Run("incremental", func(t *testing.T) {
+		t.Parallel()
+
+		l, _, removePath := createExport(t)
+		defer removePath()
+		segs := l.segmentRead()
+
+		buf := &bytes.Buffer{}
+		last, err := l.Export(buf, segs[1].baseOffset)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if last != segs[2].baseOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", last, segs[2].baseOffset)
+		}
+		bases := []uint64{}
+		errA := ReadExport(buf, func(baseOffset uint64, data []byte) error {
+			bases = append(bases, baseOffset)
+			return nil
+		})
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if len(bases) != 1 || bases[0] != segs[2].baseOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", bases, []uint64{segs[2].baseOffset})
+		}
+	})
+
+	t.Run("bad exports are detected", func(t *testing.T) {
+		t.Parallel()
+
+		_, export, removePath := createExport(t)
+		defer removePath()
+
+		corrupt := append([]byte{}, export...)
+		corrupt[len(exportMagic)+2+1+24+5] ^= 0xff // a byte of the data of the first segment.
+		badVersion := append([]byte{}, export...)
+		badVersion[len(exportMagic)+1] = 7
+
+		tt := []struct {
+			name   string
+			export []byte
+		}{
+			{name: "truncated in a segment", export: export[:len(export)/2]},
+			{name: "truncated trailer", export: export[:len(export)-3]},
+			{name: "no trailer", export: export[:len(export)-(1+16+4)]},
+			{name: "corrupt data", export: corrupt},
+			{name: "unknown version", export: badVersion},
+			{name: "not an export", export: []byte("hello world")},
+		}
+		for _, v := range tt {
+			dir, removeDir := createPathForTests(t)
+			err := Restore(bytes.NewReader(v.export), OSFileSystem{}, dir, FlatLayout{})
+			if !errors.Is(err, ErrBadExport) {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, err, ErrBadExport)
+			}
+			// nothing is left behind.
+			files, errA := (&Clog{path: dir, fs: OSFileSystem{}}).listFiles(dir, "")
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+			if len(files) != 0 {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, files, nil)
+			}
+			removeDir()
+		}
+	})
+
+	t.Run("failed restore is rolled back", func(t *testing.T) {
+		t.Parallel()
+
+		_, export, removePath := createExport(t)
+		defer removePath()
+		dir, removeDir := createPathForTests(t)
+		defer removeDir()
+
+		err := Restore(bytes.NewReader(export), &failingRenameFS{n: 2}, dir, FlatLayout{})
+		if err == nil {
+			t.Fatal("expected the restore to fail")
+		}
+		// the segments that were moved into place are gone too.
+		files, errA := os.ReadDir(dir)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if len(files) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", files, nil)
+		}
+	})
+
+	t.Run("older versions are upgraded", func(t *testing.T) {
+		t.Parallel()
+
+		dir, removeDir := createPathForTests(t)
+		defer removeDir()
+
+		err := Restore(bytes.NewReader(exportV2ForTests(5, "order # 1", "order # 2")), OSFileSystem{}, dir, FlatLayout{})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		restored, errA := New(dir, 100, 10_000, time.Hour)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		defer restored.Close()
+		records, _, errB := restored.ReadRecords(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		got := []string{}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		if want := []string{"order # 1", "order # 2"}; !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("range", func(t *testing.T) {
+		t.Parallel()
+
+		l, _, removePath := createExport(t)
+		defer removePath()
+		segs := l.segmentRead()
+
+		buf := &bytes.Buffer{}
+		// the active segment is never exported, even when it is within the range.
+		last, err := l.ExportRange(buf, segs[1].baseOffset, segs[3].baseOffset+1)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if last != segs[2].baseOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", last, segs[2].baseOffset)
+		}
+		bases := []uint64{}
+		errA := ReadExport(buf, func(baseOffset uint64, data []byte) error {
+			bases = append(bases, baseOffset)
+			return nil
+		})
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		want := []uint64{segs[1].baseOffset, segs[2].baseOffset}
+		if !cmp.Equal(bases, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", bases, want)
+		}
+
+		_, errB := l.ExportRange(buf, 7, 7)
+		if !errors.Is(errB, errBadRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, errBadRange)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package clog
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// File is a file, in a FileSystem, that holds the data of a segment.
+// *os.File implements File.
+type File interface {
+	io.ReadWriteCloser
+	Name() string
+	Sync() error
+	Truncate(size int64) error
+}
+
+// FileSystem is the filesystem in which a commitlog is stored.
+//
+// The default is OSFileSystem. A different one can be used by passing WithFileSystem to New;
+// this is mostly useful in tests, see the clogtest package.
+type FileSystem interface {
+	MkdirAll(path string, perm fs.FileMode) error
+	ReadDir(name string) ([]fs.DirEntry, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	Remove(name string) error
+	// Rename should replace newpath, if it exists, atomically.
+	Rename(oldpath, newpath string) error
+}
+
+// OSFileSystem is a FileSystem that is backed by the operating system's filesystem.
+type OSFileSystem struct{}
+
+// MkdirAll calls os.MkdirAll
+func (OSFileSystem) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+
+// ReadDir calls os.ReadDir
+func (OSFileSystem) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+// OpenFile calls os.OpenFile
+func (OSFileSystem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		// do not return a non-nil File interface that holds a nil *os.File.
+		return nil, err
+	}
+	return f, nil
+}
+
+// Stat calls os.Stat
+func (OSFileSystem) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+
+// ReadFile calls os.ReadFile
+func (OSFileSystem) ReadFile(name string) ([]byte, error) { return os.ReadFile(name) }
+
+// Remove calls os.Remove
+func (OSFileSystem) Remove(name string) error { return os.Remove(name) }
+
+// Rename calls os.Rename
+func (OSFileSystem) Rename(oldpath, newpath string) error { return os.Rename(oldpath, newpath) }
+
+// dirSyncer is implemented by a FileSystem whose directories can be synced to stable storage; so that the files created
+// in, or removed from, a directory are durable. A FileSystem that does not implement it has its directories left alone.
+type dirSyncer interface {
+	SyncDir(name string) error
+}
+
+// SyncDir syncs the directory name to stable storage.
+func (OSFileSystem) SyncDir(name string) error {
+	d, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	errS := d.Sync()
+	errC := d.Close()
+	if errS != nil {
+		return errS
+	}
+	return errC
+}
@@ -0,0 +1,323 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrOpenedReadOnly is returned by the operations that would modify a commitlog that was opened with OpenReadOnly.
+var ErrOpenedReadOnly = errors.New("commitLog was opened read-only")
+
+var errNotADir = func(path string) error { return fmt.Errorf("commitLog path %s is not a directory", path) }
+
+// OpenReadOnly opens the commitlog at path for reading only; eg to serve, copy or inspect a commitlog, or to follow, from a
+// sidecar on the same host, a commitlog that another process writes to. opts are those that were passed to New by the
+// writer, in so far as they decide where segments are; eg WithLayout & WithStripes.
+//
+// Nothing in the directory of the commitlog is created, modified or removed; neither by OpenReadOnly nor by the commitlog
+// it returns. Appends, Clean, TagSegment & the other operations that would modify the commitlog fail with
+// ErrOpenedReadOnly. If there is no commitlog at path, an empty one is opened; the directory has to exist, though.
+//
+// The commitlog keeps a view of the segments of the writer, which Refresh brings up to date; periodically if
+// WithPollInterval is passed. The consistency guarantees of a follower are:
+//
+//   - it sees a prefix of what the writer appended; records appear once they have been written whole, in the order they
+//     were appended, & with the offsets & sequence numbers that the writer gave them. Records that the writer has not yet
+//     synced may be seen; if the machine then crashes they may be gone once it restarts, as on the writer.
+//   - segments appear once the writer has recorded them in its manifest; which it does when it creates them. Segments
+//     deleted by the writer, eg by Clean, disappear at the next refresh; a read of such a segment that is in progress
+//     then may fail, since the writer does not know of the readers of the follower.
+//   - WaitForOffset, Iterator, Tail & the other ways to wait for appends are woken up by refreshes that see new data.
+//
+// usage:
+//
+//	l, err := OpenReadOnly("/var/lib/orders", WithPollInterval(100*time.Millisecond, nil))
+//	defer l.Close()
+//	records, err := l.Tail(ctx, 0)
+func OpenReadOnly(path string, opts ...Option) (*Clog, error) {
+	l := &Clog{
+		path:         path,
+		cl:           &cleaner{maxLogBytes: math.MaxUint64, maxLogAge: math.MaxInt64},
+		initialized:  true,
+		maxSegBytes:  math.MaxUint64,
+		fs:           OSFileSystem{},
+		layout:       FlatLayout{},
+		readOnlyOpen: true,
+		stop:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	// the journal records the maintenance of the writer; a follower does none.
+	l.journal = nil
+
+	fi, err := l.fs.Stat(path)
+	if err != nil {
+		return nil, errStatFile(err)
+	}
+	if !fi.IsDir() {
+		return nil, errNotADir(path)
+	}
+
+	l.mu.Lock()
+	errR := l.refresh()
+	l.mu.Unlock()
+	if errR != nil {
+		for _, seg := range l.segments {
+			seg.detach()
+		}
+		return nil, errR
+	}
+
+	if l.poll.interval > 0 {
+		l.background.Add(1)
+		go l.every(l.poll, l.Refresh)
+	}
+	return l, nil
+}
+
+// Refresh brings the view, of a commitlog opened with OpenReadOnly, of the segments of the writer up to date. It only
+// lists the segments of the writer again if the generation of the manifest of the writer has changed; otherwise, it
+// only checks for records appended to the active segment. see OpenReadOnly
+// It does nothing for a commitlog that was opened with New, since that is always up to date.
+func (l *Clog) Refresh() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return errC
+	}
+	if !l.readOnlyOpen {
+		return nil
+	}
+	return l.refresh()
+}
+
+// checkWritable returns ErrOpenedReadOnly if the commitlog was opened with OpenReadOnly. The caller should hold l.mu
+func (l *Clog) checkWritable() error {
+	if l.readOnlyOpen {
+		return ErrOpenedReadOnly
+	}
+	return nil
+}
+
+// refresh is like Refresh. The caller should hold l.mu
+func (l *Clog) refresh() error {
+	m, err := readManifest(l.fs, l.path)
+	if err != nil {
+		return err
+	}
+	if m.version() > FormatVersion {
+		return &FormatVersionError{Path: l.path, Version: m.version(), Supported: FormatVersion}
+	}
+
+	if !l.refreshed || m.Generation != l.generation {
+		errL := l.reloadSegments(m)
+		if errL != nil {
+			return errL
+		}
+		l.generation = m.Generation
+		l.refreshed = true
+	}
+
+	grew := false
+	for _, seg := range l.segments {
+		moved, errC := seg.catchUp()
+		if errC != nil {
+			return errC
+		}
+		grew = grew || moved
+	}
+	if m.version() < FormatVersion && l.highWatermark() != 0 {
+		// the data of an older format can not be read as records.
+		return &FormatVersionError{Path: l.path, Version: m.version(), Supported: FormatVersion}
+	}
+	if grew {
+		l.notifyAppended()
+	}
+	return nil
+}
+
+// reloadSegments makes the segments of the commitlog those that are in the manifest, m, of the writer. Segments that the
+// commitlog already has are kept as they are. The caller should hold l.mu
+func (l *Clog) reloadSegments(m *manifest) error {
+	l.knownStripes = unionStripes(m.Stripes, l.stripes)
+	existing := map[uint64]*segment{}
+	for _, seg := range l.segments {
+		existing[seg.baseOffset] = seg
+	}
+
+	segs := []*segment{}
+	for _, root := range append([]string{l.path}, l.knownStripes...) {
+		files, errA := l.listFiles(root, "")
+		if errA != nil {
+			if errors.Is(errA, fs.ErrNotExist) {
+				continue
+			}
+			return errA
+		}
+		for _, file := range files {
+			if root == l.path && isMetadataFile(file) {
+				continue
+			}
+			n, ok, errB := l.layout.ParseSegmentPath(file)
+			if errB != nil {
+				return errB
+			}
+			meta, inManifest := m.Segments[n]
+			if !ok || (len(m.Segments) > 0 && !inManifest) {
+				// a segment that the writer has not recorded yet is picked up once it has.
+				continue
+			}
+			seg := existing[n]
+			if seg == nil {
+				var errC error
+				seg, errC = openReadOnlySegment(l.fs, filepath.Join(root, file), n)
+				if errors.Is(errC, fs.ErrNotExist) {
+					// deleted by the writer since it was listed.
+					continue
+				}
+				if errC != nil {
+					for _, s := range segs {
+						if existing[s.baseOffset] == nil {
+							s.detach()
+						}
+					}
+					return errC
+				}
+				seg.clock = l.clock
+				seg.firstSeq = meta.FirstSequence
+			}
+			delete(existing, n)
+			seg.setMeta(meta)
+			segs = append(segs, seg)
+		}
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].baseOffset < segs[j].baseOffset })
+	if len(segs) > 0 {
+		// the latest segment is the one the writer appends to.
+		segs[len(segs)-1].setSeal(nil)
+	}
+
+	// the segments that are left were deleted by the writer.
+	for _, seg := range existing {
+		seg.detach()
+		if seg.baseOffset > l.lowWatermark {
+			l.lowWatermark = seg.baseOffset
+		}
+	}
+	l.segments = segs
+	_, errD := deriveSequences(segs)
+	return errD
+}
+
+// openReadOnlySegment opens the segment at filePath for reading only. Its data is not counted until catchUp is called.
+func openReadOnlySegment(fsys FileSystem, filePath string, baseOffset uint64) (*segment, error) {
+	f, err := fsys.OpenFile(filePath, os.O_RDONLY, ownerReadableWritable)
+	if err != nil {
+		return nil, errOpenFile(err)
+	}
+	return &segment{
+		filePath:     filePath,
+		fsys:         fsys,
+		baseOffset:   baseOffset,
+		maxSegBytes:  math.MaxUint64,
+		f:            f,
+		age:          clock(nil).age(baseOffset),
+		recordsKnown: true,
+		readOnly:     true,
+	}, nil
+}
+
+// setMeta sets the tags & seal of a segment of a follower, as recorded in the manifest of the writer.
+func (s *segment) setMeta(meta segmentMeta) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags = meta.Tags
+	s.seal = meta.Seal
+	if s.firstSeq == 0 {
+		s.firstSeq = meta.FirstSequence
+	}
+}
+
+func (s *segment) setSeal(seal *segmentSeal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seal = seal
+}
+
+// catchUp moves the end of a segment of a follower up to the end of the last whole record in its file; or, if the segment
+// is sealed, to the end of the segment. It reports whether it moved.
+func (s *segment) catchUp() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return false, nil
+	}
+	var end uint64
+	if s.seal != nil {
+		end = s.seal.Size
+	} else {
+		fi, err := s.fsys.Stat(s.filePath)
+		if err != nil {
+			return false, errStatFile(err)
+		}
+		end = uint64(fi.Size())
+	}
+	if end <= s.currentSegBytes {
+		return false, nil
+	}
+
+	f, err := s.fsys.OpenFile(s.filePath, os.O_RDONLY, ownerReadableWritable)
+	if err != nil {
+		return false, errSegmentRead(err)
+	}
+	defer f.Close()
+	b := make([]byte, end-s.currentSegBytes)
+	_, errR := readFileAt(f, b, int64(s.currentSegBytes))
+	if errR != nil {
+		return false, errSegmentRead(errR)
+	}
+
+	moved := false
+	for {
+		r, _, rest, ok := splitRecord(b)
+		if !ok {
+			break
+		}
+		s.currentSegBytes = s.currentSegBytes + recordHeaderSize + uint64(len(r))
+		s.records++
+		s.age = s.clock.age(s.baseOffset)
+		b = rest
+		moved = true
+	}
+	return moved, nil
+}
+
+// followerView trims b, the data of the segment as found in the filesystem, to the part of it that the segment has caught
+// up to; the writer may have appended more to a segment of a follower since. see catchUp
+// The caller should hold s.mu
+func (s *segment) followerView(b []byte) []byte {
+	if s.readOnly && s.seal == nil && uint64(len(b)) > s.currentSegBytes {
+		return b[:s.currentSegBytes]
+	}
+	return b
+}
+
+// detach closes the file of a segment of a follower that the writer has deleted; it is then counted as deleted.
+func (s *segment) detach() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return
+	}
+	_ = s.f.Close()
+	s.f = nil
+	s.cache = nil
+}
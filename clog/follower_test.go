@@ -0,0 +1,213 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// dirForTests returns the files in dir, with their contents.
+func dirForTests(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, errR := os.ReadFile(p)
+		files[p] = string(b)
+		return errR
+	})
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	return files
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	t.Parallel()
+
+	records := func(t *testing.T, l *Clog) []string {
+		t.Helper()
+		rs, _, err := l.ReadRecords(0, 0)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		got := []string{}
+		for _, r := range rs {
+			got = append(got, string(r))
+		}
+		return got
+	}
+
+	t.Run("follows the writer", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// each segment holds a single record.
+		w, err := New(path, 10, 60, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer w.Close()
+		for _, r := range []string{"order # 1", "order # 2"} {
+			errA := w.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		before := dirForTests(t, path)
+		f, errO := OpenReadOnly(path)
+		if errO != nil {
+			t.Fatal("\n\t", errO)
+		}
+		defer f.Close()
+		if got, want := records(t, f), []string{"order # 1", "order # 2"}; !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		errA := f.Append([]byte("order # 3"))
+		if !errors.Is(errA, ErrOpenedReadOnly) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, ErrOpenedReadOnly)
+		}
+		errL := f.Clean()
+		if !errors.Is(errL, ErrOpenedReadOnly) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errL, ErrOpenedReadOnly)
+		}
+		if after := dirForTests(t, path); !cmp.Equal(after, before) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", after, before)
+		}
+
+		// new records, & segments, are seen once the follower refreshes.
+		for _, r := range []string{"order # 3", "order # 4"} {
+			errB := w.Append([]byte(r))
+			if errB != nil {
+				t.Fatal("\n\t", errB)
+			}
+		}
+		if got, want := records(t, f), []string{"order # 1", "order # 2"}; !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+		errR := f.Refresh()
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		if got, want := records(t, f), records(t, w); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// the follower gives records the same offsets as the writer.
+		o, errF := w.AppendOffset([]byte("order # 5"))
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+		errR = f.Refresh()
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		_, spans, errS := f.ReadSpans(o.BaseOffset-1, 0)
+		if errS != nil {
+			t.Fatal("\n\t", errS)
+		}
+		if len(spans) == 0 || spans[0].BaseOffset != o.BaseOffset || spans[0].FirstSequence != o.Sequence {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", spans, o)
+		}
+
+		// segments deleted by the writer disappear.
+		errC := w.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		errR = f.Refresh()
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		if got, want := records(t, f), records(t, w); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("partial records are not seen", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		w, err := New(path, 1_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer w.Close()
+		errA := w.Append([]byte("order # 1"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		// a record that the writer is part way through writing.
+		partial := appendRecord(nil, []byte("order # 2"))[:10]
+		seg, errO := os.OpenFile(w.segments[0].filePath, os.O_WRONLY|os.O_APPEND, 0)
+		if errO != nil {
+			t.Fatal("\n\t", errO)
+		}
+		_, errW := seg.Write(partial)
+		_ = seg.Close()
+		if errW != nil {
+			t.Fatal("\n\t", errW)
+		}
+
+		f, errF := OpenReadOnly(path)
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+		defer f.Close()
+		if got, want := records(t, f), []string{"order # 1"}; !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("polling wakes up tails", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		w, err := New(path, 1_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer w.Close()
+
+		f, errO := OpenReadOnly(path, WithPollInterval(time.Millisecond, func(e error) { t.Error("\n\t", e) }))
+		if errO != nil {
+			t.Fatal("\n\t", errO)
+		}
+		defer f.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		tail, errT := f.Tail(ctx, 0)
+		if errT != nil {
+			t.Fatal("\n\t", errT)
+		}
+
+		errA := w.Append([]byte("order # 1"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		r := <-tail
+		if r.Err != nil {
+			t.Fatal("\n\t", r.Err)
+		}
+		if string(r.Record) != "order # 1" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(r.Record), "order # 1")
+		}
+		cancel()
+		for range tail {
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package clog
+
+import "sync"
+
+// groupCommit coalesces the syncs of concurrent appends; so that, rather than each of them paying for an fsync of its
+// own, appends whose data was written while a sync was in progress share the next one.
+//
+// Each append that is to be synced takes a ticket once its data has been written, then waits for a sync that covers its
+// ticket. The first waiter leads a sync, which covers the tickets of all the writes made up to then; the rest wait for it.
+// A nil *groupCommit coalesces nothing; each append makes a sync of its own.
+type groupCommit struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	// written is the ticket of the latest write; synced is the ticket of the latest write known to be synced.
+	written uint64
+	synced  uint64
+	// syncing is set while a sync is in progress.
+	syncing bool
+	// syncs is the number of syncs made.
+	syncs uint64
+}
+
+func newGroupCommit() *groupCommit {
+	g := &groupCommit{}
+	g.cond = sync.NewCond(&g.mu)
+	return g
+}
+
+// wrote returns the ticket of a write that has just been made. Writes should take their tickets in the order that they
+// are made; eg under l.mu
+func (g *groupCommit) wrote() uint64 {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.written++
+	return g.written
+}
+
+// wait returns once the write with ticket has been synced, by a sync that it either leads or shares.
+// sync should sync every write made before it is called.
+//
+// If the sync that it leads fails, the error is returned. The writes that the failed sync was to cover are not synced;
+// the appends that were waiting on it then lead syncs of their own.
+func (g *groupCommit) wait(ticket uint64, sync func() error) error {
+	if g == nil {
+		return sync()
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for g.synced < ticket {
+		if g.syncing {
+			g.cond.Wait()
+			continue
+		}
+		g.syncing = true
+		target := g.written
+		g.mu.Unlock()
+		err := sync()
+		g.mu.Lock()
+		g.syncing = false
+		g.syncs++
+		g.cond.Broadcast()
+		if err != nil {
+			return err
+		}
+		if target > g.synced {
+			g.synced = target
+		}
+	}
+	return nil
+}
+
+// syncActive syncs the active segment of the commitlog; the segments sealed before it were synced when they were closed,
+// & those that failed to be are failed by commit. It is how appends are synced by a groupCommit.
+func (l *Clog) syncActive() error {
+	l.mu.RLock()
+	a, err := l.activeSegment()
+	l.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return a.flush()
+}
@@ -0,0 +1,123 @@
+package clog
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupCommit(t *testing.T) {
+	t.Parallel()
+
+	t.Run("writes made during a sync share the next one", func(t *testing.T) {
+		t.Parallel()
+
+		g := newGroupCommit()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		first := true
+		syncFn := func() error {
+			if first {
+				first = false
+				close(started)
+				<-release
+			}
+			return nil
+		}
+
+		wg := sync.WaitGroup{}
+		errs := make(chan error, 5)
+		t1 := g.wrote()
+		wg.Add(1)
+		go func() { defer wg.Done(); errs <- g.wait(t1, syncFn) }()
+		<-started
+		for i := 0; i < 4; i++ {
+			ticket := g.wrote()
+			wg.Add(1)
+			go func() { defer wg.Done(); errs <- g.wait(ticket, syncFn) }()
+		}
+		close(release)
+		wg.Wait()
+		close(errs)
+
+		for e := range errs {
+			if e != nil {
+				t.Fatal("\n\t", e)
+			}
+		}
+		if g.syncs != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", g.syncs, 2)
+		}
+	})
+
+	t.Run("failed sync", func(t *testing.T) {
+		t.Parallel()
+
+		g := newGroupCommit()
+		errSync := errors.New("disk is on fire")
+		fail := true
+		syncFn := func() error {
+			if fail {
+				fail = false
+				return errSync
+			}
+			return nil
+		}
+
+		t1 := g.wrote()
+		t2 := g.wrote()
+		errA := g.wait(t1, syncFn)
+		if !errors.Is(errA, errSync) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, errSync)
+		}
+		// the write whose sync failed is not counted as synced; the next waiter syncs again.
+		errB := g.wait(t2, syncFn)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if g.synced != t2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", g.synced, t2)
+		}
+	})
+
+	t.Run("concurrent appends", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// a stall threshold of 0 counts every fsync.
+		l, err := New(path, 1_000_000, 100_000_000, time.Hour, WithFsyncStallHandler(0, nil))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		appenders, appends := 20, 10
+		wg := sync.WaitGroup{}
+		for i := 0; i < appenders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < appends; j++ {
+					errA := l.Append([]byte("order"))
+					if errA != nil {
+						t.Error("\n\t", errA)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		records, _, errR := l.ReadRecords(0, 0)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		if len(records) != appenders*appends {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), appenders*appends)
+		}
+		// every append was synced, by at most one fsync each.
+		if syncs := l.FsyncStalls(); syncs == 0 || syncs > uint64(appenders*appends) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", syncs, "between 1 and one fsync per append")
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package clog
+
+import "time"
+
+// SegmentGrowthEvent describes a commitlog whose number of segments has grown past a threshold.
+// see WithSegmentGrowthGuard
+type SegmentGrowthEvent struct {
+	// Path is the directory, in the filesystem, of the commitlog.
+	Path string
+	// Segments is the number of segments of the commitlog.
+	Segments int
+	// Threshold is the threshold that was configured.
+	Threshold int
+	// AutoClean is whether the commitlog cleans itself while it is past the threshold.
+	AutoClean bool
+	// At is when the growth was detected.
+	At time.Time
+}
+
+// growthGuard warns when a commitlog has more than threshold segments, and optionally cleans it.
+// A nil *growthGuard never does.
+//
+// Its fields are protected by the mu of the commitlog.
+type growthGuard struct {
+	threshold int
+	autoClean bool
+	onGrowth  func(SegmentGrowthEvent)
+
+	// warned is true while the commitlog is past the threshold & the growth has been reported.
+	warned bool
+}
+
+// observe records the number of segments of the commitlog and reports whether it has just grown past the threshold.
+func (g *growthGuard) observe(segments int) bool {
+	if g == nil {
+		return false
+	}
+	if segments <= g.threshold {
+		g.warned = false
+		return false
+	}
+	if g.warned {
+		return false
+	}
+	g.warned = true
+	return true
+}
+
+// checkGrowth reports, & if configured cleans, a commitlog that has more segments than the threshold of its growthGuard.
+// It is called whenever a segment is created. The caller should hold l.mu
+func (l *Clog) checkGrowth() {
+	if l.growth == nil {
+		return
+	}
+
+	n := len(l.segments)
+	if l.growth.observe(n) {
+		l.journal.record(OpsSegmentGrowth, "%d segments, past the threshold of %d. is Clean being called?", n, l.growth.threshold)
+		if l.growth.onGrowth != nil {
+			l.growth.onGrowth(SegmentGrowthEvent{
+				Path:      l.path,
+				Segments:  n,
+				Threshold: l.growth.threshold,
+				AutoClean: l.growth.autoClean,
+				At:        l.clock.time(),
+			})
+		}
+	}
+	if l.growth.autoClean && n > l.growth.threshold {
+		// the append that created the segment has not failed; a failure to clean is left for the next segment to retry.
+		_ = l.clean()
+		l.growth.observe(len(l.segments))
+	}
+}
@@ -0,0 +1,85 @@
+package clog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSegmentGrowthGuard(t *testing.T) {
+	t.Parallel()
+
+	t.Run("growth is reported once", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		events := []SegmentGrowthEvent{}
+		l, err := New(path, 10, 100_000, time.Hour, WithSegmentGrowthGuard(3, false, func(e SegmentGrowthEvent) {
+			events = append(events, e)
+		}))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		// each append fills a segment.
+		for i := 0; i < 6; i++ {
+			errA := l.Append([]byte("hello world"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		if len(events) != 1 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", events, "one event")
+		}
+		if events[0].Segments != 4 || events[0].Threshold != 3 || events[0].Path != path || events[0].At.IsZero() {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", events[0], "growth past 3 segments")
+		}
+		// without autoClean, segments are not deleted.
+		if n := len(l.segmentRead()); n != 6 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, 6)
+		}
+
+		journal, errB := l.OpsJournal()
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		growths := 0
+		for _, e := range journal {
+			if e.Op == OpsSegmentGrowth {
+				growths++
+			}
+		}
+		if growths != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", growths, 1)
+		}
+	})
+
+	t.Run("auto clean", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		events := []SegmentGrowthEvent{}
+		// retention is of about 4 segments.
+		maxLogBytes := uint64(4 * (11 + recordHeaderSize))
+		l, err := New(path, 10, maxLogBytes, time.Hour, WithSegmentGrowthGuard(2, true, func(e SegmentGrowthEvent) {
+			events = append(events, e)
+		}))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		for i := 0; i < 20; i++ {
+			errA := l.Append([]byte("hello world"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		if n := len(l.segmentRead()); n > 5 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, "segments to be cleaned")
+		}
+		if len(events) != 1 || !events[0].AutoClean {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", events, "one event")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package clog
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// ingestBatchBytes is about how much data AppendFrom appends each time it takes the lock of the commitlog.
+	ingestBatchBytes = 1000 * 1000 // 1Mb
+	// ingestMaxRecord is the size of the largest record that AppendFrom can split out of a stream.
+	ingestMaxRecord = internalMaxToRead
+)
+
+// AppendFrom ingests the stream r, eg a file or a network connection, into the commitlog; each token that split
+// splits r into is appended as a record. If split is nil, r is split into lines; see bufio.ScanLines.
+// Use ScanRecords to ingest data in the framing of the commitlog, eg data read from another commitlog.
+// It is meant for bulk-loading existing data files; it is not named ReadFrom since it is not an io.ReaderFrom.
+//
+// Records are appended in large batches, each under a single acquisition of the commitlog's lock;
+// with SyncAlways, each batch, rather than each record, is synced to stable storage.
+// Records larger than 64Mb can not be split out of r.
+//
+// It returns the number of records appended. If r, split or an append fails, the records before the failure have
+// been appended; so n tells where to resume from.
+//
+// usage:
+//
+//	f, errO := os.Open("/tmp/orders.jsonl")
+//	n, errA := l.AppendFrom(f, bufio.ScanLines)
+func (l *Clog) AppendFrom(r io.Reader, split bufio.SplitFunc) (n int, err error) {
+	size := 0
+	defer func(start time.Time) { l.tracer.add("append-from", start, size, err) }(time.Now())
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, ingestMaxRecord)
+	if split != nil {
+		sc.Split(split)
+	}
+
+	batch := [][]byte{}
+	batchBytes := 0
+	flush := func() error {
+		appended, errA := l.appendBatch(batch)
+		n = n + appended
+		batch = batch[:0]
+		batchBytes = 0
+		return errA
+	}
+	for sc.Scan() {
+		// the scanner reuses its buffer; so the token is copied.
+		b := append([]byte{}, sc.Bytes()...)
+		batch = append(batch, b)
+		batchBytes = batchBytes + len(b)
+		size = size + len(b)
+		if batchBytes >= ingestBatchBytes {
+			if errA := flush(); errA != nil {
+				return n, errA
+			}
+		}
+	}
+	if errB := flush(); errB != nil {
+		return n, errB
+	}
+	if errC := sc.Err(); errC != nil {
+		return n, fmt.Errorf("ingest failed after %d records: %w", n, errC)
+	}
+	return n, nil
+}
+
+// appendBatch appends bbs, in order, under a single acquisition of l.mu; syncing them once at the end if the SyncPolicy
+// is SyncAlways. It returns the number of items appended, which is less than len(bbs) if it fails.
+func (l *Clog) appendBatch(bbs [][]byte) (n int, err error) {
+	if len(bbs) == 0 {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.initialized {
+		return 0, errLogNotInitialized
+	}
+	if errP := l.waitResumed(context.Background()); errP != nil {
+		return 0, errP
+	}
+	if errC := l.checkOpen(); errC != nil {
+		return 0, errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return 0, errC
+	}
+	if l.guard != nil && l.guard.readOnly {
+		return 0, ErrReadOnly
+	}
+	if l.relocating {
+		return 0, ErrRelocating
+	}
+
+	// touched are the segments that the batch was written to; in order.
+	touched := []uint64{}
+	for _, b := range bbs {
+		o, errA := l.appendActive([][]byte{b}, nil, false)
+		if errA != nil {
+			err = errA
+			break
+		}
+		if len(touched) == 0 || touched[len(touched)-1] != o.BaseOffset {
+			touched = append(touched, o.BaseOffset)
+		}
+		n++
+	}
+	if err == nil && l.syncPolicy == SyncAlways {
+		err = l.syncTouched(touched)
+	}
+	if n > 0 {
+		l.notifyAppended()
+	}
+	l.observeAppend(err)
+	return n, err
+}
+
+// syncTouched syncs the active segment; & fails if any other of the segments with the baseOffsets in touched, which were
+// synced as they were sealed, failed to be. The caller should hold l.mu
+func (l *Clog) syncTouched(touched []uint64) error {
+	for _, baseOffset := range touched {
+		if seg := l.segmentByBaseOffset(baseOffset); seg != nil {
+			if errS := seg.sealError(); errS != nil {
+				return errS
+			}
+		}
+	}
+	a, err := l.activeSegment()
+	if err != nil {
+		return err
+	}
+	return a.flush()
+}
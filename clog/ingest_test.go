@@ -0,0 +1,157 @@
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// failingReader returns the data of r, then fails.
+type failingReader struct {
+	r io.Reader
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestAppendFrom(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lines", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		want := []string{}
+		input := ""
+		for i := 0; i < 50; i++ {
+			r := fmt.Sprintf("order # %d", i)
+			want = append(want, r)
+			input = input + r + "\n"
+		}
+		n, errA := l.AppendFrom(strings.NewReader(input), nil)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if n != len(want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, len(want))
+		}
+
+		data, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		records, errC := Records(data)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		got := []string{}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+		if len(l.segmentRead()) < 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segmentRead()), "segments to be split during the ingest")
+		}
+	})
+
+	t.Run("records of another commitlog", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		src, err := New(path, 100_000, 100_000_000, time.Hour, WithSyncPolicy(SyncOnSeal))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// more than a batch worth of records.
+		msg := []byte(strings.Repeat("a", 1_000))
+		count := 2 * ingestBatchBytes / len(msg)
+		for i := 0; i < count; i++ {
+			errA := src.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		data, _, errB := src.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		path2, removePath2 := createPathForTests(t)
+		defer removePath2()
+		dst, errC := New(path2, 100_000, 100_000_000, time.Hour)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		n, errD := dst.AppendFrom(bytes.NewReader(data), ScanRecords)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if n != count {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, count)
+		}
+		data2, _, errE := dst.Read(0, 0)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		if !bytes.Equal(data2, data) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(data2), len(data))
+		}
+	})
+
+	t.Run("records before a failure are appended", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		n, errA := l.AppendFrom(&failingReader{r: strings.NewReader("hello\nworld\n")}, bufio.ScanLines)
+		if errA == nil {
+			t.Fatal("expected an error")
+		}
+		if n != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, 2)
+		}
+		data, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if recordsForTests(t, data) != "helloworld" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, data), "helloworld")
+		}
+
+		// a torn record, at the end of a stream in the framing of the commitlog, fails the ingest.
+		torn := append(encodeRecord([]byte("order # 1")), encodeRecord([]byte("order # 2"))[:5]...)
+		n2, errC := l.AppendFrom(bytes.NewReader(torn), ScanRecords)
+		if !errors.Is(errC, ErrBadRecord) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, ErrBadRecord)
+		}
+		if n2 != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n2, 1)
+		}
+	})
+}
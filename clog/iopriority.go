@@ -0,0 +1,58 @@
+package clog
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+var errSetIOPriority = func(err error) error { return fmt.Errorf("set io priority failed: %w", err) }
+
+// IOPriority is the priority with which background work, like scrubbing, accesses the disk.
+// Lowering it makes sure that background maintenance does not compete with appends for disk bandwidth.
+type IOPriority uint8
+
+const (
+	// IOPriorityNormal accesses the disk with the same priority as foreground work.
+	IOPriorityNormal IOPriority = iota
+	// IOPriorityLow accesses the disk with the lowest priority of the best-effort scheduling class.
+	IOPriorityLow
+	// IOPriorityIdle only accesses the disk when no other work needs it.
+	IOPriorityIdle
+)
+
+// backgroundIO configures how background work accesses the disk. see WithBackgroundIO
+//
+// It applies to the Scrubber & to the cleaning that WithCleanInterval does in the background. It does not apply to
+// Clog.Clean, which runs on the goroutine that calls it. Note that cleaning holds the commitlog's write lock; a low
+// priority thus also holds back any appends waiting on that lock while segments are being deleted.
+type backgroundIO struct {
+	priority IOPriority
+	// maxBytesPerSec caps the rate at which background work reads/writes. A value of 0 means no cap.
+	maxBytesPerSec uint64
+}
+
+// apply locks the calling goroutine to its OS thread and sets the IO priority of that thread.
+//
+// IO priorities are per OS thread, not per goroutine. Since the goroutine never unlocks the thread,
+// the thread is terminated once the goroutine exits and the lowered priority thus never leaks to other goroutines.
+// apply should therefore only be called from goroutines that are dedicated to background work.
+//
+// On platforms that do not support IO priorities, apply does nothing; maxBytesPerSec is the only control available.
+func (b backgroundIO) apply() error {
+	if b.priority == IOPriorityNormal {
+		return nil
+	}
+
+	runtime.LockOSThread()
+	return setIOPriority(b.priority)
+}
+
+// pause returns how long background work should wait after doing n bytes of IO
+// so as to stay within maxBytesPerSec.
+func (b backgroundIO) pause(n uint64) time.Duration {
+	if b.maxBytesPerSec == 0 {
+		return 0
+	}
+	return time.Duration(float64(n) / float64(b.maxBytesPerSec) * float64(time.Second))
+}
@@ -0,0 +1,35 @@
+//go:build linux
+// +build linux
+
+package clog
+
+import "syscall"
+
+// see: ioprio_set(2)
+const (
+	ioprioWhoProcess = 1
+	ioprioClassShift = 13
+	ioprioClassBE    = 2
+	ioprioClassIdle  = 3
+	ioprioLowestBE   = 7
+)
+
+// setIOPriority sets the IO priority of the calling OS thread.
+func setIOPriority(p IOPriority) error {
+	var prio uintptr
+	switch p {
+	case IOPriorityLow:
+		prio = ioprioClassBE<<ioprioClassShift | ioprioLowestBE
+	case IOPriorityIdle:
+		prio = ioprioClassIdle << ioprioClassShift
+	default:
+		return nil
+	}
+
+	// with IOPRIO_WHO_PROCESS, a `who` of 0 refers to the calling thread.
+	_, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, prio)
+	if errno != 0 {
+		return errSetIOPriority(errno)
+	}
+	return nil
+}
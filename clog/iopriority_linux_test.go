@@ -0,0 +1,55 @@
+package clog
+
+import (
+	"syscall"
+	"testing"
+)
+
+// getIOPriority returns the IO priority of the calling OS thread.
+func getIOPriority() (uintptr, error) {
+	prio, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return prio, nil
+}
+
+func TestApplyIOPriority(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		priority IOPriority
+		want     uintptr
+	}{
+		{priority: IOPriorityLow, want: ioprioClassBE<<ioprioClassShift | ioprioLowestBE},
+		{priority: IOPriorityIdle, want: ioprioClassIdle << ioprioClassShift},
+	}
+	for _, v := range tt {
+		got := make(chan uintptr)
+		errs := make(chan error)
+		// apply locks the goroutine to its thread for the rest of the goroutine's life,
+		// so it has to be called from a goroutine of its own.
+		go func(p IOPriority) {
+			err := backgroundIO{priority: p}.apply()
+			if err != nil {
+				errs <- err
+				return
+			}
+			prio, errA := getIOPriority()
+			if errA != nil {
+				errs <- errA
+				return
+			}
+			got <- prio
+		}(v.priority)
+
+		select {
+		case err := <-errs:
+			t.Fatal("\n\t", err)
+		case prio := <-got:
+			if prio != v.want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", prio, v.want)
+			}
+		}
+	}
+}
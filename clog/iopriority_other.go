@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package clog
+
+// setIOPriority is a no-op on platforms that do not support IO priorities.
+func setIOPriority(p IOPriority) error {
+	return nil
+}
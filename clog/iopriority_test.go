@@ -0,0 +1,68 @@
+package clog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackgroundIOPause(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name           string
+		maxBytesPerSec uint64
+		n              uint64
+		want           time.Duration
+	}{
+		{name: "no cap", maxBytesPerSec: 0, n: 1_000_000, want: 0},
+		{name: "within a second", maxBytesPerSec: 1000, n: 500, want: 500 * time.Millisecond},
+		{name: "many seconds", maxBytesPerSec: 1000, n: 3000, want: 3 * time.Second},
+		{name: "no io", maxBytesPerSec: 1000, n: 0, want: 0},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := backgroundIO{maxBytesPerSec: v.maxBytesPerSec}
+			got := b.pause(v.n)
+			if got != v.want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, v.want)
+			}
+		})
+	}
+}
+
+func TestScrubberWithBackgroundIO(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, e := New(path, 100, 1, time.Nanosecond, WithBackgroundIO(IOPriorityIdle, 1_000_000))
+	if e != nil {
+		t.Fatal("\n\t", e)
+	}
+
+	msg := []byte(strings.Repeat("a", int(l.maxSegBytes*2)))
+	for i := 0; i < 3; i++ {
+		errA := l.Append(msg)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	errs := make(chan error, 100)
+	s, errB := NewScrubber(l, time.Millisecond, func(err error) { errs <- err })
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	s.Start()
+	time.Sleep(30 * time.Millisecond)
+	s.Stop()
+
+	close(errs)
+	for err := range errs {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, nil)
+	}
+}
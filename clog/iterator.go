@@ -0,0 +1,181 @@
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// Iterator walks the records of a commitlog one at a time, oldest first; moving from segment to segment as it goes.
+// Unlike Read, it only holds the records of one segment in memory at a time. see Clog.NewIterator
+//
+// An Iterator is not safe for concurrent use.
+type Iterator struct {
+	l *Clog
+	// after is the offset that the iterator was created with; it starts with the segments after it.
+	after uint64
+	// base is the baseOffset of the segment that records are loaded from; 0 until the first one is.
+	base uint64
+	// pos is the byte position, in the data of that segment, up to which records have been loaded.
+	pos uint64
+	// seq is the sequence number of the record at pos.
+	seq uint64
+	// sealed is whether the segment was sealed when records were last loaded from it.
+	sealed bool
+
+	// records are the records loaded, with their offsets; i is the index of the next one that Next moves to.
+	records [][]byte
+	offsets []RecordOffset
+	i       int
+
+	value  []byte
+	offset RecordOffset
+	err    error
+}
+
+// NewIterator returns an iterator over the records of the commitlog in the segments after startOffset(exclusive); as
+// in Read, a startOffset of 0 starts from the earliest data available.
+//
+// Next returns false once the iterator has caught up with the end of the commitlog. Records appended after that,
+// including to the segment it stopped in, are returned by calling Next again; so an iterator can be used to tail
+// the commitlog.
+//
+// usage:
+//
+//	it := l.NewIterator(0)
+//	for it.Next() {
+//		process(it.Value())
+//	}
+//	if err := it.Err(); err != nil {
+//		log.Fatal(err)
+//	}
+func (l *Clog) NewIterator(startOffset uint64) *Iterator {
+	return &Iterator{l: l, after: startOffset}
+}
+
+// Next moves the iterator to the next record, which is then returned by Value. It returns false if there is no next
+// record yet, or if the iterator has failed; see Err.
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	for it.i >= len(it.records) {
+		loaded, err := it.load()
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if !loaded {
+			return false
+		}
+	}
+
+	it.value = it.records[it.i]
+	it.offset = it.offsets[it.i]
+	it.i++
+	return true
+}
+
+// Value returns the record that the iterator is at. The record is owned by the caller.
+func (it *Iterator) Value() []byte {
+	return it.value
+}
+
+// Offset returns the offset of the record that the iterator is at. see RecordOffset
+func (it *Iterator) Offset() RecordOffset {
+	return it.offset
+}
+
+// Err returns the error, if any, that stopped the iterator. An iterator that has failed stays failed.
+// If segments that the iterator had not finished with were deleted, eg by Clean, it is an *OutOfRangeError.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// load loads the records after the ones loaded so far; from the segment they were in, or from the next segment that
+// has data. It reports whether it moved forward, which it does not if the iterator has caught up.
+func (it *Iterator) load() (moved bool, err error) {
+	size := 0
+	defer func(start time.Time) {
+		if moved || err != nil {
+			it.l.tracer.add("iterate", start, size, err)
+		}
+	}(time.Now())
+
+	it.l.mu.RLock()
+	if errC := it.l.checkOpen(); errC != nil {
+		it.l.mu.RUnlock()
+		return false, errC
+	}
+	if it.base == 0 && it.after != 0 && it.after < it.l.lowWatermark {
+		lw := it.l.lowWatermark
+		it.l.mu.RUnlock()
+		return false, &OutOfRangeError{Offset: it.after, LowWatermark: lw}
+	}
+	segs := it.l.segmentRead()
+	var seg *segment
+	sealed, found := false, false
+	for i, s := range segs {
+		if s.baseOffset == it.base {
+			found = true
+			if s.size() > it.pos {
+				seg, sealed = s, i < len(segs)-1
+				break
+			}
+			continue
+		}
+		if s.baseOffset > it.base && s.baseOffset > it.after && s.size() > 0 {
+			seg, sealed = s, i < len(segs)-1
+			break
+		}
+	}
+	if it.base != 0 && !found && !it.sealed {
+		// the segment was deleted after it was sealed, but before the iterator loaded its latest records.
+		lw := it.l.lowWatermark
+		it.l.mu.RUnlock()
+		return false, &OutOfRangeError{Offset: it.base, LowWatermark: lw}
+	}
+	if seg == nil {
+		it.l.mu.RUnlock()
+		return false, nil
+	}
+	acquired := seg.acquire()
+	it.l.mu.RUnlock()
+	if !acquired {
+		if seg.baseOffset == it.base {
+			return false, &OutOfRangeError{Offset: it.base, LowWatermark: it.base}
+		}
+		// the segment was deleted, eg by Clean, after it was picked; the next load moves past it.
+		return true, nil
+	}
+	defer func() { _ = seg.release() }()
+
+	if seg.baseOffset != it.base {
+		it.base, it.pos, it.seq = seg.baseOffset, 0, seg.firstSeq
+	}
+	it.sealed = sealed
+	// only the data after the records loaded so far is read; so that tailing a segment does not re-read all of it
+	// each time that records are appended to it.
+	n := seg.size() - it.pos
+	// acquire only fails once its ctx is done.
+	reserved, _ := it.l.budget.acquire(context.Background(), n)
+	defer it.l.budget.release(reserved)
+	b, _, err := seg.readRecords(it.pos, it.pos+n)
+	if err != nil {
+		it.l.reportCorruption(err)
+		return false, err
+	}
+	records, err := Records(b)
+	if err != nil {
+		return false, err
+	}
+	size = len(b)
+	it.records = records
+	it.offsets = make([]RecordOffset, 0, len(records))
+	it.i = 0
+	for _, r := range records {
+		it.offsets = append(it.offsets, RecordOffset{BaseOffset: it.base, Position: it.pos, Sequence: it.seq})
+		it.pos = it.pos + recordHeaderSize + uint64(len(r))
+		it.seq++
+	}
+	return true, nil
+}
@@ -0,0 +1,145 @@
+package clog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIterator(t *testing.T) {
+	t.Parallel()
+
+	iterateForTests := func(t *testing.T, it *Iterator) []string {
+		t.Helper()
+		got := []string{}
+		for it.Next() {
+			got = append(got, string(it.Value()))
+		}
+		if err := it.Err(); err != nil {
+			t.Fatal("\n\t", err)
+		}
+		return got
+	}
+
+	t.Run("records across segments", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// each segment holds two records.
+		l, err := New(path, 2*(9+recordHeaderSize), 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		want := []string{"order # 1", "order # 2", "order # 3", "order # 4", "order # 5"}
+		offsets := []RecordOffset{}
+		for _, r := range want {
+			o, errA := l.AppendOffset([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+			offsets = append(offsets, o)
+		}
+
+		it := l.NewIterator(0)
+		got := []string{}
+		gotOffsets := []RecordOffset{}
+		for it.Next() {
+			got = append(got, string(it.Value()))
+			gotOffsets = append(gotOffsets, it.Offset())
+		}
+		if errI := it.Err(); errI != nil {
+			t.Fatal("\n\t", errI)
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+		if !cmp.Equal(gotOffsets, offsets) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", gotOffsets, offsets)
+		}
+
+		// startOffset is excluded, as in Read.
+		got2 := iterateForTests(t, l.NewIterator(offsets[1].BaseOffset))
+		if !cmp.Equal(got2, want[2:]) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got2, want[2:])
+		}
+	})
+
+	t.Run("tail", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		fsys := &readRecordingFS{}
+		l, err := New(path, 1_000, 100_000, time.Hour, WithFileSystem(fsys))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		fsys.reads = nil
+		it := l.NewIterator(0)
+		if got := iterateForTests(t, it); len(got) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "no records")
+		}
+
+		// records appended to the segment that the iterator caught up in are returned.
+		for _, r := range []string{"order # 1", "order # 2"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+			got := iterateForTests(t, it)
+			if !cmp.Equal(got, []string{r}) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, []string{r})
+			}
+		}
+		// each load reads only the records appended since the last one; not the whole segment.
+		fsys.mu.Lock()
+		defer fsys.mu.Unlock()
+		if len(fsys.reads) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.reads, "no whole segment to be read")
+		}
+	})
+
+	t.Run("deleted segments", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// retention is of about two records.
+		l, err := New(path, 10, 2*(9+recordHeaderSize), time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3", "order # 4", "order # 5"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		it := l.NewIterator(0)
+		if !it.Next() {
+			t.Fatal("\n\t", it.Err())
+		}
+		errC := l.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+
+		// the iterator had finished with the segment that it was in, so it moves on.
+		for it.Next() {
+		}
+		if errI := it.Err(); errI != nil {
+			t.Fatal("\n\t", errI)
+		}
+		// an iterator that starts before the deleted segments fails.
+		it2 := l.NewIterator(1)
+		if it2.Next() {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(it2.Value()), "no record")
+		}
+		if !errors.Is(it2.Err(), ErrOffsetOutOfRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", it2.Err(), ErrOffsetOutOfRange)
+		}
+	})
+}
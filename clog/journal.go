@@ -0,0 +1,203 @@
+package clog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// journalName is the name of the file, in the directory of the commitlog, that holds the ops journal.
+	journalName    = "journal.log"
+	journalTmpName = journalName + ".tmp"
+	// maxJournalEntries is the number of entries the journal grows to before it is compacted.
+	// Compaction keeps the latest half of the entries.
+	maxJournalEntries = 1000
+)
+
+// The operations recorded in the ops journal.
+const (
+	// OpsOpen is recorded whenever the commitlog is opened. Its detail has the configuration it was opened with.
+	OpsOpen = "open"
+	// OpsSplit is recorded whenever the active segment is sealed and a new one is created.
+	OpsSplit = "split"
+	// OpsClean is recorded whenever Clean deletes segments.
+	OpsClean = "clean"
+	// OpsTruncate is recorded whenever a segment is truncated to drop the data of a partial write.
+	OpsTruncate = "truncate"
+	// OpsTruncateTail is recorded whenever the records at the end of the commitlog are removed. see Clog.TruncateTail
+	OpsTruncateTail = "truncate-tail"
+	// OpsTag is recorded whenever the tags of a segment are changed. see Clog.TagSegment
+	OpsTag = "tag"
+	// OpsReadOnly is recorded whenever the commitlog enters read-only mode. see WithReadOnlyAfter
+	OpsReadOnly = "read-only"
+	// OpsResumeWrites is recorded whenever the commitlog leaves read-only mode. see Clog.ResumeWrites
+	OpsResumeWrites = "resume-writes"
+	// OpsPauseAppends is recorded whenever appends to the commitlog are paused. see Clog.PauseAppends
+	OpsPauseAppends = "pause-appends"
+	// OpsResumeAppends is recorded whenever appends to the commitlog are resumed. see Clog.ResumeAppends
+	OpsResumeAppends = "resume-appends"
+	// OpsSegmentGrowth is recorded whenever the number of segments grows past the threshold of WithSegmentGrowthGuard.
+	OpsSegmentGrowth = "segment-growth"
+	// OpsRelocate is recorded whenever the commitlog is moved to another directory. see Clog.Relocate
+	OpsRelocate = "relocate"
+	// OpsForceDelete is recorded whenever segments that were still being read are removed. see Clog.ForceDelete
+	OpsForceDelete = "force-delete"
+	// OpsClose is recorded whenever the commitlog is closed. see Clog.Close
+	OpsClose = "close"
+)
+
+var errReadJournal = func(err error) error { return fmt.Errorf("read ops journal failed: %w", err) }
+
+// OpsEvent is an entry in the ops journal of a commitlog.
+type OpsEvent struct {
+	Time time.Time `json:"time"`
+	// Op is the operation, eg OpsSplit
+	Op string `json:"op"`
+	// Detail is a human readable description of the operation.
+	Detail string `json:"detail"`
+}
+
+// opsJournal is a small log, kept next to the segments of a commitlog, of the maintenance done on the commitlog.
+// It allows operators to reconstruct what happened, and when. see ReadOpsJournal
+//
+// Journaling is best effort; failing to journal an operation does not fail the operation.
+type opsJournal struct {
+	fsys  FileSystem
+	dir   string
+	clock clock
+
+	// mu protects entries, last & the journal file.
+	mu      sync.Mutex
+	entries int // -1 until the journal is first written to.
+	// last is the latest event that this journal wrote. see Stats
+	last OpsEvent
+}
+
+func newOpsJournal(fsys FileSystem, dir string) *opsJournal {
+	return &opsJournal{fsys: fsys, dir: dir, entries: -1}
+}
+
+// record adds an event, for op, to the journal.
+// It is a no-op on a nil journal, eg for segments that are not part of a commitlog.
+func (j *opsJournal) record(op string, format string, a ...interface{}) {
+	if j == nil {
+		return
+	}
+	e := OpsEvent{Time: j.clock.time(), Op: op, Detail: fmt.Sprintf(format, a...)}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.entries < 0 {
+		events, errA := ReadOpsJournal(j.fsys, j.dir)
+		if errA != nil {
+			return
+		}
+		j.entries = len(events)
+	}
+	if j.entries >= maxJournalEntries {
+		j.compact()
+	}
+
+	f, errB := j.fsys.OpenFile(filepath.Join(j.dir, journalName), os.O_WRONLY|os.O_CREATE|os.O_APPEND, ownerReadableWritable)
+	if errB != nil {
+		return
+	}
+	defer f.Close()
+	_, errC := f.Write(append(b, '\n'))
+	if errC != nil {
+		return
+	}
+	j.entries++
+	j.last = e
+}
+
+// lastEvent returns the latest event that the journal wrote; the zero OpsEvent for a nil journal.
+func (j *opsJournal) lastEvent() OpsEvent {
+	if j == nil {
+		return OpsEvent{}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.last
+}
+
+// compact drops the older half of the journal.
+// The caller should hold j.mu
+func (j *opsJournal) compact() {
+	events, err := ReadOpsJournal(j.fsys, j.dir)
+	if err != nil {
+		return
+	}
+	// the journal may have been truncated since its entries were counted.
+	if len(events) > maxJournalEntries/2 {
+		events = events[len(events)-maxJournalEntries/2:]
+	}
+
+	buf := &bytes.Buffer{}
+	for _, e := range events {
+		b, errA := json.Marshal(e)
+		if errA != nil {
+			return
+		}
+		buf.Write(b)
+		buf.WriteByte('\n')
+	}
+	errB := writeFileAtomic(j.fsys, filepath.Join(j.dir, journalName), filepath.Join(j.dir, journalTmpName), buf.Bytes())
+	if errB != nil {
+		return
+	}
+	j.entries = len(events)
+}
+
+// ReadOpsJournal returns the events in the ops journal of the commitlog in dir; oldest first.
+// The commitlog does not need to be open, so this can be used by tools to inspect a commitlog that is in use.
+//
+// The ops journal records maintenance done on a commitlog; see OpsOpen, OpsSplit, OpsClean, OpsTruncate, OpsTag, OpsReadOnly & OpsResumeWrites.
+// Only the latest few hundred events are kept.
+func ReadOpsJournal(fsys FileSystem, dir string) ([]OpsEvent, error) {
+	b, err := fsys.ReadFile(filepath.Join(dir, journalName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, errReadJournal(err)
+	}
+
+	events := []OpsEvent{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		e := OpsEvent{}
+		errA := json.Unmarshal(sc.Bytes(), &e)
+		if errA != nil {
+			// a torn write, from a crash, of the last entry.
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// OpsJournal returns the events in the ops journal of the commitlog; oldest first. see ReadOpsJournal
+func (l *Clog) OpsJournal() ([]OpsEvent, error) {
+	if l.journal == nil {
+		return ReadOpsJournal(l.fs, l.Path())
+	}
+	// the journal moves along with the commitlog; see Relocate
+	l.journal.mu.Lock()
+	defer l.journal.mu.Unlock()
+	return ReadOpsJournal(l.fs, l.journal.dir)
+}
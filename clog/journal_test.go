@@ -0,0 +1,155 @@
+package clog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOpsJournal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("maintenance is recorded & persisted", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10, 15, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 0; i < 3; i++ {
+			errA := l.Append([]byte("0123456789"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		a, _ := l.activeSegment()
+		errB := l.TagSegment(a.baseOffset, map[string]string{"owner": "billing"})
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		errC := l.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+
+		// reopening is recorded too.
+		l2, errD := New(path, 10, 15, time.Hour)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		events, errE := l2.OpsJournal()
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+
+		got := []string{}
+		for _, e := range events {
+			got = append(got, e.Op)
+			if e.Time.IsZero() || e.Detail == "" {
+				t.Errorf("incomplete event: %#+v", e)
+			}
+		}
+		want := []string{OpsOpen, OpsSplit, OpsSplit, OpsTag, OpsClean, OpsOpen}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// the journal is not mistaken for a segment.
+		if len(l2.segmentRead()) != len(l.segmentRead()) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l2.segmentRead()), len(l.segmentRead()))
+		}
+	})
+
+	t.Run("truncation is recorded", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		s, _ := l.activeSegment()
+		s.f = mockFileFail{shortWrite: true, fName: s.f.Name()}
+		_ = s.Append([]byte("hello"))
+
+		events, errA := l.OpsJournal()
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		last := events[len(events)-1]
+		if last.Op != OpsTruncate {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", last.Op, OpsTruncate)
+		}
+	})
+
+	t.Run("journal is compacted", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		j := newOpsJournal(OSFileSystem{}, path)
+		for i := 0; i < maxJournalEntries+10; i++ {
+			j.record(OpsSplit, "event %d", i)
+		}
+
+		events, err := ReadOpsJournal(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if len(events) > maxJournalEntries {
+			t.Errorf("journal has %d events, wanted at most %d", len(events), maxJournalEntries)
+		}
+		want := fmt.Sprintf("event %d", maxJournalEntries+9)
+		if got := events[len(events)-1].Detail; got != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("journal truncated behind its back", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		j := newOpsJournal(OSFileSystem{}, path)
+		for i := 0; i < maxJournalEntries-1; i++ {
+			j.record(OpsSplit, "event %d", i)
+		}
+		// eg by an operator; the journal still counts the entries it had.
+		errT := os.Truncate(filepath.Join(path, journalName), 0)
+		if errT != nil {
+			t.Fatal("\n\t", errT)
+		}
+		j.record(OpsSplit, "event %d", maxJournalEntries-1)
+		j.record(OpsSplit, "event %d", maxJournalEntries)
+
+		events, err := ReadOpsJournal(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		want := fmt.Sprintf("event %d", maxJournalEntries)
+		if len(events) == 0 || events[len(events)-1].Detail != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", events, want)
+		}
+	})
+
+	t.Run("no journal", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		events, err := ReadOpsJournal(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if len(events) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", events, nil)
+		}
+	})
+}
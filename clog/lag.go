@@ -0,0 +1,75 @@
+package clog
+
+import "time"
+
+// Lag is how far behind the end of a commitlog a consumer is. see Clog.Lag
+type Lag struct {
+	// Records is the number of records that the consumer has not yet read. see RecordOffset
+	Records uint64
+	// Segments is the number of segments that the consumer has not yet read.
+	Segments int
+	// Bytes is the size of the data that the consumer has not yet read.
+	Bytes uint64
+	// Time is the age of the oldest segment that the consumer has not yet read.
+	// Since a segment's baseOffset is the time at which it was created, this is how long ago the oldest unread data
+	// could have been appended; it is an upper bound.
+	Time time.Duration
+}
+
+// Lag returns how far behind the end of the commitlog a consumer, that last read up to offset, is.
+// offset is the lastReadOffset returned by Read, it is excluded like in Read; an offset of 0 means that nothing has been read.
+// Records are counted using their sequence numbers; from the first record of the oldest unread segment up to the
+// last record appended.
+//
+// It is meant for operators to alert on consumers that are falling behind.
+// If some data after offset has been deleted, an *OutOfRangeError is returned; as in Read.
+//
+// usage:
+//
+//	lag, err := l.Lag(consumerOffset)
+//	metrics.Gauge("consumer.lag.bytes", lag.Bytes)
+func (l *Clog) Lag(offset uint64) (Lag, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return Lag{}, errC
+	}
+	if offset != 0 && offset < l.lowWatermark {
+		return Lag{}, &OutOfRangeError{Offset: offset, LowWatermark: l.lowWatermark}
+	}
+
+	lag := Lag{}
+	var firstUnread uint64
+	segs := l.segmentRead()
+	for _, seg := range segs {
+		if seg.baseOffset <= offset {
+			continue
+		}
+		size := seg.size()
+		if size == 0 {
+			// an empty segment, eg a freshly created active one, has nothing to read.
+			continue
+		}
+		if lag.Segments == 0 {
+			firstUnread = seg.firstSeq
+			now := l.clock.now()
+			if now > seg.baseOffset {
+				lag.Time = time.Duration(now - seg.baseOffset)
+			}
+		}
+		lag.Segments++
+		lag.Bytes = lag.Bytes + size
+	}
+	if lag.Segments > 0 {
+		// the sequence numbers of the records carry on from one segment to the next; so those of the latest segment end
+		// where the commitlog does.
+		latest := segs[len(segs)-1]
+		n, err := latest.recordCount()
+		if err != nil {
+			return Lag{}, err
+		}
+		lag.Records = latest.firstSeq + n - firstUnread
+	}
+	return lag, nil
+}
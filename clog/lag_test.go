@@ -0,0 +1,102 @@
+package clog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLag(t *testing.T) {
+	t.Parallel()
+
+	t.Run("lag shrinks as the consumer reads", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// each append fills a segment.
+		for i := 0; i < 3; i++ {
+			errA := l.Append([]byte(strings.Repeat("a", 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		lag, errB := l.Lag(0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		// each segment holds a record of 100 bytes; framed.
+		size := uint64(100 + recordHeaderSize)
+		if lag.Records != 3 || lag.Segments != 3 || lag.Bytes != 3*size || lag.Time <= 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lag, Lag{Records: 3, Segments: 3, Bytes: 3 * size})
+		}
+
+		segs := l.segmentRead()
+		lag2, errC := l.Lag(segs[0].baseOffset)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if lag2.Records != 2 || lag2.Segments != 2 || lag2.Bytes != 2*size || lag2.Time > lag.Time {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lag2, Lag{Records: 2, Segments: 2, Bytes: 2 * size})
+		}
+
+		// a consumer that is caught up has no lag.
+		_, last, errD := l.Read(0, 0)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		lag3, errE := l.Lag(last)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		if lag3 != (Lag{}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lag3, Lag{})
+		}
+
+		// records, unlike segments, are counted one by one.
+		errF := l.AppendBulk([][]byte{[]byte("a"), []byte("b")})
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+		lag4, errG := l.Lag(last)
+		if errG != nil {
+			t.Fatal("\n\t", errG)
+		}
+		if lag4.Records != 2 || lag4.Segments != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lag4, Lag{Records: 2, Segments: 1})
+		}
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 150, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 0; i < 4; i++ {
+			errA := l.Append([]byte(strings.Repeat("a", 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		first := l.segmentRead()[0].baseOffset
+		errB := l.Clean()
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		_, errC := l.Lag(first)
+		if !errors.Is(errC, ErrOffsetOutOfRange) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, ErrOffsetOutOfRange)
+		}
+	})
+}
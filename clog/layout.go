@@ -0,0 +1,88 @@
+package clog
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout decides where, within the directory of a commitlog, the file of each segment lives.
+//
+// The default is FlatLayout with the ".log" suffix. A different one can be used by passing WithLayout to New.
+type Layout interface {
+	// SegmentPath returns the path, relative to the directory of the commitlog, of the segment with baseOffset.
+	SegmentPath(baseOffset uint64) string
+	// ParseSegmentPath is the inverse of SegmentPath.
+	// It returns the baseOffset of the segment whose relative path is rel,
+	// ok is false if rel is not the path of a segment.
+	ParseSegmentPath(rel string) (baseOffset uint64, ok bool, err error)
+}
+
+func suffixOrDefault(suffix string) string {
+	if suffix == "" {
+		return lFileSuffix
+	}
+	return suffix
+}
+
+// parseSegmentName parses a segment's file name; <baseOffset><suffix>
+func parseSegmentName(name string, suffix string) (uint64, bool, error) {
+	if !strings.HasSuffix(name, suffix) {
+		return 0, false, nil
+	}
+	// files are given names that have the timestamp in utc before the suffix, see tNow()
+	n, err := strconv.ParseUint(strings.TrimSuffix(name, suffix), 10, 64)
+	if err != nil {
+		return 0, false, errParseToInt64(err)
+	}
+	return n, true, nil
+}
+
+// FlatLayout stores all segments directly in the directory of the commitlog, in files named <baseOffset><Suffix>.
+type FlatLayout struct {
+	// Suffix is the extension of segment files. It defaults to ".log"
+	Suffix string
+}
+
+// SegmentPath implements Layout
+func (f FlatLayout) SegmentPath(baseOffset uint64) string {
+	return fmt.Sprintf("%d%s", baseOffset, suffixOrDefault(f.Suffix))
+}
+
+// ParseSegmentPath implements Layout
+func (f FlatLayout) ParseSegmentPath(rel string) (uint64, bool, error) {
+	if filepath.Base(rel) != rel {
+		// segments are never in subdirectories.
+		return 0, false, nil
+	}
+	return parseSegmentName(rel, suffixOrDefault(f.Suffix))
+}
+
+// DatedLayout stores segments in subdirectories named after the UTC date on which the segment was created;
+// YYYY/MM/DD/<baseOffset><Suffix>
+type DatedLayout struct {
+	// Suffix is the extension of segment files. It defaults to ".log"
+	Suffix string
+}
+
+// SegmentPath implements Layout
+func (d DatedLayout) SegmentPath(baseOffset uint64) string {
+	// baseOffsets are timestamps, see tNow()
+	t := time.Unix(0, int64(baseOffset)).UTC()
+	return filepath.Join(
+		fmt.Sprintf("%04d", t.Year()),
+		fmt.Sprintf("%02d", t.Month()),
+		fmt.Sprintf("%02d", t.Day()),
+		fmt.Sprintf("%d%s", baseOffset, suffixOrDefault(d.Suffix)),
+	)
+}
+
+// ParseSegmentPath implements Layout
+func (d DatedLayout) ParseSegmentPath(rel string) (uint64, bool, error) {
+	if strings.Count(rel, string(filepath.Separator)) != 3 {
+		return 0, false, nil
+	}
+	return parseSegmentName(filepath.Base(rel), suffixOrDefault(d.Suffix))
+}
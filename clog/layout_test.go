@@ -0,0 +1,176 @@
+package clog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlatLayout(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		layout   FlatLayout
+		rel      string
+		wantBase uint64
+		wantOk   bool
+		wantErr  bool
+	}{
+		{name: "default suffix", layout: FlatLayout{}, rel: "1618486337000000000.log", wantBase: 1618486337000000000, wantOk: true},
+		{name: "custom suffix", layout: FlatLayout{Suffix: ".seg"}, rel: "17.seg", wantBase: 17, wantOk: true},
+		{name: "other suffix is ignored", layout: FlatLayout{Suffix: ".seg"}, rel: "17.log", wantOk: false},
+		{name: "subdirectories are ignored", layout: FlatLayout{}, rel: "2021/17.log", wantOk: false},
+		{name: "mis-named file", layout: FlatLayout{}, rel: "Malema-1.log", wantErr: true},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			t.Parallel()
+
+			base, ok, err := v.layout.ParseSegmentPath(v.rel)
+			if (err != nil) != v.wantErr {
+				t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, v.wantErr)
+			}
+			if ok != v.wantOk {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ok, v.wantOk)
+			}
+			if base != v.wantBase {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", base, v.wantBase)
+			}
+			if ok {
+				if v.layout.SegmentPath(base) != v.rel {
+					t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", v.layout.SegmentPath(base), v.rel)
+				}
+			}
+		})
+	}
+}
+
+func TestDatedLayout(t *testing.T) {
+	t.Parallel()
+
+	base := uint64(time.Date(2021, 4, 15, 11, 32, 0, 0, time.UTC).UnixNano())
+	d := DatedLayout{Suffix: ".seg"}
+
+	rel := d.SegmentPath(base)
+	want := filepath.Join("2021", "04", "15", "1618486320000000000.seg")
+	if rel != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", rel, want)
+	}
+
+	got, ok, err := d.ParseSegmentPath(rel)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	if !ok || got != base {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, base)
+	}
+
+	_, okA, errA := d.ParseSegmentPath("1618486320000000000.seg")
+	if errA != nil || okA {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", okA, false)
+	}
+}
+
+func TestClogWithDatedLayout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("open, split & reopen", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 1, time.Hour, WithLayout(DatedLayout{}))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		msg := []byte(strings.Repeat("a", 200))
+		for i := 0; i < 3; i++ {
+			errA := l.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		for _, seg := range l.segments {
+			want := filepath.Join(path, DatedLayout{}.SegmentPath(seg.baseOffset))
+			if seg.filePath != want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", seg.filePath, want)
+			}
+			_, errB := os.Stat(want)
+			if errB != nil {
+				t.Error("\n\t", errB)
+			}
+		}
+
+		l2, errC := New(path, 100, 1, time.Hour, WithLayout(DatedLayout{}))
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if len(l2.segments) != 3 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l2.segments), 3)
+		}
+		data, _, errD := l2.Read(0, 0)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if got := recordsForTests(t, data); len(got) != 600 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), 600)
+		}
+	})
+
+	t.Run("clean removes empty directories", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		// create segments, in the current format, that were created on different days.
+		errM := (&manifest{Version: FormatVersion}).write(OSFileSystem{}, path)
+		if errM != nil {
+			t.Fatal("\n\t", errM)
+		}
+		d := DatedLayout{}
+		bases := []uint64{
+			uint64(time.Date(2021, 4, 13, 0, 0, 0, 0, time.UTC).UnixNano()),
+			uint64(time.Date(2021, 4, 14, 0, 0, 0, 0, time.UTC).UnixNano()),
+			uint64(time.Date(2021, 5, 15, 0, 0, 0, 0, time.UTC).UnixNano()),
+		}
+		for _, b := range bases {
+			p := filepath.Join(path, d.SegmentPath(b))
+			errA := os.MkdirAll(filepath.Dir(p), ownerReadableWritable)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+			errB := os.WriteFile(p, encodeRecord([]byte("hello")), ownerReadableWritable)
+			if errB != nil {
+				t.Fatal("\n\t", errB)
+			}
+		}
+
+		l, err := New(path, 100, 1, time.Hour, WithLayout(d))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errC := l.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if len(l.segments) != 1 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segments), 1)
+		}
+
+		_, errD := os.Stat(filepath.Join(path, "2021", "04"))
+		if !errors.Is(errD, os.ErrNotExist) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, os.ErrNotExist)
+		}
+		_, errE := os.Stat(filepath.Join(path, "2021", "05", "15"))
+		if errE != nil {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errE, nil)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package clog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// manifestName is the name of the file, in the directory of the commitlog, that holds metadata about its segments.
+	manifestName = "manifest.json"
+	// manifestTmpName is where a new manifest is written before it replaces the old one.
+	manifestTmpName = manifestName + ".tmp"
+)
+
+// FormatVersion is the version of the on-disk format of the commitlogs that this package writes.
+// It is stamped into the manifest of every commitlog that is opened. A commitlog with no stamp predates versioning,
+// and is of version 1.
+//
+// The versions are:
+//
+//	1: segments hold the appended data as is.
+//	2: segments hold records framed with their length. see Records
+//	3: records are also framed with a checksum.
+const FormatVersion = 3
+
+// FormatVersionError is returned by New when the commitlog was written in an on-disk format that this package does not support;
+// a newer one, eg by a newer version of this package during a rolling upgrade that has since been rolled back,
+// or an older one whose records are not framed the way this package frames them.
+// Writing into such a commitlog could corrupt it, so it is not opened.
+// A commitlog of an older version that holds no data is upgraded, rather than refused.
+type FormatVersionError struct {
+	// Path is the directory of the commitlog.
+	Path string
+	// Version is the format version of the commitlog.
+	Version int
+	// Supported is the latest format version supported; see FormatVersion
+	Supported int
+}
+
+func (e *FormatVersionError) Error() string {
+	return fmt.Sprintf("commitlog %s is of format version %d, this package only supports format version %d",
+		e.Path, e.Version, e.Supported)
+}
+
+var (
+	errReadManifest  = func(err error) error { return fmt.Errorf("read manifest failed: %w", err) }
+	errWriteManifest = func(err error) error { return fmt.Errorf("write manifest failed: %w", err) }
+)
+
+// manifest holds metadata about the segments of a commitlog; metadata that cannot be derived from the segment files.
+// It is stored as JSON in the directory of the commitlog. see manifestName
+type manifest struct {
+	// Version is the on-disk format version of the commitlog. see FormatVersion
+	Version int `json:"version,omitempty"`
+	// Stripes are the directories, besides that of the commitlog, that may hold segments. see WithStripes
+	Stripes []string `json:"stripes,omitempty"`
+	// Segments is keyed by the baseOffset of the segment.
+	Segments map[uint64]segmentMeta `json:"segments,omitempty"`
+	// Generation is incremented each time the manifest is written; so that readers of the commitlog, in other
+	// processes, can tell that its segments have changed. see OpenReadOnly
+	Generation uint64 `json:"generation,omitempty"`
+	// RelocatedTo is, for the directory that a commitlog was copied away from, where the commitlog now is.
+	// see Clog.Relocate
+	RelocatedTo string `json:"relocatedTo,omitempty"`
+}
+
+// segmentMeta is the metadata of a single segment.
+type segmentMeta struct {
+	Tags map[string]string `json:"tags,omitempty"`
+	// FirstSequence is the sequence number of the first record of the segment. 0 if it is not known.
+	FirstSequence uint64 `json:"firstSequence,omitempty"`
+	// Seal is the size & checksum of the data of the segment when it was sealed; nil if it is not sealed, or if it
+	// was sealed before seals were recorded.
+	Seal *segmentSeal `json:"seal,omitempty"`
+}
+
+// segmentSeal is the size & checksum of the data of a sealed segment; which, being sealed, should never change again.
+// It allows changes made to the segment file from outside the commitlog to be detected. see ErrExternallyModified
+type segmentSeal struct {
+	Size uint64 `json:"size"`
+	// Checksum is the crc32(Castagnoli) of the data.
+	Checksum uint32 `json:"checksum"`
+}
+
+// isMetadataFile reports whether the file, whose path is rel relative to the directory of the commitlog,
+// holds metadata of the commitlog, eg the manifest, rather than a segment.
+func isMetadataFile(rel string) bool {
+	switch rel {
+	case manifestName, manifestTmpName, journalName, journalTmpName:
+		return true
+	}
+	return false
+}
+
+// readManifest reads the manifest of the commitlog in dir.
+// A commitlog that has no manifest yet gets an empty one.
+func readManifest(fsys FileSystem, dir string) (*manifest, error) {
+	m := &manifest{}
+	b, err := fsys.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return m, nil
+		}
+		return nil, errReadManifest(err)
+	}
+
+	errA := json.Unmarshal(b, m)
+	if errA != nil {
+		return nil, errReadManifest(errA)
+	}
+	if m.RelocatedTo != "" {
+		return nil, errRelocated(dir, m.RelocatedTo)
+	}
+	return m, nil
+}
+
+// version returns the format version of the commitlog that the manifest belongs to.
+func (m *manifest) version() int {
+	if m.Version == 0 {
+		// the commitlog predates versioning.
+		return 1
+	}
+	return m.Version
+}
+
+// write stores the manifest in dir.
+// It is written atomically; so that a crash never leaves behind a partial manifest.
+func (m *manifest) write(fsys FileSystem, dir string) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return errWriteManifest(err)
+	}
+
+	errA := writeFileAtomic(fsys, filepath.Join(dir, manifestName), filepath.Join(dir, manifestTmpName), b)
+	if errA != nil {
+		return errWriteManifest(errA)
+	}
+	return nil
+}
+
+// writeFileAtomic replaces the contents of the file name with b.
+// b is written to, and synced in, the temporary file tmp which then replaces name; so that name is either
+// left as it was or has all of b.
+func writeFileAtomic(fsys FileSystem, name string, tmp string, b []byte) error {
+	f, err := fsys.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, ownerReadableWritable)
+	if err != nil {
+		return err
+	}
+	_, errA := f.Write(b)
+	if errA != nil {
+		_ = f.Close()
+		return errA
+	}
+	errB := f.Sync()
+	if errB != nil {
+		_ = f.Close()
+		return errB
+	}
+	errC := f.Close()
+	if errC != nil {
+		return errC
+	}
+
+	return fsys.Rename(tmp, name)
+}
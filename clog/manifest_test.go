@@ -0,0 +1,131 @@
+package clog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing manifest", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		m, err := readManifest(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if len(m.Segments) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.Segments, nil)
+		}
+	})
+
+	t.Run("write then read", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		m := &manifest{Segments: map[uint64]segmentMeta{7: {Tags: map[string]string{"a": "b"}}}}
+		err := m.write(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		got, errA := readManifest(OSFileSystem{}, path)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if !cmp.Equal(got, m) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, m)
+		}
+
+		// no temporary file is left behind.
+		_, errB := os.Stat(filepath.Join(path, manifestTmpName))
+		if !os.IsNotExist(errB) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, "not exist")
+		}
+	})
+
+	t.Run("corrupt manifest", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		err := os.WriteFile(filepath.Join(path, manifestName), []byte("{not json"), 0o600)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		_, errA := readManifest(OSFileSystem{}, path)
+		if errA == nil {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, "error")
+		}
+	})
+
+	t.Run("format version is stamped", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		// a commitlog that predates versioning has no manifest.
+		m, err := readManifest(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if m.version() != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.version(), 1)
+		}
+
+		_, errA := New(path, 100, 10_000, time.Hour)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		m2, errB := readManifest(OSFileSystem{}, path)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if m2.Version != FormatVersion {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m2.Version, FormatVersion)
+		}
+	})
+
+	t.Run("newer format version is refused", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		m := &manifest{Version: FormatVersion + 1}
+		err := m.write(OSFileSystem{}, path)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		_, errA := New(path, 100, 10_000, time.Hour)
+		var fe *FormatVersionError
+		if !errors.As(errA, &fe) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, &FormatVersionError{})
+		}
+		want := &FormatVersionError{Path: path, Version: FormatVersion + 1, Supported: FormatVersion}
+		if !cmp.Equal(fe, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fe, want)
+		}
+		// nothing is written into the commitlog.
+		files, errB := os.ReadDir(path)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(files) != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(files), 1)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package clog
+
+// RecordOffset is where, in the commitlog, a record was appended. It lets producers refer back to what they wrote.
+// see Clog.AppendOffset
+type RecordOffset struct {
+	// BaseOffset is the baseOffset of the segment that holds the record.
+	// A Read from any offset below it, eg BaseOffset-1, reads the record; & WaitForOffset(ctx, BaseOffset) waits for it.
+	BaseOffset uint64
+	// Position is the byte position of the record, framing included, within the data of its segment.
+	// In the data returned by ReadSpans, the record starts at Start+Position of the span of its segment.
+	Position uint64
+	// Sequence is the sequence number of the record. The records of a commitlog are numbered, in the order in which
+	// they were appended, from 1; & the numbering carries on across segments, & across opens of the commitlog.
+	// Unlike baseOffsets, which are derived from the time at which segments are created, sequence numbers identify
+	// individual records.
+	Sequence uint64
+}
+
+// firstSequence is the sequence number of the first record of a commitlog.
+const firstSequence = 1
+
+// deriveSequences sets the sequence number of the first record of each of segs, which should be sorted by baseOffset,
+// whose manifest did not have it; eg because of a crash between the creation of the segment & the write of the
+// manifest, or because the commitlog predates sequence numbers.
+// It carries on from the segment before, whose records are counted. It reports whether any sequence number was derived.
+func deriveSequences(segs []*segment) (bool, error) {
+	derived := false
+	for i, seg := range segs {
+		if seg.firstSeq != 0 {
+			continue
+		}
+		derived = true
+		if i == 0 {
+			seg.firstSeq = firstSequence
+			continue
+		}
+		prev := segs[i-1]
+		n, err := prev.recordCount()
+		if err != nil {
+			return derived, err
+		}
+		seg.firstSeq = prev.firstSeq + n
+	}
+	return derived, nil
+}
+
+// AppendOffset is Append, but it also returns the offset that the record was appended at.
+func (l *Clog) AppendOffset(b []byte) (RecordOffset, error) {
+	return l.append(b, l.syncPolicy == SyncAlways)
+}
+
+// AppendBulkOffsets is AppendBulk, but it also returns the offsets that the records were appended at; in order.
+// The records are appended to the same segment, one after the other.
+func (l *Clog) AppendBulkOffsets(bbs [][]byte) ([]RecordOffset, error) {
+	first, err := l.appendBulkTraced(bbs)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make([]RecordOffset, 0, len(bbs))
+	pos := first.Position
+	for i, b := range bbs {
+		offsets = append(offsets, RecordOffset{BaseOffset: first.BaseOffset, Position: pos, Sequence: first.Sequence + uint64(i)})
+		pos = pos + recordHeaderSize + uint64(len(b))
+	}
+	return offsets, nil
+}
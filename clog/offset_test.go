@@ -0,0 +1,188 @@
+package clog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAppendOffset(t *testing.T) {
+	t.Parallel()
+
+	t.Run("offsets point at the records", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 30, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		msgs := []string{"order # 1", "order # 2", "order # 3", "order # 4"}
+		offsets := []RecordOffset{}
+		for _, m := range msgs {
+			o, errA := l.AppendOffset([]byte(m))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+			offsets = append(offsets, o)
+		}
+		bulk, errB := l.AppendBulkOffsets([][]byte{[]byte("order # 5"), []byte("order # 6")})
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		msgs = append(msgs, "order # 5", "order # 6")
+		offsets = append(offsets, bulk...)
+
+		data, spans, errC := l.ReadSpans(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if len(spans) < 2 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", spans, "records across several segments")
+		}
+		for i, o := range offsets {
+			var span *ReadSpan
+			for j := range spans {
+				if spans[j].BaseOffset == o.BaseOffset {
+					span = &spans[j]
+				}
+			}
+			if span == nil {
+				t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", o, "the offset of a segment that was read")
+			}
+			r, _, _, ok := splitRecord(data[span.Start+o.Position : span.End])
+			if !ok || string(r) != msgs[i] {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(r), msgs[i])
+			}
+
+			// a read from below the offset reads the record.
+			got, _, errD := l.Read(o.BaseOffset-1, 0)
+			if errD != nil {
+				t.Fatal("\n\t", errD)
+			}
+			if len(got) <= int(o.Position) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(got), "the record to be read")
+			}
+		}
+	})
+
+	t.Run("failed appends have no offset", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 30, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errA := l.PauseAppends()
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		o, errB := l.AppendOffset([]byte("hello"))
+		if errB == nil || o != (RecordOffset{}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []interface{}{o, errB}, ErrPaused)
+		}
+		offsets, errC := l.AppendBulkOffsets([][]byte{[]byte("hello")})
+		if errC == nil || offsets != nil {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []interface{}{offsets, errC}, ErrPaused)
+		}
+	})
+}
+
+func TestSequences(t *testing.T) {
+	t.Parallel()
+
+	appendForTests := func(t *testing.T, l *Clog, n int) []uint64 {
+		t.Helper()
+		seqs := []uint64{}
+		for i := 0; i < n; i++ {
+			o, err := l.AppendOffset([]byte("order"))
+			if err != nil {
+				t.Fatal("\n\t", err)
+			}
+			seqs = append(seqs, o.Sequence)
+		}
+		return seqs
+	}
+
+	t.Run("sequences carry on across segments & opens", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// two records fill a segment.
+		l, err := New(path, 2*(recordHeaderSize+5), 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		got := appendForTests(t, l, 5)
+		bulk, errA := l.AppendBulkOffsets([][]byte{[]byte("order"), []byte("order")})
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		for _, o := range bulk {
+			got = append(got, o.Sequence)
+		}
+
+		l2, errB := New(path, 2*(recordHeaderSize+5), 10_000, time.Hour)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		got = append(got, appendForTests(t, l2, 2)...)
+		want := []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		_, spans, errC := l2.ReadSpans(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		firsts := []uint64{}
+		for _, s := range spans {
+			firsts = append(firsts, s.FirstSequence)
+		}
+		if !cmp.Equal(firsts, []uint64{1, 3, 5, 8}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", firsts, []uint64{1, 3, 5, 8})
+		}
+	})
+
+	t.Run("sequences missing from the manifest are derived", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 2*(recordHeaderSize+5), 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		appendForTests(t, l, 5)
+
+		// as if the commitlog crashed before the manifest was written, or predates sequence numbers.
+		errA := (&manifest{Version: FormatVersion}).write(l.fs, l.path)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		l2, errB := New(path, 2*(recordHeaderSize+5), 10_000, time.Hour)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		got := appendForTests(t, l2, 1)
+		if !cmp.Equal(got, []uint64{6}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, []uint64{6})
+		}
+		m, errC := readManifest(l2.fs, l2.path)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		for _, seg := range l2.segmentRead() {
+			if m.Segments[seg.baseOffset].FirstSequence != seg.firstSeq {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.Segments[seg.baseOffset], seg.firstSeq)
+			}
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package clog
+
+import "time"
+
+// Option configures optional behaviour of a commitlog.
+// Options are passed to New.
+type Option func(*Clog)
+
+// WithCorruptionHandler registers fn to be called whenever the commitlog detects corruption.
+// Corruption may be detected when reading from the commitlog or when a Scrubber verifies segments.
+// fn is called synchronously, from the goroutine that detected the corruption, and should thus not block.
+func WithCorruptionHandler(fn func(*CorruptionError)) Option {
+	return func(l *Clog) {
+		l.onCorruption = fn
+	}
+}
+
+// WithBackgroundIO configures how background work, like a Scrubber or the cleaning of WithCleanInterval, accesses the disk.
+// priority is the IO priority of the background work; it is honoured on linux and ignored on other platforms.
+// maxBytesPerSec caps the rate at which background work reads/writes on all platforms. A value of 0 means no cap.
+func WithBackgroundIO(priority IOPriority, maxBytesPerSec uint64) Option {
+	return func(l *Clog) {
+		l.bgIO = backgroundIO{priority: priority, maxBytesPerSec: maxBytesPerSec}
+	}
+}
+
+// WithMemoryBudget makes the reads of the commitlog share the memory budget m.
+// The same budget can be passed to many commitlogs, so that together they do not use more than m allows.
+func WithMemoryBudget(m *MemoryBudget) Option {
+	return func(l *Clog) {
+		l.budget = m
+	}
+}
+
+// WithFileSystem makes the commitlog store its segments in fsys instead of the operating system's filesystem.
+func WithFileSystem(fsys FileSystem) Option {
+	return func(l *Clog) {
+		l.fs = fsys
+	}
+}
+
+// WithLayout makes the commitlog lay out its segment files, within its directory, as decided by layout.
+// Opening an existing commitlog with a different layout from the one it was created with will not find its segments.
+func WithLayout(layout Layout) Option {
+	return func(l *Clog) {
+		l.layout = layout
+	}
+}
+
+// WithFsyncStallHandler makes the commitlog track fsyncs of its segments that take longer than threshold.
+// fsync stalls, especially on cheap disks, are usually the largest source of tail latency for appends.
+// fn, if not nil, is called with the details of each stall. It is called synchronously from within Append,
+// while the segment is locked, and should thus not block.
+// The number of stalls is available from Clog.FsyncStalls.
+func WithFsyncStallHandler(threshold time.Duration, fn func(FsyncStall)) Option {
+	return func(l *Clog) {
+		l.fsyncMon = &fsyncMonitor{threshold: threshold, onStall: fn}
+	}
+}
+
+// WithRetentionExempt exempts segments from retention; Clean never deletes a segment for which exempt, called with the
+// segment's tags, returns true. Exempt segments also do not count towards maxLogBytes & maxLogAge. see Clog.TagSegment
+//
+// usage:
+//
+//	l, errN := New("/tmp/orders", 100, 5, time.Hour*3, WithRetentionExempt(func(tags map[string]string) bool {
+//		return tags["backfill"] == "true"
+//	}))
+func WithRetentionExempt(exempt func(tags map[string]string) bool) Option {
+	return func(l *Clog) {
+		l.retentionExempt = exempt
+	}
+}
+
+// WithSyncPolicy decides when appends are synced to stable storage. The default is SyncAlways.
+// Individual appends can still ask to be synced, whatever the policy, by using Clog.AppendDurable.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(l *Clog) {
+		l.syncPolicy = policy
+	}
+}
+
+// WithReadOnlyAfter makes the commitlog enter read-only mode after failures consecutive appends have failed;
+// rather than oscillating between failed appends and ones that partially succeed, eg on a failing disk.
+// In read-only mode reads keep working, but appends return ErrReadOnly until Clog.ResumeWrites is called.
+// fn, if not nil, is called when the commitlog enters read-only mode. It is called synchronously from within Append,
+// while the commitlog is locked, and should thus not block nor call methods of the commitlog.
+func WithReadOnlyAfter(failures int, fn func(ReadOnlyEvent)) Option {
+	return func(l *Clog) {
+		if failures <= 0 {
+			failures = 1
+		}
+		l.guard = &writeGuard{maxFailures: failures, onReadOnly: fn}
+	}
+}
+
+// WithTrace makes the commitlog keep its latest n operations(appends, reads & cleans); with their duration, size and error,
+// in memory. They are available from Clog.Trace, eg for inspection after an incident.
+// It is a lightweight alternative to a tracing library, for those who cannot take on its dependencies.
+func WithTrace(n int) Option {
+	return func(l *Clog) {
+		if n <= 0 {
+			l.tracer = nil
+			return
+		}
+		l.tracer = newTracer(n)
+	}
+}
+
+// WithStripes spreads the segments of the commitlog across dirs, in addition to the directory of the commitlog;
+// a new segment is created in each of them in turn. It aggregates the bandwidth of hosts with several disks.
+// Each of dirs should be on a different disk, and should be dedicated to this commitlog.
+// Metadata, like the manifest, is only kept in the directory of the commitlog.
+//
+// The stripes are recorded in the manifest; so that their segments are still found when the commitlog is
+// opened without some of them. New segments are only created in the stripes passed to WithStripes.
+func WithStripes(dirs ...string) Option {
+	return func(l *Clog) {
+		l.stripes = dirs
+	}
+}
+
+// WithTailCache makes the latest segments of the commitlog, the active one and the one sealed before it, keep their data
+// in memory for as long as they are no larger than maxBytes. Tailing consumers, that read data soon after it is appended,
+// are then served from memory rather than the filesystem.
+// Each of the two segments uses up to maxBytes of memory; so maxBytes is usually the maxSegBytes of the commitlog.
+// Segments that exist when the commitlog is opened are not cached; see Clog.Warmup
+func WithTailCache(maxBytes uint64) Option {
+	return func(l *Clog) {
+		l.tailCache = maxBytes
+	}
+}
+
+// WithClock makes the commitlog tell the time using now, instead of the clock of the operating system.
+// The baseOffsets of new segments, the ages of segments that retention is decided by & the times of journal events are
+// all taken from it. Together with clogtest.MemFS & the scripted faults of clogtest.FaultyFS, it allows applications to
+// run deterministic replays of crash & recovery scenarios; see clogtest.Clock
+//
+// now should never go backwards, & should move forward between the creation of segments; baseOffsets are unique.
+func WithClock(now func() time.Time) Option {
+	return func(l *Clog) {
+		l.clock = now
+	}
+}
+
+// WithMaxSegments makes Clean delete the oldest segments of the commitlog once it has more than max of them, regardless
+// of maxLogBytes & maxLogAge; since some filesystems degrade badly with huge directories.
+// The active segment is always retained, & segments exempt from retention do not count towards max; see WithRetentionExempt
+// A max of 0, the default, means no cap.
+func WithMaxSegments(max int) Option {
+	return func(l *Clog) {
+		if max < 0 {
+			max = 0
+		}
+		l.cl.maxSegments = max
+	}
+}
+
+// WithAdaptiveSegmentSize makes the commitlog pick the size of each new segment, within [minBytes, maxBytes], from the
+// rate at which the segment before it was filled; so that a segment is rolled about every target, eg 10 minutes,
+// whether the commitlog is appended to at a kilobyte or at a hundred megabytes a second. The maxSegBytes passed to New
+// is then only the size of the first segment, & of the active segment when the commitlog is opened.
+// Sizes follow changes in the rate of appends a segment late; bursts shorter than a segment are evened out.
+// If minBytes is 0, minBytes is larger than maxBytes or target is not positive, New fails.
+func WithAdaptiveSegmentSize(minBytes, maxBytes uint64, target time.Duration) Option {
+	return func(l *Clog) {
+		l.sizer = &segmentSizer{minBytes: minBytes, maxBytes: maxBytes, target: target}
+	}
+}
+
+// WithMaxSegmentAge makes the commitlog roll its active segment, by sealing it & creating a new one, once it is older
+// than maxAge; not only once it holds maxSegBytes. Without it, the segments of a commitlog with few appends take long to
+// fill up, & thus to become old enough to be cleaned or archived. maxAge is usually an hour or a day.
+// Segments are rolled by the first append after they expire; a segment that holds no data is never rolled.
+func WithMaxSegmentAge(maxAge time.Duration) Option {
+	return func(l *Clog) {
+		l.maxSegAge = maxAge
+	}
+}
+
+// WithAgeRetention decides how Clean enforces maxLogAge; by the ages of the segments added up, the default, or by the
+// age of each segment. see AgeRetention
+func WithAgeRetention(mode AgeRetention) Option {
+	return func(l *Clog) {
+		l.cl.ageRetention = mode
+	}
+}
+
+// WithSegmentGrowthGuard is a safety valve for applications that never call Clean; the segments of such a commitlog,
+// and the memory used to keep track of them, grow forever.
+// Whenever the number of segments grows past threshold, the growth is recorded in the ops journal & fn, if not nil, is
+// called. It is called synchronously from within Append, while the commitlog is locked, and should thus not block
+// nor call methods of the commitlog. fn is not called again until the number of segments has dropped to threshold.
+//
+// If autoClean is true, the commitlog also cleans itself each time a segment is created while it has more than
+// threshold segments. Cleaning is conservative; only the segments beyond the retention of the commitlog, maxLogBytes,
+// maxLogAge & WithMaxSegments, are deleted, as they would be by Clean.
+func WithSegmentGrowthGuard(threshold int, autoClean bool, fn func(SegmentGrowthEvent)) Option {
+	return func(l *Clog) {
+		l.growth = &growthGuard{threshold: threshold, autoClean: autoClean, onGrowth: fn}
+	}
+}
+
+// WithPauseMode decides what appends do while appends to the commitlog are paused. The default is PauseFail.
+// see Clog.PauseAppends
+func WithPauseMode(mode PauseMode) Option {
+	return func(l *Clog) {
+		l.pause.mode = mode
+	}
+}
+
+// WithPollInterval makes a commitlog that is opened with OpenReadOnly refresh its view of the segments of the writer every
+// interval; see Clog.Refresh. Refreshes that fail are retried at the next interval; onErr, if not nil, is called with
+// their errors. It is called from the goroutine that polls, & should not block.
+// It has no effect on a commitlog that is opened with New.
+func WithPollInterval(interval time.Duration, onErr func(error)) Option {
+	return func(l *Clog) {
+		l.poll = schedule{interval: interval, onErr: onErr}
+	}
+}
+
+// WithCleanInterval makes the commitlog clean itself every interval, from a goroutine that Close stops; so that its
+// retention, maxLogBytes, maxLogAge & WithMaxSegments, is enforced without the application calling Clean.
+// Cleans that fail are retried at the next interval; onErr, if not nil, is called with their errors. It is called from
+// the goroutine that cleans, & should not block. The cleaning honours WithBackgroundIO.
+// It has no effect on a commitlog that is opened with OpenReadOnly.
+func WithCleanInterval(interval time.Duration, onErr func(error)) Option {
+	return func(l *Clog) {
+		l.autoClean = schedule{interval: interval, onErr: onErr}
+	}
+}
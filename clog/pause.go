@@ -0,0 +1,106 @@
+package clog
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPaused is returned by appends to a commitlog whose appends are paused, if its PauseMode is PauseFail.
+// see Clog.PauseAppends
+var ErrPaused = errors.New("commitLog appends are paused")
+
+// PauseMode decides what appends to a commitlog do while its appends are paused. see WithPauseMode
+type PauseMode uint8
+
+const (
+	// PauseFail makes appends fail fast with ErrPaused. It is the default.
+	PauseFail PauseMode = iota
+	// PauseBlock makes appends block until Clog.ResumeAppends is called.
+	PauseBlock
+)
+
+// pauser holds appends to a commitlog while an operator has paused them. see Clog.PauseAppends
+//
+// Its fields are protected by the mu of the commitlog.
+type pauser struct {
+	mode   PauseMode
+	paused bool
+	// resumed is closed when appends are resumed; appends that block wait on it.
+	resumed chan struct{}
+}
+
+// PauseAppends quiesces writes to the commitlog, eg during a migration or while a snapshot of its directory is taken.
+// Appends made while paused either fail with ErrPaused or block until ResumeAppends is called; see WithPauseMode.
+// Reads, & Clean, keep working.
+//
+// Appends that are in progress complete before PauseAppends returns. The active segment is then synced, so that
+// a snapshot taken while paused has all the data appended so far; if the sync fails, appends are not paused.
+// It is a no-op if appends are already paused.
+func (l *Clog) PauseAppends() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return errC
+	}
+	if l.pause.paused {
+		return nil
+	}
+	a, err := l.activeSegment()
+	if err != nil {
+		return err
+	}
+	errA := a.flush()
+	if errA != nil {
+		return errA
+	}
+	l.pause.paused = true
+	l.pause.resumed = make(chan struct{})
+	l.journal.record(OpsPauseAppends, "appends paused")
+	return nil
+}
+
+// ResumeAppends lets appends to the commitlog carry on after PauseAppends; appends that are blocked are let through.
+// It is a no-op if appends are not paused.
+func (l *Clog) ResumeAppends() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.pause.paused {
+		return
+	}
+	l.pause.paused = false
+	close(l.pause.resumed)
+	l.journal.record(OpsResumeAppends, "appends resumed")
+}
+
+// AppendsPaused reports whether appends to the commitlog are paused. see PauseAppends
+func (l *Clog) AppendsPaused() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.pause.paused
+}
+
+// waitResumed returns once appends are not paused; or, if the PauseMode is PauseFail, returns ErrPaused if they are.
+// It stops waiting, returning ctx.Err(), if ctx is done first.
+// The caller should hold l.mu; it is released while waiting.
+func (l *Clog) waitResumed(ctx context.Context) error {
+	for l.pause.paused {
+		if l.pause.mode != PauseBlock {
+			return ErrPaused
+		}
+		resumed := l.pause.resumed
+		l.mu.Unlock()
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			l.mu.Lock()
+			return ctx.Err()
+		}
+		l.mu.Lock()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package clog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPauseAppends(t *testing.T) {
+	t.Parallel()
+
+	t.Run("paused appends fail fast", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		errA := l.PauseAppends()
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if !l.AppendsPaused() {
+			t.Fatal("appends should be paused")
+		}
+		errB := l.Append([]byte("hello"))
+		if !errors.Is(errB, ErrPaused) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, ErrPaused)
+		}
+		errC := l.AppendDurable([]byte("hello"))
+		if !errors.Is(errC, ErrPaused) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, ErrPaused)
+		}
+
+		l.ResumeAppends()
+		if l.AppendsPaused() {
+			t.Fatal("appends should not be paused")
+		}
+		errD := l.Append([]byte("hello"))
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		data, _, errE := l.Read(0, 0)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		if recordsForTests(t, data) != "hello" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, data), "hello")
+		}
+
+		events, errF := l.OpsJournal()
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+		ops := []string{}
+		for _, e := range events {
+			ops = append(ops, e.Op)
+		}
+		want := []string{OpsOpen, OpsPauseAppends, OpsResumeAppends}
+		if len(ops) != len(want) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", ops, want)
+		}
+		for i := range want {
+			if ops[i] != want[i] {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ops, want)
+			}
+		}
+	})
+
+	t.Run("paused appends block until resumed", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour, WithPauseMode(PauseBlock))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		errA := l.PauseAppends()
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		done := make(chan error, 1)
+		go func() {
+			done <- l.Append([]byte("hello"))
+		}()
+
+		select {
+		case <-done:
+			t.Fatal("append should block while paused")
+		case <-time.After(50 * time.Millisecond):
+		}
+		// reads keep working while paused.
+		_, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		l.ResumeAppends()
+		errC := <-done
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		data, _, errD := l.Read(0, 0)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if recordsForTests(t, data) != "hello" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, data), "hello")
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package clog
+
+// PendingDeletion describes the segments that have been deleted, eg by Clean, but that are still on the filesystem
+// because reads that started before they were deleted are still using them. see Clog.PendingDeletion
+//
+// Their space is not counted by the commitlog, eg by Lag or retention; but the filesystem, eg df, still counts it.
+type PendingDeletion struct {
+	// Segments is the number of such segments.
+	Segments int
+	// Bytes is the space that they take up.
+	Bytes uint64
+	// Readers is the number of reads that are still using them.
+	Readers int
+}
+
+// PendingDeletion returns what segments have been deleted but are kept on the filesystem for reads that are using them.
+// No new read can use such a segment; each is removed from the filesystem once the last read using it is done.
+// A long-lived LogReader or Iterator that falls far behind can hold on to a lot of space; see ForceDelete.
+func (l *Clog) PendingDeletion() PendingDeletion {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p := PendingDeletion{}
+	pending := l.pendingDeletes[:0]
+	for _, seg := range l.pendingDeletes {
+		size, readers, ok := seg.pendingDeletion()
+		if !ok {
+			// the last read using it is done; it has been removed.
+			continue
+		}
+		pending = append(pending, seg)
+		p.Segments++
+		p.Bytes = p.Bytes + size
+		p.Readers = p.Readers + readers
+	}
+	l.pendingDeletes = pending
+	return p
+}
+
+// ForceDelete removes, from the filesystem, the segments whose removal is pending on reads that are using them; so as
+// to reclaim their space now, eg when the disk is filling up. Those reads fail when they next read from the segments.
+// It returns the number of segments removed.
+func (l *Clog) ForceDelete() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return 0, errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return 0, errC
+	}
+	removed := []uint64{}
+	pending := l.pendingDeletes[:0]
+	var err error
+	for _, seg := range l.pendingDeletes {
+		if err != nil {
+			pending = append(pending, seg)
+			continue
+		}
+		ok, errR := seg.forceRemove()
+		if errR != nil {
+			err = errR
+			pending = append(pending, seg)
+			continue
+		}
+		if ok {
+			removed = append(removed, seg.baseOffset)
+			l.removeEmptyDirs(seg)
+		}
+	}
+	l.pendingDeletes = pending
+	if len(removed) > 0 {
+		l.journal.record(OpsForceDelete, "removed segments %v, that were still being read", removed)
+	}
+	return len(removed), err
+}
@@ -0,0 +1,55 @@
+package clog
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPendingDeletion(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// retention is of about two records.
+	l, err := New(path, 10, 2*(9+recordHeaderSize), time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3", "order # 4"} {
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	r, errB := l.Reader(0)
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	defer r.Close()
+	errC := l.Clean()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	p := l.PendingDeletion()
+	if p.Segments == 0 || p.Bytes != uint64(p.Segments)*(9+recordHeaderSize) || p.Readers != p.Segments {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", p, "the segments cleaned to be pending")
+	}
+
+	n, errD := l.ForceDelete()
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	if n != p.Segments {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, p.Segments)
+	}
+	if got := l.PendingDeletion(); got != (PendingDeletion{}) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, PendingDeletion{})
+	}
+	// the reader fails to read the removed segments.
+	_, errE := io.ReadAll(r)
+	if errE == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,416 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProducerBatchBytes = 16 * 1024
+	defaultProducerLinger     = 5 * time.Millisecond
+	defaultProducerBuffered   = 1024
+)
+
+var (
+	errBadProducer    = errors.New("producer cannot have a nil commitLog or negative config values")
+	errProducerClosed = errors.New("producer is closed")
+)
+
+// ErrDropped is the error delivered for a record that was not appended because the producer was shut down, see
+// Producer.Shutdown, before the record's turn came.
+var ErrDropped = errors.New("record dropped, the producer was shut down before it was appended")
+
+// ErrKeyOrdering is the error delivered for a record that was not appended because an earlier record,
+// with the same key, failed. Appending it would have put it ahead of the earlier record once that is resent.
+var ErrKeyOrdering = errors.New("an earlier record with the same key failed")
+
+// ProducerConfig configures a Producer.
+// The zero value of each field picks a sensible default.
+type ProducerConfig struct {
+	// BatchBytes is the size a batch of records grows to before it is appended. It defaults to 16KB.
+	BatchBytes uint64
+	// Linger is the longest a record waits for its batch to fill up before the batch is appended anyway.
+	// It defaults to 5ms.
+	Linger time.Duration
+	// Buffered is the number of records that can be queued, waiting to be batched, before Send blocks.
+	// It defaults to 1024.
+	Buffered int
+	// MaxRetries is the number of times a failed append of a batch is retried before giving up on its records.
+	// It defaults to 0.
+	MaxRetries int
+	// RetryBackoff is how long to wait between retries.
+	RetryBackoff time.Duration
+	// OnDelivery, if not nil, is called once for every record that was sent; after it is appended or given up on.
+	// It is called from the producer's goroutine, in the order in which the records were sent; it should thus not block.
+	OnDelivery func(Delivery)
+}
+
+// Delivery is the outcome of sending a record with a Producer.
+type Delivery struct {
+	// Key is the ordering key the record was sent with, if any. see SendKey
+	Key string
+	// Sequence is the position of the record among the records sent with Key; starting at 1.
+	// It is 0 for records sent without a key.
+	Sequence uint64
+	// Record is the record that was sent.
+	Record []byte
+	// Err is nil if the record was appended, otherwise it is the error of the last attempt.
+	Err error
+}
+
+// Producer batches records that are sent to it, by size and linger time, and appends them to a commitlog.
+// Records are appended in the order in which they were sent, including across retries. Each batch is appended with a
+// single AppendBulk; so its records are appended, or fail, together.
+// Since a failed append may have reached the commitlog before failing, eg if the fsync failed, retries give
+// at-least-once delivery.
+//
+// Records sent with the same key, see SendKey, are never reordered; not even when the application resends a failed record.
+// Once a record fails, every record with the same key that had been sent before the failure was delivered fails with
+// ErrKeyOrdering instead of being appended. Resending the failed records, in order, thus restores the order of the key.
+//
+// To create a producer, use the NewProducer method.
+type Producer struct {
+	l CommitLog
+	c ProducerConfig
+
+	// mu protects closed, and makes sure that records is not sent to after it is closed.
+	mu      sync.RWMutex
+	closed  bool
+	records chan producerRecord
+	// stopping is closed once the producer starts to stop; so that sends blocked on a full buffer, which hold mu, give up
+	// & let it be closed.
+	stopping chan struct{}
+	stopOnce sync.Once
+	flushes  chan chan struct{}
+	done     chan struct{}
+	// abort is closed when a Shutdown runs out of time; the records that have not been appended by then are dropped.
+	abort     chan struct{}
+	abortOnce sync.Once
+	// dropped is the number of records that were dropped. It is only accessed from the producer's goroutine,
+	// & after it is done.
+	dropped int
+
+	// seqMu protects seqs
+	seqMu sync.Mutex
+	// seqs is the sequence of the latest record sent with each key.
+	seqs map[string]uint64
+	// fenced is, for each key, the sequence upto which records fail with ErrKeyOrdering.
+	// It is only accessed from the producer's goroutine.
+	fenced map[string]uint64
+}
+
+type producerRecord struct {
+	key string
+	seq uint64
+	b   []byte
+}
+
+// NewProducer creates a producer that appends to the commitlog l, which can be a local or a remote one.
+// Close should be called once the producer is no longer needed.
+//
+// usage:
+//
+//	p, errN := NewProducer(l, ProducerConfig{Linger: 10 * time.Millisecond, OnDelivery: func(d Delivery) {
+//		if d.Err != nil {
+//			log.Println(d.Err)
+//		}
+//	}})
+//	defer p.Close()
+//	errS := p.Send([]byte("order # 1"))
+func NewProducer(l CommitLog, c ProducerConfig) (*Producer, error) {
+	if l == nil || c.Linger < 0 || c.Buffered < 0 || c.MaxRetries < 0 || c.RetryBackoff < 0 {
+		return nil, errBadProducer
+	}
+	if c.BatchBytes == 0 {
+		c.BatchBytes = defaultProducerBatchBytes
+	}
+	if c.Linger == 0 {
+		c.Linger = defaultProducerLinger
+	}
+	if c.Buffered == 0 {
+		c.Buffered = defaultProducerBuffered
+	}
+
+	p := &Producer{
+		l:        l,
+		c:        c,
+		records:  make(chan producerRecord, c.Buffered),
+		stopping: make(chan struct{}),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+		abort:    make(chan struct{}),
+		seqs:     map[string]uint64{},
+		fenced:   map[string]uint64{},
+	}
+	go p.run()
+	return p, nil
+}
+
+// Send queues the record b to be appended to the commitlog.
+// It only blocks if the producer's buffer is full; until there is room in it, or the producer is closed. The outcome of
+// appending b is reported to OnDelivery.
+// b should not be modified after it has been sent.
+func (p *Producer) Send(b []byte) error {
+	return p.SendKey("", b)
+}
+
+// SendKey is like Send, but records that are sent with the same key are guaranteed to never be reordered.
+// A key is usually the identity of the entity that the record is about, eg an order ID.
+// An empty key is the same as no key.
+func (p *Producer) SendKey(key string, b []byte) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return errProducerClosed
+	}
+
+	var seq uint64
+	if key != "" {
+		p.seqMu.Lock()
+		seq = p.seqs[key] + 1
+		p.seqs[key] = seq
+		p.seqMu.Unlock()
+	}
+	select {
+	case p.records <- producerRecord{key: key, seq: seq, b: b}:
+		return nil
+	case <-p.stopping:
+		return errProducerClosed
+	}
+}
+
+// Flush blocks until all the records sent so far have been delivered.
+func (p *Producer) Flush() {
+	ack := make(chan struct{})
+	select {
+	case p.flushes <- ack:
+		<-ack
+	case <-p.done:
+		// the producer has been closed; everything has been delivered already.
+	}
+}
+
+// Close delivers all the records sent so far and stops the producer.
+// Send fails after Close has been called. It is safe to call Close more than once.
+// To bound how long it takes use Shutdown.
+func (p *Producer) Close() error {
+	p.stop()
+	<-p.done
+	return nil
+}
+
+// syncer is implemented by commitlogs that can be synced to stable storage on demand.
+type syncer interface {
+	Sync() error
+}
+
+// Shutdown is like Close, but it gives up once ctx is done; so that embedders can bound how long shutting down takes,
+// & know what durability they got.
+// The records sent so far are appended, in order, until ctx is done. Those that are still buffered then are dropped;
+// they are delivered to OnDelivery with ErrDropped, & their number is returned along with the error of ctx.
+// If every record was appended in time, & the commitlog has a Sync method, the commitlog is then synced; so that
+// records appended without a sync, eg with SyncOnSeal, are not lost.
+// An append that is in progress when ctx is done is not interrupted; Shutdown waits for it.
+//
+// usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	dropped, err := p.Shutdown(ctx)
+//	if dropped > 0 {
+//		log.Printf("%d records were not appended: %v", dropped, err)
+//	}
+func (p *Producer) Shutdown(ctx context.Context) (dropped int, err error) {
+	p.stop()
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.abortOnce.Do(func() { close(p.abort) })
+		<-p.done
+	}
+
+	if p.dropped > 0 {
+		return p.dropped, ctx.Err()
+	}
+	if s, ok := p.l.(syncer); ok && ctx.Err() == nil {
+		return 0, s.Sync()
+	}
+	return 0, nil
+}
+
+// stop makes Send fail, & lets the producer's goroutine exit once it has delivered the records sent so far.
+func (p *Producer) stop() {
+	p.stopOnce.Do(func() { close(p.stopping) })
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.records)
+	}
+	p.mu.Unlock()
+}
+
+// backoff waits for RetryBackoff before a retry. It returns false, without waiting any longer, if a Shutdown runs out
+// of time meanwhile.
+func (p *Producer) backoff() bool {
+	t := time.NewTimer(p.c.RetryBackoff)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-p.abort:
+		return false
+	}
+}
+
+// aborted reports whether a Shutdown has run out of time.
+func (p *Producer) aborted() bool {
+	select {
+	case <-p.abort:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Producer) run() {
+	defer close(p.done)
+
+	var (
+		batch  []producerRecord
+		size   uint64
+		timer  *time.Timer
+		linger <-chan time.Time
+	)
+	deliver := func() {
+		if timer != nil {
+			timer.Stop()
+			timer, linger = nil, nil
+		}
+		p.deliver(batch)
+		batch, size = nil, 0
+	}
+	add := func(r producerRecord) {
+		batch = append(batch, r)
+		size = size + uint64(len(r.b))
+		if len(batch) == 1 {
+			timer = time.NewTimer(p.c.Linger)
+			linger = timer.C
+		}
+	}
+
+	for {
+		select {
+		case r, ok := <-p.records:
+			if !ok {
+				deliver()
+				return
+			}
+			add(r)
+			if size >= p.c.BatchBytes {
+				deliver()
+			}
+		case <-linger:
+			deliver()
+		case ack := <-p.flushes:
+			// include whatever was sent before Flush was called, but is still queued.
+			closed := false
+		drain:
+			for {
+				select {
+				case r, ok := <-p.records:
+					if !ok {
+						closed = true
+						break drain
+					}
+					add(r)
+					if size >= p.c.BatchBytes {
+						deliver()
+					}
+				default:
+					break drain
+				}
+			}
+			deliver()
+			close(ack)
+			if closed {
+				return
+			}
+		}
+	}
+}
+
+// deliver appends the records of batch, in order, with a single AppendBulk; and reports the outcome of each.
+// The batch is appended, & retried, as a whole; so its records are either all appended or all fail. A record that
+// is fenced, because an earlier record of its key failed, is left out of the batch & fails with ErrKeyOrdering.
+func (p *Producer) deliver(batch []producerRecord) {
+	errs := make([]error, len(batch))
+	if p.aborted() {
+		for i := range batch {
+			errs[i] = ErrDropped
+			p.dropped++
+		}
+	} else {
+		bbs := make([][]byte, 0, len(batch))
+		for i, r := range batch {
+			if r.key != "" && r.seq <= p.fenced[r.key] {
+				errs[i] = ErrKeyOrdering
+				continue
+			}
+			bbs = append(bbs, r.b)
+		}
+
+		err := p.append(bbs)
+		if err != nil {
+			failed := map[string]bool{}
+			for i, r := range batch {
+				if errs[i] != nil {
+					continue
+				}
+				errs[i] = err
+				if r.key != "" && failed[r.key] {
+					// the record came after one of its key that failed; it fails as it would have in a later batch.
+					errs[i] = ErrKeyOrdering
+				}
+				failed[r.key] = true
+			}
+			p.fence(failed)
+		}
+	}
+
+	if p.c.OnDelivery != nil {
+		for i, r := range batch {
+			p.c.OnDelivery(Delivery{Key: r.key, Sequence: r.seq, Record: r.b, Err: errs[i]})
+		}
+	}
+}
+
+// append appends bbs with a single AppendBulk, retrying it as configured.
+func (p *Producer) append(bbs [][]byte) error {
+	if len(bbs) == 0 {
+		return nil
+	}
+	err := p.l.AppendBulk(bbs)
+	for i := 0; err != nil && i < p.c.MaxRetries; i++ {
+		if !p.backoff() {
+			// a Shutdown has run out of time; give up on the records.
+			break
+		}
+		err = p.l.AppendBulk(bbs)
+	}
+	return err
+}
+
+// fence fails the records of keys that are already in flight; they would otherwise get ahead of the failed records of
+// those keys once they are resent.
+func (p *Producer) fence(keys map[string]bool) {
+	p.seqMu.Lock()
+	defer p.seqMu.Unlock()
+	for key := range keys {
+		if key != "" {
+			p.fenced[key] = p.seqs[key]
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyLog is a CommitLog whose first failures appends fail.
+type flakyLog struct {
+	CommitLog
+	mu       sync.Mutex
+	failures int
+	appended []string
+	bulks    int
+}
+
+var errFlaky = errors.New("flaky append")
+
+func (f *flakyLog) Append(b []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return errFlaky
+	}
+	f.appended = append(f.appended, string(b))
+	return nil
+}
+
+func (f *flakyLog) AppendBulk(bbs [][]byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return errFlaky
+	}
+	for _, b := range bbs {
+		f.appended = append(f.appended, string(b))
+	}
+	f.bulks++
+	return nil
+}
+
+func TestProducer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records are appended in order", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t, createLogConfig{maxSegBytes: 100, maxLogBytes: 100_000, maxLogAge: time.Hour})
+		defer removePath()
+
+		var mu sync.Mutex
+		delivered := 0
+		p, err := NewProducer(l, ProducerConfig{BatchBytes: 50, Linger: time.Hour, OnDelivery: func(d Delivery) {
+			if d.Err != nil {
+				t.Error("\n\t", d.Err)
+			}
+			mu.Lock()
+			delivered++
+			mu.Unlock()
+		}})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer p.Close()
+
+		want := ""
+		for i := 0; i < 100; i++ {
+			r := fmt.Sprintf("record-%d;", i)
+			want = want + r
+			errA := p.Send([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		p.Flush()
+
+		mu.Lock()
+		if delivered != 100 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", delivered, 100)
+		}
+		mu.Unlock()
+		got, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if recordsForTests(t, got) != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, got), want)
+		}
+	})
+
+	t.Run("batches are appended after lingering", func(t *testing.T) {
+		t.Parallel()
+
+		f := &flakyLog{}
+		delivered := make(chan Delivery, 1)
+		p, err := NewProducer(f, ProducerConfig{Linger: 10 * time.Millisecond, OnDelivery: func(d Delivery) { delivered <- d }})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer p.Close()
+
+		errA := p.Send([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		select {
+		case d := <-delivered:
+			if d.Err != nil || string(d.Record) != "hello" {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", d, "hello")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("record was never delivered")
+		}
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		t.Parallel()
+
+		tt := []struct {
+			name       string
+			maxRetries int
+			wantErr    error
+		}{
+			{name: "enough retries", maxRetries: 2, wantErr: nil},
+			{name: "too few retries", maxRetries: 1, wantErr: errFlaky},
+		}
+		for _, v := range tt {
+			v := v
+			t.Run(v.name, func(t *testing.T) {
+				t.Parallel()
+
+				f := &flakyLog{failures: 2}
+				deliveries := []Delivery{}
+				// each record is a batch of its own.
+				p, err := NewProducer(f, ProducerConfig{BatchBytes: 1, MaxRetries: v.maxRetries, OnDelivery: func(d Delivery) {
+					deliveries = append(deliveries, d)
+				}})
+				if err != nil {
+					t.Fatal("\n\t", err)
+				}
+				for _, r := range []string{"a", "b"} {
+					errA := p.Send([]byte(r))
+					if errA != nil {
+						t.Fatal("\n\t", errA)
+					}
+				}
+				p.Close()
+
+				if len(deliveries) != 2 {
+					t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(deliveries), 2)
+				}
+				if !errors.Is(deliveries[0].Err, v.wantErr) {
+					t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", deliveries[0].Err, v.wantErr)
+				}
+				if string(deliveries[1].Record) != "b" || deliveries[1].Err != nil {
+					t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", deliveries[1], "b")
+				}
+				if strings.Join(f.appended, "") == "" {
+					t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", f.appended, "some appends")
+				}
+			})
+		}
+	})
+
+	t.Run("each batch is a single bulk append", func(t *testing.T) {
+		t.Parallel()
+
+		tt := []struct {
+			name     string
+			failures int
+			wantErrs []error
+		}{
+			{name: "appended", failures: 0, wantErrs: []error{nil, nil, nil}},
+			// the records of a key that come after one that failed get ErrKeyOrdering, as they would in a later batch.
+			{name: "failed", failures: 1, wantErrs: []error{errFlaky, errFlaky, ErrKeyOrdering}},
+		}
+		for _, v := range tt {
+			f := &flakyLog{failures: v.failures}
+			deliveries := []Delivery{}
+			// the batch is only appended once the producer is closed.
+			p, err := NewProducer(f, ProducerConfig{Linger: time.Hour, OnDelivery: func(d Delivery) {
+				deliveries = append(deliveries, d)
+			}})
+			if err != nil {
+				t.Fatal(v.name, "\n\t", err)
+			}
+			for _, r := range []struct{ key, b string }{{"order-1", "a1"}, {"order-2", "b1"}, {"order-1", "a2"}} {
+				errA := p.SendKey(r.key, []byte(r.b))
+				if errA != nil {
+					t.Fatal(v.name, "\n\t", errA)
+				}
+			}
+			p.Close()
+
+			if len(deliveries) != 3 {
+				t.Fatalf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, deliveries, 3)
+			}
+			for i, d := range deliveries {
+				if !errors.Is(d.Err, v.wantErrs[i]) {
+					t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, d, v.wantErrs[i])
+				}
+			}
+			if got, want := strings.Join(f.appended, ","), strings.Repeat("a1,b1,a2", 1-v.failures); got != want || f.bulks != 1-v.failures {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, []interface{}{got, f.bulks}, []interface{}{want, 1 - v.failures})
+			}
+		}
+	})
+
+	t.Run("send after close", func(t *testing.T) {
+		t.Parallel()
+
+		p, err := NewProducer(&flakyLog{}, ProducerConfig{})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		p.Close()
+		p.Close()
+		p.Flush()
+
+		errA := p.Send([]byte("hello"))
+		if !errors.Is(errA, errProducerClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, errProducerClosed)
+		}
+	})
+
+	t.Run("bad config", func(t *testing.T) {
+		t.Parallel()
+
+		for _, c := range []ProducerConfig{{Linger: -1}, {MaxRetries: -1}} {
+			_, err := NewProducer(&flakyLog{}, c)
+			if !errors.Is(err, errBadProducer) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadProducer)
+			}
+		}
+		_, err := NewProducer(nil, ProducerConfig{})
+		if !errors.Is(err, errBadProducer) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadProducer)
+		}
+	})
+}
+
+func TestProducerKeyOrdering(t *testing.T) {
+	t.Parallel()
+
+	f := &flakyLog{failures: 1}
+	deliveries := []Delivery{}
+	// each record is a batch of its own; so a2 is in flight when a1 fails.
+	p, err := NewProducer(f, ProducerConfig{BatchBytes: 1, OnDelivery: func(d Delivery) {
+		deliveries = append(deliveries, d)
+	}})
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer p.Close()
+
+	send := func(key, r string) {
+		errA := p.SendKey(key, []byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	send("order-1", "a1")
+	send("order-1", "a2")
+	send("order-2", "b1")
+	p.Flush()
+
+	// a1 fails, so a2 should not get ahead of it.
+	want := []Delivery{
+		{Key: "order-1", Sequence: 1, Record: []byte("a1"), Err: errFlaky},
+		{Key: "order-1", Sequence: 2, Record: []byte("a2"), Err: ErrKeyOrdering},
+		{Key: "order-2", Sequence: 1, Record: []byte("b1"), Err: nil},
+	}
+	if len(deliveries) != len(want) {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", deliveries, want)
+	}
+	for i := range want {
+		if deliveries[i].Key != want[i].Key || deliveries[i].Sequence != want[i].Sequence || !errors.Is(deliveries[i].Err, want[i].Err) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", deliveries[i], want[i])
+		}
+	}
+
+	// resending the failed records, in order, succeeds.
+	send("order-1", "a1")
+	send("order-1", "a2")
+	p.Flush()
+
+	if len(deliveries) != 5 {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(deliveries), 5)
+	}
+	for _, d := range deliveries[3:] {
+		if d.Err != nil {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", d.Err, nil)
+		}
+	}
+	if deliveries[4].Sequence != 4 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", deliveries[4].Sequence, 4)
+	}
+	wantAppended := "b1,a1,a2"
+	if strings.Join(f.appended, ",") != wantAppended {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", strings.Join(f.appended, ","), wantAppended)
+	}
+}
+
+// slowLog takes delay to append each record, and counts its syncs.
+type slowLog struct {
+	flakyLog
+	delay time.Duration
+	syncs int
+}
+
+func (s *slowLog) Append(b []byte) error {
+	time.Sleep(s.delay)
+	return s.flakyLog.Append(b)
+}
+
+func (s *slowLog) AppendBulk(bbs [][]byte) error {
+	time.Sleep(s.delay)
+	return s.flakyLog.AppendBulk(bbs)
+}
+
+func (s *slowLog) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.syncs++
+	return nil
+}
+
+func TestProducerShutdown(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records are appended & the commitlog synced in time", func(t *testing.T) {
+		t.Parallel()
+
+		l := &slowLog{}
+		p, err := NewProducer(l, ProducerConfig{})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 0; i < 10; i++ {
+			errA := p.Send([]byte(fmt.Sprint(i)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		dropped, errB := p.Shutdown(context.Background())
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if dropped != 0 || len(l.appended) != 10 || l.syncs != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []int{dropped, len(l.appended), l.syncs}, []int{0, 10, 1})
+		}
+		errC := p.Send([]byte("late"))
+		if !errors.Is(errC, errProducerClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, errProducerClosed)
+		}
+	})
+
+	t.Run("a Clog is synced", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 1_000, 100_000, time.Hour, WithSyncPolicy(SyncOnSeal), WithTrace(100))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer l.Close()
+		p, errP := NewProducer(l, ProducerConfig{})
+		if errP != nil {
+			t.Fatal("\n\t", errP)
+		}
+		for i := 0; i < 10; i++ {
+			errA := p.Send([]byte(fmt.Sprint(i)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		dropped, errB := p.Shutdown(context.Background())
+		if errB != nil || dropped != 0 {
+			t.Fatal("\n\t", dropped, errB)
+		}
+		records, _, errC := l.ReadRecords(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if len(records) != 10 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 10)
+		}
+		// SyncOnSeal leaves the appends unsynced; Shutdown syncs them.
+		syncs := 0
+		for _, e := range l.Trace() {
+			if e.Op == "sync" && e.Err == nil {
+				syncs++
+			}
+		}
+		if syncs != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", syncs, 1)
+		}
+	})
+
+	t.Run("records that cannot be appended in time are dropped", func(t *testing.T) {
+		t.Parallel()
+
+		l := &slowLog{delay: 20 * time.Millisecond}
+		droppedDeliveries := 0
+		p, err := NewProducer(l, ProducerConfig{BatchBytes: 1, OnDelivery: func(d Delivery) {
+			if errors.Is(d.Err, ErrDropped) {
+				droppedDeliveries++
+			}
+		}})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		total := 20
+		for i := 0; i < total; i++ {
+			errA := p.Send([]byte(fmt.Sprint(i)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		dropped, errB := p.Shutdown(ctx)
+		if !errors.Is(errB, context.DeadlineExceeded) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, context.DeadlineExceeded)
+		}
+		if dropped == 0 || dropped != droppedDeliveries || dropped+len(l.appended) != total {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []int{dropped, droppedDeliveries, len(l.appended)}, "every record appended or dropped")
+		}
+		if l.syncs != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", l.syncs, 0)
+		}
+	})
+
+	t.Run("sends blocked on a full buffer do not hold up shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		l := &slowLog{delay: 20 * time.Millisecond}
+		p, err := NewProducer(l, ProducerConfig{BatchBytes: 1, Buffered: 1})
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// many senders block on the full buffer.
+		wg := sync.WaitGroup{}
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_ = p.Send([]byte(fmt.Sprint(i)))
+			}(i)
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _ = p.Shutdown(ctx)
+		// at most the append in progress, when ctx was done, is waited for.
+		if took := time.Since(start); took > 200*time.Millisecond {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", took, "shutdown to give up in time")
+		}
+		wg.Wait()
+	})
+}
@@ -0,0 +1,183 @@
+package clog
+
+import (
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxQuotaClients is the number of clients a quota handler keeps track of; beyond it, the clients that are not being
+// throttled are forgotten.
+const maxQuotaClients = 10_000
+
+// Quota caps the rate at which each client of a server reads from, & writes to, a commitlog. see NewQuotaHandler
+type Quota struct {
+	// ProduceBytesPerSec caps the rate at which a client sends data, ie the bodies of its requests. 0 means no cap.
+	ProduceBytesPerSec uint64
+	// FetchBytesPerSec caps the rate at which a client is sent data, ie the bodies of the responses to its GET & HEAD
+	// requests. 0 means no cap.
+	FetchBytesPerSec uint64
+	// Burst is how far ahead of its quota a client can get before it is throttled. The default is one second.
+	Burst time.Duration
+	// Client returns the identity of the client that made a request; eg the value of an API key header.
+	// The default is the host of the request's RemoteAddr.
+	Client func(r *http.Request) string
+}
+
+// NewQuotaHandler returns an http.Handler that enforces quota on each client of next; so that a single misbehaving
+// client can not saturate the disk of the server. It can wrap NewSegmentHandler, NewShipHandler or any other handler.
+//
+// The bytes that each client produces & fetches are accounted for as its requests are served. A client that gets more
+// than Burst ahead of its quota is throttled: its requests are rejected with 429 Too Many Requests, & a Retry-After
+// header of the number of seconds until it is back within its quota, without being passed on to next.
+//
+// usage:
+//
+//	q := Quota{FetchBytesPerSec: 10 * 1024 * 1024}
+//	http.Handle("/segments/", NewQuotaHandler(NewSegmentHandler(l), q))
+func NewQuotaHandler(next http.Handler, q Quota) http.Handler {
+	if q.Burst <= 0 {
+		q.Burst = time.Second
+	}
+	if q.Client == nil {
+		q.Client = remoteHost
+	}
+	return &quotaHandler{next: next, q: q, clients: map[string]*clientQuota{}, now: time.Now}
+}
+
+type quotaHandler struct {
+	next http.Handler
+	q    Quota
+	now  func() time.Time
+
+	// mu protects clients
+	mu      sync.Mutex
+	clients map[string]*clientQuota
+}
+
+// clientQuota is the usage of a client. A client is within its quota for as long as the time, at which the bytes it has
+// been accounted for are paid off at its quota, is no later than Burst from now.
+type clientQuota struct {
+	producedUntil time.Time
+	fetchedUntil  time.Time
+}
+
+func (h *quotaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	client := h.q.Client(r)
+	fetch := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if wait := h.throttled(client, fetch); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	cr := &countingReader{r: r.Body}
+	if r.Body != nil {
+		r.Body = cr
+	}
+	cw := &countingResponseWriter{ResponseWriter: w}
+	h.next.ServeHTTP(cw, r)
+	h.account(client, cr.n, cw.n)
+}
+
+// throttled returns how long the client has to wait before it is back within its quota; 0 if it already is.
+func (h *quotaHandler) throttled(client string, fetch bool) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	c, ok := h.clients[client]
+	if !ok {
+		return 0
+	}
+	until := c.producedUntil
+	if fetch {
+		until = c.fetchedUntil
+	}
+	wait := until.Sub(h.now()) - h.q.Burst
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// account adds the bytes that the client produced & fetched to its usage.
+func (h *quotaHandler) account(client string, produced, fetched uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := h.now()
+	c, ok := h.clients[client]
+	if !ok {
+		if len(h.clients) >= maxQuotaClients {
+			h.forget(now)
+		}
+		c = &clientQuota{}
+		h.clients[client] = c
+	}
+	c.producedUntil = payOff(c.producedUntil, now, produced, h.q.ProduceBytesPerSec)
+	c.fetchedUntil = payOff(c.fetchedUntil, now, fetched, h.q.FetchBytesPerSec)
+}
+
+// forget drops the clients that are within their quota; they would be let through anyway.
+// The caller should hold h.mu
+func (h *quotaHandler) forget(now time.Time) {
+	for client, c := range h.clients {
+		if !c.producedUntil.After(now) && !c.fetchedUntil.After(now) {
+			delete(h.clients, client)
+		}
+	}
+}
+
+// payOff returns the time at which n more bytes, on top of those that are paid off at until, are paid off at
+// bytesPerSec.
+func payOff(until, now time.Time, n, bytesPerSec uint64) time.Time {
+	if bytesPerSec == 0 || n == 0 {
+		return until
+	}
+	if until.Before(now) {
+		until = now
+	}
+	return until.Add(time.Duration(float64(n) / float64(bytesPerSec) * float64(time.Second)))
+}
+
+// remoteHost returns the host of the RemoteAddr of r.
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.ReadCloser
+	n uint64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n = c.n + uint64(n)
+	return n, err
+}
+
+func (c *countingReader) Close() error {
+	return c.r.Close()
+}
+
+// countingResponseWriter counts the bytes of the body of a response.
+type countingResponseWriter struct {
+	http.ResponseWriter
+	n uint64
+}
+
+func (c *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n = c.n + uint64(n)
+	return n, err
+}
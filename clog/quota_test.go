@@ -0,0 +1,67 @@
+package clog
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuotaHandler(t *testing.T) {
+	t.Parallel()
+
+	// next serves 100 bytes, & reads the body of the request.
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		_, _ = w.Write([]byte(strings.Repeat("a", 100)))
+	})
+	now := time.Now()
+	h := NewQuotaHandler(next, Quota{
+		ProduceBytesPerSec: 10,
+		FetchBytesPerSec:   100,
+		Client:             func(r *http.Request) string { return r.Header.Get("client") },
+	}).(*quotaHandler)
+	h.now = func() time.Time { return now }
+
+	serve := func(method, client, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/segments/", strings.NewReader(body))
+		r.Header.Set("client", client)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// each fetch is a second's worth of quota; the burst allows one second ahead.
+	for i := 0; i < 2; i++ {
+		if w := serve(http.MethodGet, "a", ""); w.Code != http.StatusOK {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", w.Code, http.StatusOK)
+		}
+	}
+	w := serve(http.MethodGet, "a", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []interface{}{w.Code, w.Header().Get("Retry-After")}, []interface{}{429, "1"})
+	}
+	// other clients are not affected.
+	if w := serve(http.MethodGet, "b", ""); w.Code != http.StatusOK {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", w.Code, http.StatusOK)
+	}
+	// the client is let through once it is back within its quota.
+	now = now.Add(time.Second)
+	if w := serve(http.MethodGet, "a", ""); w.Code != http.StatusOK {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", w.Code, http.StatusOK)
+	}
+
+	// produced bytes count against their own quota; 30 bytes at 10 bytes/sec is 3 seconds.
+	if w := serve(http.MethodPut, "c", strings.Repeat("b", 30)); w.Code != http.StatusOK {
+		t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", w.Code, http.StatusOK)
+	}
+	w = serve(http.MethodPut, "c", "b")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []interface{}{w.Code, w.Header().Get("Retry-After")}, []interface{}{429, "2"})
+	}
+	if w := serve(http.MethodGet, "c", ""); w.Code != http.StatusOK {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", w.Code, http.StatusOK)
+	}
+}
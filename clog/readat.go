@@ -0,0 +1,156 @@
+package clog
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+var errNegativeOffset = errors.New("commitLog read at a negative offset")
+
+// SegmentReader gives random access to the data of a segment of a commitlog. It implements io.ReaderAt; so it can be
+// used with io.NewSectionReader. see Clog.SegmentReader
+//
+// Unlike Read, it does not check that the data it reads is made of whole records that match their checksums;
+// use Records on the data of whole records to do so.
+type SegmentReader struct {
+	seg *segment
+}
+
+// SegmentReader returns a reader of the data of the segment with baseOffset; eg to read the record at a RecordOffset
+// without reading the whole of its segment. The segment is kept on the filesystem until the reader is closed.
+// It returns an error if the commitlog has no segment with baseOffset.
+//
+// usage:
+//
+//	o, errA := l.AppendOffset([]byte("order # 1"))
+//	r, errS := l.SegmentReader(o.BaseOffset)
+//	defer r.Close()
+//	header := make([]byte, 8)
+//	n, errR := r.ReadAt(header, int64(o.Position))
+func (l *Clog) SegmentReader(baseOffset uint64) (*SegmentReader, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return nil, errC
+	}
+	seg := l.segmentByBaseOffset(baseOffset)
+	if seg == nil || !seg.acquire() {
+		return nil, errSegmentNotFound
+	}
+	return &SegmentReader{seg: seg}, nil
+}
+
+// ReadAt reads len(p) bytes of the data of the segment, starting at the byte position off. As io.ReaderAt requires,
+// it returns io.EOF if fewer bytes are read because the segment ends before off+len(p).
+func (r *SegmentReader) ReadAt(p []byte, off int64) (int, error) {
+	if r.seg == nil {
+		return 0, errReaderClosed
+	}
+	return r.seg.readAt(p, off)
+}
+
+// Size returns the size of the segment; it grows for as long as the segment is the active one.
+func (r *SegmentReader) Size() int64 {
+	if r.seg == nil {
+		return 0
+	}
+	return int64(r.seg.size())
+}
+
+// Close releases the segment. Reads after Close fail.
+func (r *SegmentReader) Close() error {
+	if r.seg == nil {
+		return nil
+	}
+	// we do not care about this error; as with Clean, a segment that could not be removed is left behind.
+	_ = r.seg.release()
+	r.seg = nil
+	return nil
+}
+
+// ReadAt is a byte-addressed read of the commitlog; it reads len(p) bytes from the segment with baseOffset, starting at
+// the byte position off within the data of that segment. see SegmentReader
+func (l *Clog) ReadAt(p []byte, baseOffset uint64, off int64) (int, error) {
+	r, err := l.SegmentReader(baseOffset)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return r.ReadAt(p, off)
+}
+
+// readAt reads len(p) bytes of the data of the segment, starting at off. see SegmentReader.ReadAt
+func (s *segment) readAt(p []byte, off int64) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if off < 0 {
+		return 0, errNegativeOffset
+	}
+	size := int64(s.currentSegBytes)
+	if off >= size {
+		return 0, io.EOF
+	}
+	want := p
+	if int64(len(p)) > size-off {
+		want = p[:size-off]
+	}
+
+	var n int
+	if s.cache != nil && int64(len(s.cache)) == size {
+		n = copy(want, s.cache[off:])
+	} else {
+		f, err := s.fsys.OpenFile(s.filePath, os.O_RDONLY, ownerReadableWritable)
+		if err != nil {
+			return 0, errSegmentRead(err)
+		}
+		defer f.Close()
+		n, err = readFileAt(f, want, off)
+		if err != nil {
+			// the file is shorter than the segment should be.
+			return n, errSegmentRead(err)
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readFileAt reads exactly len(p) bytes of f, starting at off. Files that are not an io.ReaderAt are read up to off.
+func readFileAt(f File, p []byte, off int64) (int, error) {
+	if ra, ok := f.(io.ReaderAt); ok {
+		return ra.ReadAt(p, off)
+	}
+	_, err := io.CopyN(io.Discard, f, off)
+	if err != nil {
+		return 0, err
+	}
+	return io.ReadFull(f, p)
+}
+
+// readRecords reads the data of the segment in the byte range [start, end), which should hold whole records; & checks
+// that it does. It returns the data & the number of records in it; or a *CorruptionError whose byte range is within the
+// segment.
+func (s *segment) readRecords(start, end uint64) ([]byte, uint64, error) {
+	if end <= start {
+		return nil, 0, nil
+	}
+	b := make([]byte, end-start)
+	_, err := s.readAt(b, int64(start))
+	if err != nil {
+		return nil, 0, err
+	}
+	n, err := countRecords(s.filePath, b)
+	if err != nil {
+		var ce *CorruptionError
+		if errors.As(err, &ce) {
+			// the byte range is within b, which starts at start.
+			ce.Start, ce.End = ce.Start+start, ce.End+start
+		}
+		return nil, 0, err
+	}
+	return b, n, nil
+}
@@ -0,0 +1,65 @@
+package clog
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestReadAt(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 1_000, 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	offsets := []RecordOffset{}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		o, errA := l.AppendOffset([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		offsets = append(offsets, o)
+	}
+
+	// the record at an offset is read without reading the whole segment.
+	o := offsets[1]
+	p := make([]byte, recordHeaderSize+len("order # 2"))
+	n, errB := l.ReadAt(p, o.BaseOffset, int64(o.Position))
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	if n != len(p) || recordsForTests(t, p) != "order # 2" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(p[:n]), "order # 2")
+	}
+
+	r, errC := l.SegmentReader(o.BaseOffset)
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	defer r.Close()
+	// reads past the end of the segment are short.
+	tail := make([]byte, 100)
+	n2, errD := r.ReadAt(tail, int64(offsets[2].Position))
+	if !errors.Is(errD, io.EOF) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, io.EOF)
+	}
+	if recordsForTests(t, tail[:n2]) != "order # 3" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(tail[:n2]), "order # 3")
+	}
+	all, errE := io.ReadAll(io.NewSectionReader(r, 0, r.Size()))
+	if errE != nil {
+		t.Fatal("\n\t", errE)
+	}
+	if recordsForTests(t, all) != "order # 1order # 2order # 3" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, all), "order # 1order # 2order # 3")
+	}
+
+	_, errF := l.ReadAt(p, 1, 0)
+	if !errors.Is(errF, errSegmentNotFound) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errF, errSegmentNotFound)
+	}
+}
@@ -0,0 +1,116 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var errReaderClosed = errors.New("commitLog reader is closed")
+
+// LogReader streams the data of a commitlog, as Read returns it, across segments; holding one segment in memory at a
+// time. see Clog.Reader
+//
+// A LogReader is not safe for concurrent use.
+type LogReader struct {
+	l *Clog
+	// segs are the segments that are still to be read; they have been acquired.
+	segs []*segment
+	// buf is the data of the current segment that has not been read yet.
+	buf []byte
+	// reserved is how much of the memory budget of the commitlog buf holds.
+	reserved uint64
+	offset   uint64
+	err      error
+}
+
+// Reader returns a reader that streams the data of the commitlog, from the segments after offset(exclusive), as Read
+// would return it; eg to pipe a large commitlog to an HTTP response or a file without holding all of it in memory.
+// Use ScanRecords to split the stream into records.
+//
+// Like Read, it sees the commitlog as it was when Reader was called; the segments it is to read are not deleted from
+// the filesystem until it has read them, or it is closed. So it should always be closed.
+// If the commitlog has a memory budget(see WithMemoryBudget), each segment is read once the budget has room for it.
+//
+// usage:
+//
+//	r, errR := l.Reader(offset)
+//	defer r.Close()
+//	_, errC := io.Copy(w, r)
+//	offset = r.Offset()
+func (l *Clog) Reader(offset uint64) (*LogReader, error) {
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, errC
+	}
+	if offset != 0 && offset < l.lowWatermark {
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, &OutOfRangeError{Offset: offset, LowWatermark: lw}
+	}
+	segs := acquireAfter(l.segmentRead(), offset)
+	l.mu.RUnlock()
+
+	return &LogReader{l: l, segs: segs}, nil
+}
+
+// Read reads the data of the commitlog into p. It returns io.EOF once all the segments have been read.
+func (r *LogReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if len(r.segs) == 0 {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next moves on to the next segment, making its data the one that is read.
+func (r *LogReader) next() (err error) {
+	seg := r.segs[0]
+	r.segs = r.segs[1:]
+	// we do not care about this error; as with Clean, a segment that could not be removed is left behind.
+	defer func() { _ = seg.release() }()
+
+	r.l.budget.release(r.reserved)
+	r.reserved = 0
+	size := 0
+	defer func(start time.Time) { r.l.tracer.add("reader", start, size, err) }(time.Now())
+
+	// acquire only fails once its ctx is done.
+	r.reserved, _ = r.l.budget.acquire(context.Background(), seg.size())
+	b, err := seg.Read()
+	if err != nil {
+		r.l.reportCorruption(err)
+		return err
+	}
+	size = len(b)
+	r.buf = b
+	r.offset = seg.baseOffset
+	return nil
+}
+
+// Offset returns the baseOffset of the segment whose data is being read; 0 before any data has been read.
+// Once Read has returned io.EOF, it is the lastReadOffset that Read would have returned; the offset to read from next.
+func (r *LogReader) Offset() uint64 {
+	return r.offset
+}
+
+// Close releases the segments that have not been read. Reads after Close fail.
+func (r *LogReader) Close() error {
+	releaseSegments(r.segs)
+	r.segs = nil
+	r.buf = nil
+	r.l.budget.release(r.reserved)
+	r.reserved = 0
+	r.err = errReaderClosed
+	return nil
+}
@@ -0,0 +1,106 @@
+package clog
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestLogReader(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streams what Read reads", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 30, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3", "order # 4", "order # 5"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		want, lastReadOffset, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		r, errC := l.Reader(0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		defer r.Close()
+		errD := iotest.TestReader(r, want)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if r.Offset() != lastReadOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", r.Offset(), lastReadOffset)
+		}
+
+		// the stream splits into the records appended.
+		r2, errE := l.Reader(0)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		defer r2.Close()
+		sc := bufio.NewScanner(r2)
+		sc.Split(ScanRecords)
+		n := 0
+		for sc.Scan() {
+			n++
+		}
+		if sc.Err() != nil || n != 5 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", n, 5)
+		}
+	})
+
+	t.Run("segments are kept until read", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		// retention is of about two records.
+		l, err := New(path, 10, 2*(9+recordHeaderSize), time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3", "order # 4"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		r, errB := l.Reader(0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		errC := l.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		data, errD := io.ReadAll(r)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if got := recordsForTests(t, data); got != "order # 1order # 2order # 3order # 4" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "order # 1order # 2order # 3order # 4")
+		}
+
+		errE := r.Close()
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		_, errF := r.Read(make([]byte, 1))
+		if !errors.Is(errF, errReaderClosed) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errF, errReaderClosed)
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package clog
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrReadOnly is returned by appends to a commitlog that has entered read-only mode after repeated append failures.
+// see WithReadOnlyAfter
+var ErrReadOnly = errors.New("commitLog is read-only after repeated append failures")
+
+// ReadOnlyEvent describes a commitlog entering read-only mode. see WithReadOnlyAfter
+type ReadOnlyEvent struct {
+	// Path is the directory, in the filesystem, of the commitlog.
+	Path string
+	// Failures is the number of consecutive appends that failed.
+	Failures int
+	// LastErr is the error of the last of those appends.
+	LastErr error
+	// At is when the commitlog entered read-only mode.
+	At time.Time
+}
+
+// writeGuard puts a commitlog into read-only mode after maxFailures consecutive append failures.
+// A nil *writeGuard never does.
+//
+// Its fields are protected by the mu of the commitlog.
+type writeGuard struct {
+	maxFailures int
+	onReadOnly  func(ReadOnlyEvent)
+
+	failures int
+	readOnly bool
+}
+
+// observe records the outcome, err, of an append and reports whether the commitlog has just entered read-only mode.
+func (g *writeGuard) observe(err error) bool {
+	if g == nil {
+		return false
+	}
+	if err == nil {
+		g.failures = 0
+		return false
+	}
+	g.failures++
+	if g.failures < g.maxFailures || g.readOnly {
+		return false
+	}
+	g.readOnly = true
+	return true
+}
+
+// ReadOnly reports whether the commitlog is in read-only mode. see WithReadOnlyAfter
+func (l *Clog) ReadOnly() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.guard != nil && l.guard.readOnly
+}
+
+// ResumeWrites takes the commitlog out of read-only mode, once the cause of the append failures has been dealt with.
+// It is a no-op if the commitlog is not in read-only mode.
+func (l *Clog) ResumeWrites() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.guard == nil || !l.guard.readOnly {
+		return
+	}
+	l.guard.readOnly = false
+	l.guard.failures = 0
+	l.journal.record(OpsResumeWrites, "writes resumed")
+}
@@ -0,0 +1,123 @@
+package clog
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReadOnlyAfter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("enters read-only mode & resumes", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		events := []ReadOnlyEvent{}
+		l, err := New(path, 10_000, 100_000, time.Hour, WithReadOnlyAfter(3, func(e ReadOnlyEvent) {
+			events = append(events, e)
+		}))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		a, _ := l.activeSegment()
+		good := a.f
+		errWrite := errors.New("disk is failing")
+		a.f = mockFileFail{errWrite: errWrite, fName: good.Name()}
+
+		// a success in between failures resets the count.
+		for i := 0; i < 2; i++ {
+			_ = l.Append([]byte("hello"))
+		}
+		a.f = good
+		errB := l.Append([]byte("hello"))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		a.f = mockFileFail{errWrite: errWrite, fName: good.Name()}
+		for i := 0; i < 2; i++ {
+			_ = l.Append([]byte("hello"))
+		}
+		if l.ReadOnly() {
+			t.Fatal("read-only before 3 consecutive failures")
+		}
+
+		errC := l.Append([]byte("hello"))
+		if !errors.Is(errC, errWrite) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, errWrite)
+		}
+		if !l.ReadOnly() {
+			t.Fatal("not read-only after 3 consecutive failures")
+		}
+		if len(events) != 1 || events[0].Failures != 3 || !errors.Is(events[0].LastErr, errWrite) || events[0].Path != path {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", events, "one event of 3 failures")
+		}
+
+		// appends are refused, even though the disk has recovered; reads keep working.
+		a.f = good
+		errD := l.Append([]byte("hello"))
+		if !errors.Is(errD, ErrReadOnly) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, ErrReadOnly)
+		}
+		b, _, errE := l.Read(0, 0)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		if recordsForTests(t, b) != "hellohello" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, b), "hellohello")
+		}
+
+		l.ResumeWrites()
+		if l.ReadOnly() {
+			t.Fatal("read-only after ResumeWrites")
+		}
+		errF := l.Append([]byte("hello"))
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+
+		journal, errG := l.OpsJournal()
+		if errG != nil {
+			t.Fatal("\n\t", errG)
+		}
+		ops := []string{}
+		for _, e := range journal {
+			ops = append(ops, e.Op)
+		}
+		if ops[len(ops)-2] != OpsReadOnly || ops[len(ops)-1] != OpsResumeWrites {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ops, []string{OpsReadOnly, OpsResumeWrites})
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		a, _ := l.activeSegment()
+		good := a.f
+		a.f = mockFileFail{errWrite: errors.New("disk is failing"), fName: good.Name()}
+		for i := 0; i < 10; i++ {
+			_ = l.Append([]byte("hello"))
+		}
+		a.f = good
+
+		if l.ReadOnly() {
+			t.Fatal("read-only without WithReadOnlyAfter")
+		}
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	})
+}
@@ -0,0 +1,214 @@
+package clog
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"time"
+)
+
+// Records are framed, within segments, as:
+//
+//	length uint32 | crc32 uint32 | data [length]byte
+//
+// Integers are big endian. The crc32(Castagnoli) of a record covers its length & data.
+// Framing allows the records that are concatenated in a segment to be split apart again, and the checksum
+// allows bit rot & torn writes to be detected when they are read.
+const (
+	recordHeaderSize = 8
+	// maxRecordSize is the size of the largest record that can be framed.
+	maxRecordSize = math.MaxUint32
+)
+
+// ErrBadRecord is matched, using errors.Is, by the errors returned by Records when data is not a sequence of whole records.
+var ErrBadRecord = errors.New("data is not a sequence of whole records")
+
+var errRecordTooLarge = fmt.Errorf("record is larger than the maximum record size of %d bytes", maxRecordSize)
+
+// encodeRecord returns b, framed as a record.
+func encodeRecord(b []byte) []byte {
+	return appendRecord(make([]byte, 0, recordHeaderSize+len(b)), b)
+}
+
+// appendRecord appends b, framed as a record, to dst and returns the extended buffer.
+func appendRecord(dst []byte, b []byte) []byte {
+	return appendChecksummedRecord(dst, b, RecordChecksum(b))
+}
+
+// appendChecksummedRecord is appendRecord, for a b whose checksum, sum, has already been computed. see RecordChecksum
+func appendChecksummedRecord(dst []byte, b []byte, sum uint32) []byte {
+	var header [recordHeaderSize]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(b)))
+	binary.BigEndian.PutUint32(header[4:], sum)
+	dst = append(dst, header[:]...)
+	return append(dst, b...)
+}
+
+// recordChecksum returns the checksum of the record whose length field is length & whose data is b.
+func recordChecksum(length []byte, b []byte) uint32 {
+	return crc32.Update(crc32.Checksum(length, crcTable), crcTable, b)
+}
+
+// splitRecord splits the record at the start of data from the rest of data.
+// ok is false if data does not start with a whole record. sum is the checksum the record was framed with.
+func splitRecord(data []byte) (record []byte, sum uint32, rest []byte, ok bool) {
+	if len(data) < recordHeaderSize {
+		return nil, 0, data, false
+	}
+	n := uint64(binary.BigEndian.Uint32(data))
+	if uint64(len(data)-recordHeaderSize) < n {
+		return nil, 0, data, false
+	}
+	end := recordHeaderSize + int(n)
+	return data[recordHeaderSize:end:end], binary.BigEndian.Uint32(data[4:]), data[end:], true
+}
+
+// validRecord reports whether record, as split from the start of data, matches its checksum.
+func validRecord(data []byte, record []byte, sum uint32) bool {
+	return recordChecksum(data[:4], record) == sum
+}
+
+// Records splits data, as returned by Clog.Read, into the records that were appended to the commitlog; in order.
+// The records share their memory with data.
+// It returns an error matching ErrBadRecord, along with the records before it, if data does not end with a whole record
+// or a record does not match its checksum.
+//
+// usage:
+//
+//	data, lastReadOffset, err := l.Read(offset, 0)
+//	records, errR := clog.Records(data)
+//	for _, r := range records {
+//		process(r)
+//	}
+func Records(data []byte) ([][]byte, error) {
+	records := [][]byte{}
+	for pos := 0; pos < len(data); {
+		r, sum, _, ok := splitRecord(data[pos:])
+		if !ok {
+			return records, fmt.Errorf("%w: torn record at byte %d of %d", ErrBadRecord, pos, len(data))
+		}
+		if !validRecord(data[pos:], r, sum) {
+			return records, fmt.Errorf("%w: record at byte %d of %d does not match its checksum", ErrBadRecord, pos, len(data))
+		}
+		records = append(records, r)
+		pos = pos + recordHeaderSize + len(r)
+	}
+	return records, nil
+}
+
+// spanRecords splits the data of the segment of span s, within data as returned by Clog.ReadSpans, into its records;
+// & returns them alongside their offsets. Like Records, it returns the records before an error along with it.
+func spanRecords(data []byte, s ReadSpan) ([][]byte, []RecordOffset, error) {
+	records, err := Records(data[s.Start:s.End])
+	offsets := make([]RecordOffset, 0, len(records))
+	pos := uint64(0)
+	for i, r := range records {
+		offsets = append(offsets, RecordOffset{BaseOffset: s.BaseOffset, Position: pos, Sequence: s.FirstSequence + uint64(i)})
+		pos = pos + recordHeaderSize + uint64(len(r))
+	}
+	return records, offsets, err
+}
+
+// ReadRecords is Read; but it returns the records read, split apart, rather than their framed data, along with the
+// offset of each. It reads the segments after offset, one at a time, & stops once it has read at least maxRecords records.
+// If maxRecords <= 0, it reads as much as Read would.
+//
+// Offsets are those of segments; so the records of a segment are never split across calls & more than maxRecords
+// records may be returned. To carry on from where it stopped, read from the BaseOffset of the last of offsets.
+// No records means that there is nothing after offset to read. The records share their memory with each other, but not
+// with the commitlog.
+//
+// If it encounters an error, it still returns the records of the segments read before it & the error.
+//
+// usage:
+//
+//	records, offsets, err := l.ReadRecords(offset, 100)
+//	for _, r := range records {
+//		process(r)
+//	}
+//	if len(offsets) > 0 {
+//		offset = offsets[len(offsets)-1].BaseOffset
+//	}
+func (l *Clog) ReadRecords(offset uint64, maxRecords int) (records [][]byte, offsets []RecordOffset, err error) {
+	size := 0
+	defer func(start time.Time) { l.tracer.add("read-records", start, size, err) }(time.Now())
+
+	records, offsets = [][]byte{}, []RecordOffset{}
+	if maxRecords <= 0 {
+		data, spans, errR := l.read(context.Background(), offset, 0)
+		size = len(data)
+		for _, s := range spans {
+			rs, offs, errS := spanRecords(data, s)
+			records, offsets = append(records, rs...), append(offsets, offs...)
+			if errS != nil {
+				return records, offsets, errS
+			}
+		}
+		return records, offsets, errR
+	}
+
+	for len(records) < maxRecords {
+		// a maxToRead of 1 reads a single, non-empty, segment at a time.
+		data, spans, errR := l.read(context.Background(), offset, 1)
+		size = size + len(data)
+		for _, s := range spans {
+			rs, offs, errS := spanRecords(data, s)
+			records, offsets = append(records, rs...), append(offsets, offs...)
+			if errS != nil {
+				return records, offsets, errS
+			}
+			offset = s.BaseOffset
+		}
+		if errR != nil || len(spans) == 0 {
+			return records, offsets, errR
+		}
+	}
+	return records, offsets, nil
+}
+
+// ScanRecords is a split function, for a bufio.Scanner, that splits a stream that is in the framing of the commitlog,
+// eg the data returned by Read, into its records. see Clog.AppendFrom
+// A record that does not match its checksum, or that is torn at the end of the stream, fails the scan with an error
+// matching ErrBadRecord.
+func ScanRecords(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	r, sum, _, ok := splitRecord(data)
+	if !ok {
+		if atEOF && len(data) > 0 {
+			return 0, nil, fmt.Errorf("%w: torn record of %d bytes at the end of the stream", ErrBadRecord, len(data))
+		}
+		// request more data.
+		return 0, nil, nil
+	}
+	if !validRecord(data, r, sum) {
+		return 0, nil, fmt.Errorf("%w: record does not match its checksum", ErrBadRecord)
+	}
+	return recordHeaderSize + len(r), r, nil
+}
+
+// checkRecords returns a *CorruptionError if b, the data of the segment at path, is not a sequence of whole records
+// that each match their checksum.
+func checkRecords(path string, b []byte) error {
+	_, err := countRecords(path, b)
+	return err
+}
+
+// countRecords returns the number of records in b, the data of the segment at path; or a *CorruptionError if b is
+// not a sequence of whole records that each match their checksum.
+func countRecords(path string, b []byte) (uint64, error) {
+	n := uint64(0)
+	for pos := 0; pos < len(b); {
+		r, sum, _, ok := splitRecord(b[pos:])
+		if !ok {
+			return n, newRecordCorruption(path, uint64(pos), b[pos:])
+		}
+		if !validRecord(b[pos:], r, sum) {
+			return n, newChecksumCorruption(path, uint64(pos), b[pos:pos+recordHeaderSize+len(r)], sum)
+		}
+		pos = pos + recordHeaderSize + len(r)
+		n++
+	}
+	return n, nil
+}
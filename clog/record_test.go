@@ -0,0 +1,236 @@
+package clog
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecords(t *testing.T) {
+	t.Parallel()
+
+	t.Run("records are split apart", func(t *testing.T) {
+		t.Parallel()
+
+		want := [][]byte{[]byte("hello"), {}, []byte("world")}
+		data := []byte{}
+		for _, r := range want {
+			data = append(data, encodeRecord(r)...)
+		}
+
+		got, err := Records(data)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%q \nwanted \n\t%q", got, want)
+		}
+	})
+
+	t.Run("torn record", func(t *testing.T) {
+		t.Parallel()
+
+		data := append(encodeRecord([]byte("hello")), encodeRecord([]byte("world"))[:7]...)
+		got, err := Records(data)
+		if !errors.Is(err, ErrBadRecord) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, ErrBadRecord)
+		}
+		if len(got) != 1 || string(got[0]) != "hello" {
+			t.Errorf("\ngot \n\t%q \nwanted \n\t%q", got, []string{"hello"})
+		}
+	})
+
+	t.Run("read records from a commitlog", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 12, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		want := []string{"order # 1", "order # 2", "order # 3"}
+		for _, r := range want {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		data, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		records, errC := Records(data)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		got := []string{}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("torn write is corruption", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		// a crash in the middle of an append leaves part of a record behind.
+		a, _ := l.activeSegment()
+		f, errB := os.OpenFile(a.filePath, os.O_WRONLY|os.O_APPEND, ownerReadableWritable)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		_, _ = f.Write(encodeRecord([]byte("world"))[:6])
+		_ = f.Close()
+
+		l2, errC := New(path, 100, 10_000, time.Hour)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		_, _, errD := l2.Read(0, 0)
+		var ce *CorruptionError
+		if !errors.As(errD, &ce) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, &CorruptionError{})
+		}
+		if ce.Check != "record length" || ce.Start != recordHeaderSize+5 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ce, "torn record after the first one")
+		}
+	})
+
+	t.Run("bit rot is corruption", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"hello", "world"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		// flip a bit in the data of the second record, behind the commitlog's back.
+		a, _ := l.activeSegment()
+		f, errB := os.OpenFile(a.filePath, os.O_RDWR, ownerReadableWritable)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		pos := int64(2*recordHeaderSize + 5 + 1)
+		b := make([]byte, 1)
+		_, _ = f.ReadAt(b, pos)
+		_, _ = f.WriteAt([]byte{b[0] ^ 1}, pos)
+		_ = f.Close()
+
+		l2, errC := New(path, 100, 10_000, time.Hour)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		_, _, errD := l2.Read(0, 0)
+		var ce *CorruptionError
+		if !errors.As(errD, &ce) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, &CorruptionError{})
+		}
+		if ce.Path != a.filePath || ce.Check != "record checksum" || ce.Start != recordHeaderSize+5 || ce.End != 2*(recordHeaderSize+5) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ce, "second record fails its checksum")
+		}
+		if ce.Expected == ce.Actual {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ce.Actual, "a different checksum")
+		}
+	})
+
+	t.Run("records are checked against their checksum", func(t *testing.T) {
+		t.Parallel()
+
+		data := append(encodeRecord([]byte("hello")), encodeRecord([]byte("world"))...)
+		data[len(data)-1] = 'x'
+		got, err := Records(data)
+		if !errors.Is(err, ErrBadRecord) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, ErrBadRecord)
+		}
+		if len(got) != 1 || string(got[0]) != "hello" {
+			t.Errorf("\ngot \n\t%q \nwanted \n\t%q", got, []string{"hello"})
+		}
+	})
+}
+
+func TestReadRecords(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each segment holds two records.
+	l, err := New(path, 2*(9+recordHeaderSize), 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	want := []string{}
+	for i := 0; i < 6; i++ {
+		r := "order # " + string(rune('0'+i))
+		want = append(want, r)
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	got := []string{}
+	offset := uint64(0)
+	for i := 0; i < len(want); i++ {
+		records, offsets, errR := l.ReadRecords(offset, 1)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		if len(records) == 0 {
+			break
+		}
+		// whole segments are read; but no more of them than it takes to read maxRecords.
+		if len(records) != 2 || len(offsets) != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(records), 2)
+		}
+		for j, r := range records {
+			got = append(got, string(r))
+			if o := offsets[j]; o.Sequence != uint64(len(got)) || o.BaseOffset != offsets[0].BaseOffset {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", o, len(got))
+			}
+		}
+		offset = offsets[len(offsets)-1].BaseOffset
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	some, _, errA := l.ReadRecords(0, 3)
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	if len(some) != 4 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(some), 4)
+	}
+
+	all, _, errB := l.ReadRecords(0, 0)
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	if len(all) != len(want) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(all), len(want))
+	}
+}
@@ -0,0 +1,320 @@
+package clog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrRelocating is returned by appends, & other changes to a commitlog, while it is being moved to another directory.
+// see Clog.Relocate
+var ErrRelocating = errors.New("commitLog is being relocated")
+
+// ErrRelocated is matched, using errors.Is, by the error that opening the directory that a commitlog was copied away
+// from returns; if the process crashed before Relocate could remove that directory. see Clog.Relocate
+var ErrRelocated = errors.New("commitLog has been relocated")
+
+var (
+	errRelocated = func(path, newPath string) error {
+		return fmt.Errorf("%w: %s has been moved to %s", ErrRelocated, path, newPath)
+	}
+	errRelocateExists = errors.New("relocate target already exists")
+	errRelocate       = func(err error) error { return fmt.Errorf("relocate failed: %w", err) }
+	errRelocateVerify = errors.New("relocate failed: copy does not match the original")
+)
+
+// Relocate moves the commitlog, while it is open, from its directory to newPath; eg off a disk that is filling up.
+// newPath should not exist. Segments that live in the stripes of the commitlog are not moved. see WithStripes
+//
+// If newPath is in the same filesystem, the directory is renamed; appends & reads wait for the rename.
+// Otherwise, the files of the commitlog are copied to newPath & each copy is read back & compared to its original.
+// While they are copied, reads are served from the old directory, but appends, Clean & TagSegment fail with
+// ErrRelocating. Once all the copies are verified, the commitlog swaps over to newPath & the old directory is removed.
+// If the copy fails, the commitlog is left where it was & what was copied is removed.
+//
+// Before the files of the old directory are removed, its manifest is replaced with one that points to newPath; so if
+// the process crashes part way through removing them, opening what is left of the old directory fails with
+// ErrRelocated rather than opening a partial commitlog.
+//
+// usage:
+//
+//	errR := l.Relocate("/mnt/bigdisk/orders")
+func (l *Clog) Relocate(newPath string) (err error) {
+	defer func(start time.Time) { l.tracer.add("relocate", start, 0, err) }(time.Now())
+
+	newPath = filepath.Clean(newPath)
+
+	l.mu.Lock()
+	if !l.initialized {
+		l.mu.Unlock()
+		return errLogNotInitialized
+	}
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.Unlock()
+		return errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		l.mu.Unlock()
+		return errC
+	}
+	if l.relocating {
+		l.mu.Unlock()
+		return ErrRelocating
+	}
+	oldPath := filepath.Clean(l.path)
+	if newPath == oldPath {
+		l.mu.Unlock()
+		return nil
+	}
+	_, errS := l.fs.Stat(newPath)
+	if errS == nil {
+		l.mu.Unlock()
+		return errRelocateExists
+	}
+	if !errors.Is(errS, fs.ErrNotExist) {
+		l.mu.Unlock()
+		return errStatFile(errS)
+	}
+	errM := l.fs.MkdirAll(filepath.Dir(newPath), ownerReadableWritable)
+	if errM != nil {
+		l.mu.Unlock()
+		return errMkDir(errM)
+	}
+
+	// same filesystem; the rename moves all the files at once.
+	unlockSegments := l.lockSegments()
+	errR := l.fs.Rename(oldPath, newPath)
+	if errR == nil {
+		l.movePaths(oldPath, newPath)
+		unlockSegments()
+		l.mu.Unlock()
+		l.journal.record(OpsRelocate, "renamed from %s to %s", oldPath, newPath)
+		return nil
+	}
+	unlockSegments()
+	if !errors.Is(errR, syscall.EXDEV) {
+		l.mu.Unlock()
+		return errRelocate(errR)
+	}
+
+	// another filesystem; the files are copied, with appends refused until the commitlog has swapped over.
+	files, errL := l.listFiles(oldPath, "")
+	if errL != nil {
+		l.mu.Unlock()
+		return errL
+	}
+	l.relocating = true
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.relocating = false
+		l.mu.Unlock()
+	}()
+
+	errC := l.copyFiles(oldPath, newPath, files)
+	if errC != nil {
+		l.removeFiles(newPath, files)
+		return errC
+	}
+
+	l.mu.Lock()
+	unlockSegments = l.lockSegments()
+	errO := l.reopenSegments(oldPath, newPath)
+	if errO != nil {
+		unlockSegments()
+		l.mu.Unlock()
+		l.removeFiles(newPath, files)
+		return errO
+	}
+	l.movePaths(oldPath, newPath)
+	l.removeStaleCopies(newPath, files)
+	unlockSegments()
+	l.mu.Unlock()
+
+	errI := (&manifest{Version: FormatVersion, RelocatedTo: newPath}).write(l.fs, oldPath)
+	if errI != nil {
+		// without the pointer to newPath, a crash part way through removing the old files would leave a partial
+		// commitlog behind; a whole one is better.
+		l.journal.record(OpsRelocate, "copied %d files from %s to %s, but left %s in place: %v", len(files), oldPath, newPath, oldPath, errI)
+		return nil
+	}
+	// the manifest, that points to newPath, is removed last.
+	old := []string{}
+	for _, rel := range files {
+		if rel != manifestName {
+			old = append(old, rel)
+		}
+	}
+	l.removeFiles(oldPath, append(old, manifestName))
+	l.journal.record(OpsRelocate, "copied %d files from %s to %s", len(files), oldPath, newPath)
+	return nil
+}
+
+// lockSegments takes the mu of each segment of the commitlog, including those whose removal is pending, so that no
+// segment is read while its file moves. It returns a func that releases them. The caller should hold l.mu
+func (l *Clog) lockSegments() func() {
+	segs := l.relocatableSegments()
+	for _, seg := range segs {
+		seg.mu.Lock()
+	}
+	return func() {
+		for _, seg := range segs {
+			seg.mu.Unlock()
+		}
+	}
+}
+
+// relocatableSegments returns the segments whose files move with the commitlog; those whose removal is pending on
+// reads included, so that they are removed from where their files end up. The caller should hold l.mu
+func (l *Clog) relocatableSegments() []*segment {
+	return append(append([]*segment{}, l.segmentRead()...), l.pendingDeletes...)
+}
+
+// movePaths points the commitlog, & those of its segments that live in oldPath, at newPath.
+// The caller should hold l.mu & the mu of each segment. see lockSegments
+func (l *Clog) movePaths(oldPath, newPath string) {
+	for _, seg := range l.relocatableSegments() {
+		if rel, ok := relativeTo(oldPath, seg.filePath); ok {
+			seg.filePath = filepath.Join(newPath, rel)
+		}
+	}
+	l.path = newPath
+
+	l.journal.mu.Lock()
+	l.journal.dir = newPath
+	l.journal.mu.Unlock()
+}
+
+// reopenSegments replaces the open files of the segments that live in oldPath with their copies in newPath.
+// Either all of them are replaced, or, if any fails to open, none is.
+// The caller should hold l.mu & the mu of each segment. see lockSegments
+func (l *Clog) reopenSegments(oldPath, newPath string) error {
+	type reopened struct {
+		seg *segment
+		f   File
+	}
+	opened := []reopened{}
+	for _, seg := range l.relocatableSegments() {
+		rel, ok := relativeTo(oldPath, seg.filePath)
+		if !ok || seg.closed || seg.f == nil {
+			continue
+		}
+		f, err := l.fs.OpenFile(filepath.Join(newPath, rel), os.O_RDWR|os.O_APPEND, ownerReadableWritable)
+		if err != nil {
+			for _, o := range opened {
+				_ = o.f.Close()
+			}
+			return errRelocate(errOpenFile(err))
+		}
+		opened = append(opened, reopened{seg: seg, f: f})
+	}
+
+	for _, o := range opened {
+		// the data of the old file has been copied; so failing to close it loses nothing.
+		_ = o.seg.f.Close()
+		o.seg.f = o.f
+	}
+	return nil
+}
+
+// removeStaleCopies removes, from newPath, the copies of the segments that were removed while files, whose paths are
+// relative to newPath, were being copied; eg a segment whose removal was pending on a read that has since finished.
+// The caller should hold l.mu & the mu of each segment. see lockSegments
+func (l *Clog) removeStaleCopies(newPath string, files []string) {
+	live := map[string]bool{}
+	for _, seg := range l.relocatableSegments() {
+		if seg.f != nil {
+			live[seg.filePath] = true
+		}
+	}
+	for _, rel := range files {
+		p := filepath.Join(newPath, rel)
+		if _, ok, err := l.layout.ParseSegmentPath(rel); err == nil && ok && !live[p] {
+			_ = l.fs.Remove(p)
+		}
+	}
+}
+
+// copyFiles copies files, whose paths are relative to oldPath, into newPath; reading each copy back to verify it.
+// A file that no longer exists is skipped; appends & Clean are refused while the files are copied, but a segment whose
+// removal was pending on a read is removed once the read is done.
+func (l *Clog) copyFiles(oldPath, newPath string, files []string) error {
+	for _, rel := range files {
+		b, err := l.fs.ReadFile(filepath.Join(oldPath, rel))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return errRelocate(err)
+		}
+
+		dst := filepath.Join(newPath, rel)
+		errM := l.fs.MkdirAll(filepath.Dir(dst), ownerReadableWritable)
+		if errM != nil {
+			return errMkDir(errM)
+		}
+		f, errO := l.fs.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, ownerReadableWritable)
+		if errO != nil {
+			return errRelocate(errOpenFile(errO))
+		}
+		_, errW := f.Write(b)
+		if errW == nil {
+			errW = f.Sync()
+		}
+		errC := f.Close()
+		if errW != nil {
+			return errRelocate(errW)
+		}
+		if errC != nil {
+			return errRelocate(errC)
+		}
+
+		c, errR := l.fs.ReadFile(dst)
+		if errR != nil {
+			return errRelocate(errR)
+		}
+		if !bytes.Equal(c, b) {
+			return errRelocateVerify
+		}
+	}
+	return nil
+}
+
+// removeFiles removes files, whose paths are relative to root, & then the directories, root included, that held them.
+// It is best effort; a directory that still holds other files is left in place.
+func (l *Clog) removeFiles(root string, files []string) {
+	dirs := map[string]bool{root: true}
+	for _, rel := range files {
+		p := filepath.Join(root, rel)
+		_ = l.fs.Remove(p)
+		for d := filepath.Dir(p); d != root && strings.HasPrefix(d, root); d = filepath.Dir(d) {
+			dirs[d] = true
+		}
+	}
+
+	// the deepest directories are removed first.
+	ordered := make([]string, 0, len(dirs))
+	for d := range dirs {
+		ordered = append(ordered, d)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return len(ordered[i]) > len(ordered[j]) })
+	for _, d := range ordered {
+		_ = l.fs.Remove(d)
+	}
+}
+
+// relativeTo returns the path of p relative to dir, if p is within dir.
+func relativeTo(dir, p string) (string, bool) {
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	if !strings.HasPrefix(p, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(p, prefix), true
+}
@@ -0,0 +1,284 @@
+package clog
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// crossDeviceFS is a filesystem whose renames of directories fail as they do across filesystems.
+// onCopy, if not nil, is called whenever a file is created exclusively; as Relocate does for its copies.
+// onRemove, if not nil, is called before a file is removed; an error it returns fails the removal.
+type crossDeviceFS struct {
+	OSFileSystem
+	onCopy   func()
+	onRemove func(name string) error
+}
+
+func (c *crossDeviceFS) Remove(name string) error {
+	if c.onRemove != nil {
+		if err := c.onRemove(name); err != nil {
+			return err
+		}
+	}
+	return c.OSFileSystem.Remove(name)
+}
+
+func (c *crossDeviceFS) Rename(oldpath, newpath string) error {
+	if fi, err := os.Stat(oldpath); err == nil && fi.IsDir() {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	return c.OSFileSystem.Rename(oldpath, newpath)
+}
+
+func (c *crossDeviceFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&os.O_EXCL != 0 && c.onCopy != nil {
+		c.onCopy()
+	}
+	return c.OSFileSystem.OpenFile(name, flag, perm)
+}
+
+func TestRelocate(t *testing.T) {
+	t.Parallel()
+
+	checkRelocated := func(t *testing.T, l *Clog, oldPath, newPath string) {
+		t.Helper()
+		if _, err := os.Stat(oldPath); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, fs.ErrNotExist)
+		}
+		errA := l.Append([]byte("order # 4"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		want := "order # 1order # 2order # 3order # 4"
+		data, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if got := recordsForTests(t, data); got != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// the commitlog opens from its new directory.
+		l2, errC := New(newPath, 20, 100_000, time.Hour)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		data2, _, errD := l2.Read(0, 0)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		if got := recordsForTests(t, data2); got != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+		journal, errE := l2.OpsJournal()
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		relocations := 0
+		for _, e := range journal {
+			if e.Op == OpsRelocate {
+				relocations++
+			}
+		}
+		if relocations != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", relocations, 1)
+		}
+	}
+
+	t.Run("same filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		oldPath, removePath := createPathForTests(t)
+		defer removePath()
+		newParent, removeNewParent := createPathForTests(t)
+		defer removeNewParent()
+		newPath := filepath.Join(newParent, "orders")
+
+		l, err := New(oldPath, 20, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		errR := l.Relocate(newPath)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		checkRelocated(t, l, oldPath, newPath)
+	})
+
+	t.Run("another filesystem", func(t *testing.T) {
+		t.Parallel()
+
+		var l *Clog
+		errs := []error{}
+		cfs := &crossDeviceFS{}
+		cfs.onCopy = func() {
+			errs = append(errs, l.Append([]byte("order # x")), l.Clean())
+		}
+		oldPath, removePath := createPathForTests(t)
+		defer removePath()
+		newParent, removeNewParent := createPathForTests(t)
+		defer removeNewParent()
+		newPath := filepath.Join(newParent, "orders")
+
+		var err error
+		l, err = New(oldPath, 20, 100_000, time.Hour, WithFileSystem(cfs))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		errR := l.Relocate(newPath)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		if len(errs) == 0 {
+			t.Fatal("expected files to be copied")
+		}
+		for _, e := range errs {
+			if !errors.Is(e, ErrRelocating) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", e, ErrRelocating)
+			}
+		}
+		cfs.onCopy = nil
+		checkRelocated(t, l, oldPath, newPath)
+	})
+
+	t.Run("old directory is not mistaken for the commitlog", func(t *testing.T) {
+		t.Parallel()
+
+		oldPath, removePath := createPathForTests(t)
+		defer removePath()
+		newParent, removeNewParent := createPathForTests(t)
+		defer removeNewParent()
+		newPath := filepath.Join(newParent, "orders")
+
+		cfs := &crossDeviceFS{}
+		l, err := New(oldPath, 20, 100_000, time.Hour, WithFileSystem(cfs))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+			errA := l.Append([]byte(r))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		// as if the process crashed part way through removing the old files.
+		removed := 0
+		cfs.onRemove = func(name string) error {
+			if _, ok := relativeTo(oldPath, name); ok {
+				removed++
+				if removed > 1 {
+					return errors.New("crashed")
+				}
+			}
+			return nil
+		}
+		errR := l.Relocate(newPath)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+
+		_, errN := New(oldPath, 20, 100_000, time.Hour)
+		if !errors.Is(errN, ErrRelocated) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errN, ErrRelocated)
+		}
+		_, errO := OpenReadOnly(oldPath)
+		if !errors.Is(errO, ErrRelocated) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errO, ErrRelocated)
+		}
+		if got := l.Path(); got != newPath {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, newPath)
+		}
+	})
+
+	t.Run("segments pending deletion move too", func(t *testing.T) {
+		t.Parallel()
+
+		for _, fsys := range []FileSystem{OSFileSystem{}, &crossDeviceFS{}} {
+			oldPath, removePath := createPathForTests(t)
+			defer removePath()
+			newParent, removeNewParent := createPathForTests(t)
+			defer removeNewParent()
+			newPath := filepath.Join(newParent, "orders")
+
+			// each segment holds a single record, & retention is of about two records.
+			l, err := New(oldPath, 10, 2*(9+recordHeaderSize), time.Hour, WithFileSystem(fsys))
+			if err != nil {
+				t.Fatal("\n\t", err)
+			}
+			for _, r := range []string{"order # 1", "order # 2", "order # 3", "order # 4"} {
+				errA := l.Append([]byte(r))
+				if errA != nil {
+					t.Fatal("\n\t", errA)
+				}
+			}
+			oldest := l.segmentRead()[0]
+			if !oldest.acquire() {
+				t.Fatal("could not acquire the oldest segment")
+			}
+			errC := l.Clean()
+			if errC != nil {
+				t.Fatal("\n\t", errC)
+			}
+			if len(l.pendingDeletes) == 0 {
+				t.Fatal("expected a segment to be pending deletion")
+			}
+
+			errR := l.Relocate(newPath)
+			if errR != nil {
+				t.Fatal("\n\t", errR)
+			}
+			if _, ok := relativeTo(newPath, oldest.filePath); !ok {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", oldest.filePath, newPath)
+			}
+			// once the read is done, the segment is removed from where it was moved to.
+			errL := oldest.release()
+			if errL != nil {
+				t.Fatal("\n\t", errL)
+			}
+			if _, errS := os.Stat(oldest.filePath); !errors.Is(errS, fs.ErrNotExist) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errS, fs.ErrNotExist)
+			}
+			errD := l.Close()
+			if errD != nil {
+				t.Fatal("\n\t", errD)
+			}
+		}
+	})
+
+	t.Run("target exists", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		newPath, removeNewPath := createPathForTests(t)
+		defer removeNewPath()
+		l, err := New(path, 20, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errR := l.Relocate(newPath)
+		if !errors.Is(errR, errRelocateExists) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errR, errRelocateExists)
+		}
+		if l.path != path {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", l.path, path)
+		}
+	})
+}
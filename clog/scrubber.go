@@ -0,0 +1,147 @@
+package clog
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errBadScrubber = errors.New("scrubber cannot have a nil commitLog/onErr or a negative or zero interval")
+
+// Scrubber slowly walks the sealed segments of a commitlog verifying them.
+// This allows corruption to be reported long before a consumer trips over it.
+//
+// To create a scrubber, use the NewScrubber method.
+type Scrubber struct {
+	l        *Clog
+	interval time.Duration
+	onErr    func(error)
+
+	// lastScrubbed is the baseOffset of the segment that was verified most recently.
+	// It is only accessed from the scrubber's goroutine.
+	lastScrubbed uint64
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewScrubber creates a scrubber for the commitlog l.
+//
+// Once started, the scrubber verifies one sealed segment every interval; moving on to the next one
+// and wrapping around after the latest sealed segment.
+// The scrubber accesses the disk as configured by the commitlog's WithBackgroundIO option;
+// if a rate cap is configured, the scrubber waits longer than interval after verifying large segments.
+// Whenever verification of a segment fails, onErr is called with the error.
+// If the failure is due to corruption, the commitlog's corruption handler is called as well; see WithCorruptionHandler.
+// The active segment is never verified since it is still being written to.
+//
+// usage:
+//
+//	s, errN := NewScrubber(l, time.Minute, func(err error) { log.Println(err) })
+//	s.Start()
+//	defer s.Stop()
+func NewScrubber(l *Clog, interval time.Duration, onErr func(error)) (*Scrubber, error) {
+	if l == nil || onErr == nil || interval <= 0 {
+		return nil, errBadScrubber
+	}
+
+	return &Scrubber{
+		l:        l,
+		interval: interval,
+		onErr:    onErr,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start runs the scrubber in a background goroutine.
+// Calling Start more than once has no effect.
+func (s *Scrubber) Start() {
+	s.startOnce.Do(func() {
+		go s.run()
+	})
+}
+
+// Stop stops the scrubber and waits for its goroutine to exit.
+// It is safe to call Stop more than once, or without having called Start.
+func (s *Scrubber) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	s.startOnce.Do(func() {
+		// the scrubber was never started; there's no goroutine to wait for.
+		close(s.done)
+	})
+	<-s.done
+}
+
+func (s *Scrubber) run() {
+	defer close(s.done)
+
+	errA := s.l.bgIO.apply()
+	if errA != nil {
+		s.onErr(errA)
+	}
+
+	wait := s.interval
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		n, err := s.scrubNext()
+		if err != nil {
+			s.l.reportCorruption(err)
+			s.onErr(err)
+		}
+
+		wait = s.interval
+		p := s.l.bgIO.pause(n)
+		if p > wait {
+			wait = p
+		}
+	}
+}
+
+// scrubNext verifies the sealed segment that comes after the one that was verified last.
+// It returns the number of bytes verified.
+func (s *Scrubber) scrubNext() (uint64, error) {
+	seg := s.nextSegment()
+	if seg == nil {
+		// there are no sealed segments yet.
+		return 0, nil
+	}
+	s.lastScrubbed = seg.baseOffset
+	if !seg.acquire() {
+		// the segment has been deleted, eg by Clean, after we picked it.
+		return 0, nil
+	}
+	defer func() { _ = seg.release() }()
+
+	return seg.verify()
+}
+
+func (s *Scrubber) nextSegment() *segment {
+	s.l.mu.RLock()
+	defer s.l.mu.RUnlock()
+
+	segs := s.l.segmentRead()
+	if len(segs) <= 1 {
+		return nil
+	}
+	sealed := segs[:len(segs)-1] // the latest segment is the active one.
+
+	for _, seg := range sealed {
+		if seg.baseOffset > s.lastScrubbed {
+			return seg
+		}
+	}
+	// wrap around.
+	return sealed[0]
+}
@@ -0,0 +1,161 @@
+package clog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewScrubber(t *testing.T) {
+	t.Parallel()
+
+	l, removePath := createClogForTests(t)
+	defer removePath()
+	onErr := func(error) {}
+
+	_, errA := NewScrubber(nil, time.Second, onErr)
+	if !errors.Is(errA, errBadScrubber) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, errBadScrubber)
+	}
+	_, errB := NewScrubber(l, 0, onErr)
+	if !errors.Is(errB, errBadScrubber) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, errBadScrubber)
+	}
+	_, errC := NewScrubber(l, time.Second, nil)
+	if !errors.Is(errC, errBadScrubber) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, errBadScrubber)
+	}
+	s, errD := NewScrubber(l, time.Second, onErr)
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	// stopping a scrubber that was never started should not block.
+	s.Stop()
+	s.Stop()
+}
+
+func TestScrub(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no sealed segments", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t)
+		defer removePath()
+
+		s, errA := NewScrubber(l, time.Second, func(error) {})
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		_, errB := s.scrubNext()
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+	})
+
+	t.Run("walks all sealed segments", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t)
+		defer removePath()
+
+		msg := []byte(strings.Repeat("a", int(l.maxSegBytes*2)))
+		for i := 0; i < 4; i++ {
+			errA := l.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		if len(l.segments) != 4 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(l.segments), 4)
+		}
+
+		s, errB := NewScrubber(l, time.Second, func(error) {})
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		for i := 0; i < 4; i++ {
+			_, errC := s.scrubNext()
+			if errC != nil {
+				t.Fatal("\n\t", errC)
+			}
+			// the active segment(index 3) is never scrubbed; so we wrap around.
+			want := l.segments[i%3].baseOffset
+			if s.lastScrubbed != want {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.lastScrubbed, want)
+			}
+		}
+	})
+
+	t.Run("detects corruption", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t)
+		defer removePath()
+
+		msg := []byte(strings.Repeat("a", int(l.maxSegBytes*2)))
+		for i := 0; i < 2; i++ {
+			errA := l.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		// truncate a sealed segment behind the commitlog's back.
+		errB := os.Truncate(l.segments[0].filePath, 3)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		s, errC := NewScrubber(l, time.Second, func(error) {})
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		_, errD := s.scrubNext()
+		if errD == nil {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errD, "nonNilError")
+		}
+	})
+
+	t.Run("background scrubber reports corruption", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t)
+		defer removePath()
+
+		msg := []byte(strings.Repeat("a", int(l.maxSegBytes*2)))
+		for i := 0; i < 2; i++ {
+			errA := l.Append(msg)
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		errB := os.Truncate(l.segments[0].filePath, 3)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		var mu sync.Mutex
+		var reported []error
+		s, errC := NewScrubber(l, time.Millisecond, func(err error) {
+			mu.Lock()
+			reported = append(reported, err)
+			mu.Unlock()
+		})
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		s.Start()
+		time.Sleep(50 * time.Millisecond)
+		s.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reported) < 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(reported), ">=1")
+		}
+	})
+}
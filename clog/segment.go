@@ -1,8 +1,8 @@
 package clog
 
 import (
-	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
@@ -20,60 +20,82 @@ var (
 	errSegmentRead          = func(err error) error { return fmt.Errorf("segment read failed: %w", err) }
 )
 
-type readWriteCloserSyncerTruncater interface {
-	io.ReadWriteCloser
-	Name() string
-	Sync() error
-	Truncate(size int64) error
-}
-
 type segment struct {
 	baseOffset uint64
-	filePath   string
+	// firstSeq is the sequence number of the first record of the segment. It is set once, before the segment is
+	// shared, & does not change afterwards. see Clog.AppendOffset
+	firstSeq uint64
+	filePath string
+	fsys     FileSystem
+	// fsyncMon, if not nil, tracks fsyncs of the segment that stall.
+	fsyncMon *fsyncMonitor
+	// journal, if not nil, records truncations of the segment.
+	journal *opsJournal
+	// clock tells the age of the segment. see WithClock
+	clock clock
 
-	// mu protects currentSegBytes, maxSegBytes, f & age
+	// mu protects currentSegBytes, maxSegBytes, f, age, tags, refs, deletePending, cache, records & sealErr
 	mu              sync.RWMutex
 	currentSegBytes uint64
 	maxSegBytes     uint64
-	f               readWriteCloserSyncerTruncater
-	age             uint64 // diff between now() - baseOffset
+	f               File
+	age             uint64            // diff between now() - baseOffset
+	tags            map[string]string // see Clog.TagSegment
+	// refs is the number of readers that are using the segment. see acquire
+	refs int
+	// deletePending is set when the segment is deleted while it has readers.
+	// It is removed from the filesystem once the last of them releases it.
+	deletePending bool
+	// cache, if not nil, holds all the data of the segment; so that reads of the hot tail of the commitlog are
+	// served from memory. It is dropped once the segment grows beyond cacheMax. see WithTailCache
+	cache    []byte
+	cacheMax uint64
+	// records is the number of records in the segment, if recordsKnown. It is not known for a segment that already
+	// held data when it was opened, until it is counted. see recordCount
+	records      uint64
+	recordsKnown bool
+	// sum is the crc32(Castagnoli) of the data of the segment, if sumKnown. It is not known for a segment that already
+	// held data when it was opened.
+	sum      uint32
+	sumKnown bool
+	// seal, if not nil, is the size & checksum of the data of the segment when it was sealed. see sealData
+	seal *segmentSeal
+	// readOnly is set for the segments of a commitlog opened with OpenReadOnly; another process appends to them.
+	readOnly bool
+	// sealErr is the error, if any, with which the segment failed to be synced & closed when it was sealed. The appends
+	// written to it that were not synced before then are not durable. see Clog.commit
+	sealErr error
 
 	closed bool
 }
 
-func newSegment(path string, baseOffset uint64, maxSegBytes uint64) (*segment, error) {
-	filePath := filepath.Join(path, fmt.Sprintf("%d.log", baseOffset))
-	f, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, ownerReadableWritable)
+func newSegment(fsys FileSystem, filePath string, baseOffset uint64, maxSegBytes uint64) (*segment, error) {
+	err := fsys.MkdirAll(filepath.Dir(filePath), ownerReadableWritable)
+	if err != nil {
+		return nil, errMkDir(err)
+	}
+	f, err := fsys.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, ownerReadableWritable)
 	if err != nil {
 		return nil, errOpenFile(err)
 	}
 
-	fi, err := f.Stat()
+	fi, err := fsys.Stat(filePath)
 	if err != nil {
+		_ = f.Close()
 		return nil, errStatFile(err)
 	}
 
-	var age uint64
-	now := tNow()
-	if baseOffset > now {
-		// The segment appears to have been created in the future. Is that you Einstein?
-		// Although it would be pleasing to Albert, we are not amused.
-		// Set age to 0; as if the segment has just been created.
-		//
-		// uint64(7) - uint64(12) == 18446744073709551611
-		// because of overflow. So we have to prevent that
-		age = uint64(0)
-	} else {
-		age = now - baseOffset
-	}
-
+	// the clock, & thus the age, of a segment that is part of a commitlog is set by Clog.createSegment
 	return &segment{
 		filePath:        filePath,
+		fsys:            fsys,
 		baseOffset:      baseOffset,
 		currentSegBytes: uint64(fi.Size()),
 		maxSegBytes:     maxSegBytes,
 		f:               f,
-		age:             age,
+		age:             clock(nil).age(baseOffset),
+		recordsKnown:    fi.Size() == 0,
+		sumKnown:        fi.Size() == 0,
 	}, nil
 }
 
@@ -89,48 +111,196 @@ func (s *segment) IsFull() bool {
 	return r
 }
 
-// Append adds an item to the segment.
+// failSeal records that the segment failed to be synced & closed, with err, when it was sealed. see sealErr
+func (s *segment) failSeal(err error) {
+	s.mu.Lock()
+	s.sealErr = err
+	s.mu.Unlock()
+}
+
+// sealError returns the error with which the segment failed to be sealed, if any. see sealErr
+func (s *segment) sealError() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sealErr
+}
+
+// size returns the number of bytes held by the segment.
+func (s *segment) size() uint64 {
+	s.mu.RLock()
+	r := s.currentSegBytes
+	s.mu.RUnlock()
+	return r
+}
+
+// Append adds an item to the segment, and syncs it to stable storage.
 // To append more items at once use AppendBulk
 func (s *segment) Append(b []byte) error {
+	return s.append(b, true)
+}
+
+// append adds an item to the segment. If sync is false, the item is left for the operating system to flush.
+func (s *segment) append(b []byte, sync bool) error {
+	_, _, err := s.appendBulk([][]byte{b}, nil, sync)
+	return err
+}
+
+// AppendBulk adds multiple items to the segment, and syncs them to stable storage.
+// To append one item at a time use Append
+func (s *segment) AppendBulk(bbs [][]byte) error {
+	_, _, err := s.appendBulk(bbs, nil, true)
+	return err
+}
+
+// appendBulk adds items to the segment with a single write; so that either all of them are appended, or none is.
+// It returns the position, within the segment, & the sequence number of the first item. The sequence number is only
+// right if the number of records of the segment is known. see recordCount
+// sums, if not nil, are the checksums of the items, that their producer computed; they are framed with those rather than
+// with checksums computed here. see AppendChecksummed
+// If sync is false, the items are left for the operating system to flush.
+func (s *segment) appendBulk(bbs [][]byte, sums []uint32, sync bool) (pos uint64, seq uint64, err error) {
+	size := 0
+	for _, b := range bbs {
+		if uint64(len(b)) > maxRecordSize {
+			return 0, 0, errRecordTooLarge
+		}
+		size = size + recordHeaderSize + len(b)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// TODO: write in encoded form
-	// https://github.com/komuw/shifta/issues/1
-	n, err := s.f.Write(b)
+	pos = s.currentSegBytes
+	seq = s.firstSeq + s.records
+	if len(bbs) == 0 {
+		return pos, seq, nil
+	}
+
+	// the records are framed, so that they can be split apart from the records around them when read. see Records
+	// They are joined into one buffer; a single write, rather than one per item, is cheaper & can not be torn in
+	// between items.
+	r := make([]byte, 0, size)
+	for i, b := range bbs {
+		if sums != nil {
+			r = appendChecksummedRecord(r, b, sums[i])
+		} else {
+			r = appendRecord(r, b)
+		}
+	}
+	n, err := s.f.Write(r)
 	if err != nil {
-		return errSegmentWrite(err)
+		if n > 0 {
+			// partial write. Do not leave behind data that the segment has not accounted for.
+			errA := s.f.Truncate(int64(s.currentSegBytes))
+			if errA != nil {
+				return 0, 0, errPartialWriteTruncate(errA)
+			}
+			s.journal.record(OpsTruncate, "segment %d truncated to %d bytes after a partial write of %d bytes", s.baseOffset, s.currentSegBytes, n)
+		}
+		return 0, 0, errSegmentWrite(err)
 	}
 
-	if n != len(b) {
+	if n != len(r) {
 		// partial write.
 		errA := s.f.Truncate(int64(s.currentSegBytes))
 		if errA != nil {
-			return errPartialWriteTruncate(errA)
+			return 0, 0, errPartialWriteTruncate(errA)
+		}
+		s.journal.record(OpsTruncate, "segment %d truncated to %d bytes after a partial write of %d bytes", s.baseOffset, s.currentSegBytes, n)
+		return 0, 0, errSegmentWrite(io.ErrShortWrite)
+	}
+
+	s.currentSegBytes = s.currentSegBytes + uint64(n)
+	s.records = s.records + uint64(len(bbs))
+	if s.sumKnown {
+		s.sum = crc32.Update(s.sum, crcTable, r)
+	}
+	s.age = s.clock.age(s.baseOffset)
+	if s.cache != nil {
+		if s.currentSegBytes <= s.cacheMax {
+			s.cache = append(s.cache, r...)
+		} else {
+			s.cache = nil
 		}
-	} else {
-		s.currentSegBytes = s.currentSegBytes + uint64(n)
-		s.age = tNow() - s.baseOffset
 	}
 
-	errB := s.f.Sync()
+	if !sync {
+		return pos, seq, nil
+	}
+	errB := s.sync()
 	if errB != nil {
-		return errSegmentSync(errB)
+		return 0, 0, errSegmentSync(errB)
 	}
 
-	return nil
+	return pos, seq, nil
 }
 
-// AppendBulk adds multiple items to the segment.
-// To append one item at a time use Append
-func (s *segment) AppendBulk(bbs [][]byte) error {
+// isDeleted reports whether the segment has been deleted.
+// A segment whose removal from the filesystem is pending, because it still has readers, counts as deleted.
+func (s *segment) isDeleted() bool {
+	s.mu.RLock()
+	r := s.f == nil || s.deletePending
+	s.mu.RUnlock()
+	return r
+}
+
+// acquire takes a reference to the segment, which keeps it in the filesystem until it is released.
+// It reports false, and takes no reference, if the segment has already been deleted.
+func (s *segment) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil || s.deletePending {
+		return false
+	}
+	s.refs++
+	return true
+}
+
+// release drops a reference taken by acquire.
+// If the segment was deleted while it was referenced, the last release removes it from the filesystem.
+func (s *segment) release() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refs--
+	if s.refs > 0 || !s.deletePending {
+		return nil
+	}
+	s.deletePending = false
+	return s.remove()
+}
+
+// pendingDeletion returns the size of the segment, & the number of readers using it, if it has been deleted but its
+// removal is pending on those readers. see Delete
+func (s *segment) pendingDeletion() (size uint64, readers int, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.deletePending {
+		return 0, 0, false
+	}
+	return s.currentSegBytes, s.refs, true
+}
+
+// forceRemove removes the segment from the filesystem if its removal is pending on readers; which then fail to read it.
+// It reports whether the segment was removed.
+func (s *segment) forceRemove() (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	return errors.New("TODO: implement appendBulk")
+	if !s.deletePending {
+		return false, nil
+	}
+	err := s.remove()
+	if err != nil {
+		return false, err
+	}
+	s.deletePending = false
+	return true, nil
 }
 
 // Delete removes a segment from the filesystem.
+// If the segment has readers(see acquire), its removal is deferred until the last of them releases it.
 func (s *segment) Delete() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -138,33 +308,64 @@ func (s *segment) Delete() error {
 	if s.f == nil {
 		return nil
 	}
+	if s.refs > 0 {
+		s.deletePending = true
+		return nil
+	}
+	return s.remove()
+}
 
+// remove closes the segment and removes its file.
+// The caller should hold s.mu
+func (s *segment) remove() error {
 	err := s.close()
 	if err != nil {
 		return err
 	}
-	errA := os.Remove(s.filePath)
+	errA := s.fsys.Remove(s.filePath)
 	if errA != nil {
 		return errSegmentRemove(errA)
 	}
 
 	// do we need to do this?
 	s.f = nil
-	s = nil
+	s.cache = nil
 
 	return nil
 }
 
+// sync flushes the segment's file to stable storage.
+func (s *segment) sync() error {
+	return s.fsyncMon.sync(s.filePath, s.f.Sync)
+}
+
+// flush syncs the segment's file to stable storage; unless the segment is closed, in which case it already has been.
+func (s *segment) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil || s.closed {
+		return nil
+	}
+	err := s.sync()
+	if err != nil {
+		return errSegmentSync(err)
+	}
+	return nil
+}
+
 func (s *segment) close() error {
 	if s.closed {
 		return nil
 	}
 
-	// Note: sync of file does not also sync its directory.
-	//  TODO: sync the directory also
-	err := s.f.Sync()
-	if err != nil {
-		return errSegmentSync(err)
+	if !s.readOnly {
+		// Note: sync of file does not also sync its directory.
+		//  TODO: sync the directory also
+		err := s.sync()
+		if err != nil {
+			return errSegmentSync(err)
+		}
 	}
 
 	errA := s.f.Close()
@@ -176,16 +377,162 @@ func (s *segment) close() error {
 	return nil
 }
 
-// Read reads all data from the segment.
+// startCache makes the segment, which should be empty, cache its data for as long as it is no larger than max bytes.
+func (s *segment) startCache(max uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if max == 0 || s.currentSegBytes != 0 {
+		return
+	}
+	s.cache = []byte{}
+	s.cacheMax = max
+}
+
+// dropCache releases the cached data, if any, of the segment.
+func (s *segment) dropCache() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
+
+// Read reads all data from the segment; its records, framed. see Records
+// A segment whose data is not a sequence of whole records that match their checksums, eg because of a torn write or
+// bit rot, is corrupt.
 func (s *segment) Read() ([]byte, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	if s.cache != nil && uint64(len(s.cache)) == s.currentSegBytes {
+		// the caller owns the data it is returned.
+		return append([]byte{}, s.cache...), nil
+	}
+
 	// TODO: we should not read the whole file to memory.
-	b, err := os.ReadFile(s.f.Name())
+	b, err := s.fsys.ReadFile(s.filePath)
 	if err != nil {
 		return nil, errSegmentRead(err)
 	}
+	b = s.followerView(b)
+	if errS := s.checkSeal(b); errS != nil {
+		return nil, errS
+	}
+	if uint64(len(b)) != s.currentSegBytes {
+		return nil, newSizeCorruption(s.filePath, s.currentSegBytes, uint64(len(b)))
+	}
+	errA := checkRecords(s.filePath, b)
+	if errA != nil {
+		return nil, errA
+	}
 
 	return b, nil
 }
+
+// sealData records the size & checksum of the data of the segment, which is being sealed; so that reads can detect
+// the segment file being modified afterwards. see ErrExternallyModified
+// It is best effort; if the data of a segment that was opened with data can not be read, the segment is left unsealed.
+func (s *segment) sealData() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seal != nil {
+		return
+	}
+	if !s.sumKnown {
+		b, err := s.fsys.ReadFile(s.filePath)
+		if err != nil || uint64(len(b)) != s.currentSegBytes {
+			return
+		}
+		s.sum = crc32.Checksum(b, crcTable)
+		s.sumKnown = true
+	}
+	s.seal = &segmentSeal{Size: s.currentSegBytes, Checksum: s.sum}
+}
+
+// sealCopy returns a copy of the seal of the segment; nil if it has none.
+func (s *segment) sealCopy() *segmentSeal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.seal == nil {
+		return nil
+	}
+	c := *s.seal
+	return &c
+}
+
+// checkSeal returns a *CorruptionError, that matches ErrExternallyModified, if b, the data of the segment as found in
+// the filesystem, does not match the seal of the segment. The caller should hold s.mu
+func (s *segment) checkSeal(b []byte) error {
+	if s.seal == nil {
+		return nil
+	}
+	if uint64(len(b)) != s.seal.Size || crc32.Checksum(b, crcTable) != s.seal.Checksum {
+		return newSealCorruption(s.filePath, *s.seal, b)
+	}
+	return nil
+}
+
+// recordCount returns the number of records in the segment; counting them, once, if they are not known.
+func (s *segment) recordCount() (uint64, error) {
+	s.mu.RLock()
+	records, known := s.records, s.recordsKnown
+	s.mu.RUnlock()
+	if known {
+		return records, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recordsKnown {
+		return s.records, nil
+	}
+	b, err := s.fsys.ReadFile(s.filePath)
+	if err != nil {
+		return 0, errSegmentRead(err)
+	}
+	b = s.followerView(b)
+	if errS := s.checkSeal(b); errS != nil {
+		return 0, errS
+	}
+	if uint64(len(b)) != s.currentSegBytes {
+		return 0, newSizeCorruption(s.filePath, s.currentSegBytes, uint64(len(b)))
+	}
+	n, errA := countRecords(s.filePath, b)
+	if errA != nil {
+		return 0, errA
+	}
+	// appends, which count the records they add, are held off by s.mu; so the count stays right from now on.
+	s.records = n
+	s.recordsKnown = true
+	return n, nil
+}
+
+// verify checks that the data of the segment, as found in the filesystem, is what the segment expects.
+// It returns the number of bytes read while verifying.
+func (s *segment) verify() (uint64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.f == nil {
+		// the segment has been deleted.
+		return 0, nil
+	}
+
+	// reading the whole file makes sure that any IO errors are surfaced.
+	b, err := s.fsys.ReadFile(s.filePath)
+	if err != nil {
+		return 0, errSegmentRead(err)
+	}
+	b = s.followerView(b)
+	if errS := s.checkSeal(b); errS != nil {
+		return uint64(len(b)), errS
+	}
+	if uint64(len(b)) != s.currentSegBytes {
+		return uint64(len(b)), newSizeCorruption(s.filePath, s.currentSegBytes, uint64(len(b)))
+	}
+	errA := checkRecords(s.filePath, b)
+	if errA != nil {
+		return uint64(len(b)), errA
+	}
+
+	return uint64(len(b)), nil
+}
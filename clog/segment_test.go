@@ -3,16 +3,19 @@ package clog
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
 
-// a mock readWriteCloserSyncerTruncater that fails in various configurable ways
+// a mock File that fails in various configurable ways
 type mockFileFail struct {
 	fName       string
 	errWrite    error
@@ -32,6 +35,23 @@ func (m mockFileFail) Close() error              { return nil }
 func (m mockFileFail) Sync() error               { return nil }
 func (m mockFileFail) Truncate(size int64) error { return m.errTruncate }
 
+// countingFile counts the writes & syncs made to a File.
+type countingFile struct {
+	File
+	writes int
+	syncs  int
+}
+
+func (c *countingFile) Write(p []byte) (int, error) {
+	c.writes++
+	return c.File.Write(p)
+}
+
+func (c *countingFile) Sync() error {
+	c.syncs++
+	return c.File.Sync()
+}
+
 func TestNewSegment(t *testing.T) {
 	// https://github.com/golang/go/wiki/TableDrivenTests#parallel-testing
 	t.Parallel()
@@ -46,7 +66,7 @@ func TestNewSegment(t *testing.T) {
 		defer os.RemoveAll(path)
 
 		baseOffset := tNow()
-		s, err := newSegment(path, baseOffset, 100)
+		s, err := newSegment(OSFileSystem{}, filepath.Join(path, FlatLayout{}.SegmentPath(baseOffset)), baseOffset, 100)
 		if err != nil {
 			t.Fatal("\n\t", err)
 		}
@@ -77,7 +97,7 @@ func TestNewSegment(t *testing.T) {
 		defer os.RemoveAll(path)
 
 		bo := uint64(0)
-		s, errSeg := newSegment(path, bo, 100)
+		s, errSeg := newSegment(OSFileSystem{}, filepath.Join(path, FlatLayout{}.SegmentPath(bo)), bo, 100)
 		if errSeg != nil {
 			t.Fatal("\n\t", errSeg)
 		}
@@ -105,7 +125,7 @@ func TestNewSegment(t *testing.T) {
 		defer os.RemoveAll(path)
 
 		baseOffset := tNow() * 7
-		s, err := newSegment(path, baseOffset, 100)
+		s, err := newSegment(OSFileSystem{}, filepath.Join(path, FlatLayout{}.SegmentPath(baseOffset)), baseOffset, 100)
 		if err != nil {
 			t.Fatal("\n\t", err)
 		}
@@ -137,7 +157,7 @@ func TestIsFull(t *testing.T) {
 	defer os.RemoveAll(path)
 
 	baseOffset := tNow()
-	s, err := newSegment(path, baseOffset, 100)
+	s, err := newSegment(OSFileSystem{}, filepath.Join(path, FlatLayout{}.SegmentPath(baseOffset)), baseOffset, 100)
 	if err != nil {
 		t.Fatal("\n\t", err)
 	}
@@ -159,8 +179,8 @@ func TestIsFull(t *testing.T) {
 	if s.IsFull() != true {
 		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.IsFull(), true)
 	}
-	if s.currentSegBytes != count {
-		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.currentSegBytes, count)
+	if s.currentSegBytes != count+recordHeaderSize {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.currentSegBytes, count+recordHeaderSize)
 	}
 }
 
@@ -171,7 +191,7 @@ func createSegmentForTests(t *testing.T) (*segment, func()) {
 	}
 
 	baseOffset := tNow()
-	s, errA := newSegment(path, baseOffset, 100)
+	s, errA := newSegment(OSFileSystem{}, filepath.Join(path, FlatLayout{}.SegmentPath(baseOffset)), baseOffset, 100)
 	if errA != nil {
 		t.Fatal("\n\t", errA)
 	}
@@ -209,12 +229,12 @@ func TestSegmentAppend(t *testing.T) {
 		if errC != nil {
 			t.Fatal("\n\t", errC)
 		}
-		if len(rMsg) != len(msg) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(rMsg), len(msg))
+		if len(rMsg) != len(msg)+recordHeaderSize {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(rMsg), len(msg)+recordHeaderSize)
 		}
 
-		if !cmp.Equal(rMsg, msg) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(rMsg), string(msg))
+		if !cmp.Equal(rMsg, encodeRecord(msg)) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(rMsg), string(encodeRecord(msg)))
 		}
 	})
 
@@ -249,14 +269,14 @@ func TestSegmentAppend(t *testing.T) {
 		if errC != nil {
 			t.Fatal("\n\t", errC)
 		}
-		if len(rMsg) != (len(msg1) + len(msg2) + len(msg3)) {
-			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(rMsg), (len(msg1) + len(msg2) + len(msg3)))
+		if len(rMsg) != (len(msg1) + len(msg2) + len(msg3) + 3*recordHeaderSize) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(rMsg), (len(msg1) + len(msg2) + len(msg3) + 3*recordHeaderSize))
 		}
 
 		hold := [][]byte{}
-		hold = append(hold, msg1)
-		hold = append(hold, msg2)
-		hold = append(hold, msg3)
+		hold = append(hold, encodeRecord(msg1))
+		hold = append(hold, encodeRecord(msg2))
+		hold = append(hold, encodeRecord(msg3))
 		res := bytes.Join(hold, []byte(""))
 		if !cmp.Equal(rMsg, res) {
 			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(rMsg), string(res))
@@ -300,6 +320,77 @@ func TestSegmentAppend(t *testing.T) {
 	})
 }
 
+func TestSegmentAppendBulk(t *testing.T) {
+	t.Parallel()
+
+	t.Run("one write & one sync", func(t *testing.T) {
+		t.Parallel()
+
+		s, removePath := createSegmentForTests(t)
+		defer removePath()
+		c := &countingFile{File: s.f}
+		s.f = c
+
+		bbs := [][]byte{[]byte("hello"), []byte("world"), []byte("123456")}
+		err := s.AppendBulk(bbs)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if c.writes != 1 || c.syncs != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []int{c.writes, c.syncs}, []int{1, 1})
+		}
+
+		rMsg, errB := os.ReadFile(s.filePath)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		want := []byte{}
+		for _, b := range bbs {
+			want = append(want, encodeRecord(b)...)
+		}
+		if !cmp.Equal(rMsg, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(rMsg), string(want))
+		}
+		if s.currentSegBytes != uint64(len(want)) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.currentSegBytes, len(want))
+		}
+	})
+
+	t.Run("no items", func(t *testing.T) {
+		t.Parallel()
+
+		s, removePath := createSegmentForTests(t)
+		defer removePath()
+		c := &countingFile{File: s.f}
+		s.f = c
+
+		err := s.AppendBulk(nil)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if c.writes != 0 || s.currentSegBytes != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", []uint64{uint64(c.writes), s.currentSegBytes}, []uint64{0, 0})
+		}
+	})
+
+	t.Run("s.f.Write() failing", func(t *testing.T) {
+		t.Parallel()
+
+		s, removePath := createSegmentForTests(t)
+		defer removePath()
+		setErr := errors.New("writing to `mockFileFailWrites` failed")
+		s.f = mockFileFail{errWrite: setErr, fName: s.f.Name()}
+
+		err := s.AppendBulk([][]byte{[]byte("hello"), []byte("world")})
+		if !errors.Is(err, setErr) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, setErr)
+		}
+		if s.currentSegBytes != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.currentSegBytes, 0)
+		}
+	})
+}
+
 func TestClose(t *testing.T) {
 	t.Parallel()
 
@@ -356,4 +447,108 @@ func TestDelete(t *testing.T) {
 			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", f, nil)
 		}
 	})
+
+	t.Run("delete is deferred while the segment is acquired", func(t *testing.T) {
+		t.Parallel()
+
+		s, removePath := createSegmentForTests(t)
+		defer removePath()
+
+		if !s.acquire() {
+			t.Fatal("could not acquire segment")
+		}
+		err := s.Delete()
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if !s.isDeleted() {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.isDeleted(), true)
+		}
+		_, errA := os.Stat(s.filePath)
+		if errA != nil {
+			t.Fatal("segment was removed while acquired:", errA)
+		}
+
+		errB := s.release()
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		_, errC := os.Stat(s.filePath)
+		if !errors.Is(errC, os.ErrNotExist) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, os.ErrNotExist)
+		}
+	})
+}
+
+func TestTailCache(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	fsys := &readRecordingFS{}
+	l, err := New(path, 100, 10_000, time.Hour, WithFileSystem(fsys), WithTailCache(100))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	fsys.reads = nil
+
+	want := ""
+	// each append, of a 100 byte record, fills a segment; so we end up with 3 segments.
+	for i := 0; i < 3; i++ {
+		msg := strings.Repeat(fmt.Sprint(i), 100-recordHeaderSize)
+		want = want + msg
+		errA := l.Append([]byte(msg))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	got, _, errB := l.Read(0, 0)
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	if recordsForTests(t, got) != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, got), want)
+	}
+
+	// only the oldest segment, that is no longer part of the hot tail, is read from the filesystem.
+	segs := l.segmentRead()
+	if !cmp.Equal(fsys.reads, []string{segs[0].filePath}) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.reads, []string{segs[0].filePath})
+	}
+
+	// the data returned is the caller's.
+	got[len(got)-1] = 'x'
+	got2, _, errC := l.Read(segs[1].baseOffset, 0)
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	if recordsForTests(t, got2) != strings.Repeat("2", 100-recordHeaderSize) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, got2), strings.Repeat("2", 100-recordHeaderSize))
+	}
+
+	// a segment that grows larger than the cache is read from the filesystem.
+	path2, removePath2 := createPathForTests(t)
+	defer removePath2()
+	fsys2 := &readRecordingFS{}
+	l2, errD := New(path2, 1_000, 10_000, time.Hour, WithFileSystem(fsys2), WithTailCache(100))
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	fsys2.reads = nil
+	for i := 0; i < 2; i++ {
+		errE := l2.Append([]byte(strings.Repeat("a", 60)))
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+	}
+	got3, _, errF := l2.Read(0, 0)
+	if errF != nil {
+		t.Fatal("\n\t", errF)
+	}
+	if recordsForTests(t, got3) != strings.Repeat("a", 120) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, got3), strings.Repeat("a", 120))
+	}
+	if len(fsys2.reads) != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys2.reads, "one read from the filesystem")
+	}
 }
@@ -0,0 +1,59 @@
+package clog
+
+import "time"
+
+// SegmentInfo is a read-only description of a segment of a commitlog. see Clog.Segments & NewSegmentHandler
+type SegmentInfo struct {
+	BaseOffset uint64 `json:"baseOffset"`
+	Size       uint64 `json:"size"`
+	// Records is the number of records in the segment.
+	Records uint64 `json:"records"`
+	// FirstSequence is the sequence number of the first record of the segment. see RecordOffset
+	FirstSequence uint64 `json:"firstSequence"`
+	// Created is when the segment was created; which is what its baseOffset is derived from.
+	Created time.Time `json:"created"`
+	// Sealed is whether the segment is no longer appended to. Every segment but the latest, active, one is sealed.
+	Sealed bool `json:"sealed"`
+	// Path is the path, in the filesystem, of the segment. It is not served by a SegmentHandler.
+	Path string `json:"-"`
+	// ETag is the HTTP entity tag of the segment; it changes whenever the segment's content does.
+	ETag string `json:"etag"`
+}
+
+// Segments returns a description of each segment of the commitlog, oldest first.
+// It lets tooling, retention policies & tests inspect how the commitlog is laid out.
+//
+// The records of a segment that already held data when the commitlog was opened are counted the first time they are
+// asked for, by reading the segment; so the first call may be slow. If the segment is corrupt, a *CorruptionError
+// is returned.
+// Segments deleted, eg by Clean, while Segments runs are left out.
+func (l *Clog) Segments() ([]SegmentInfo, error) {
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, errC
+	}
+	segs := acquireAfter(l.segmentRead(), 0)
+	l.mu.RUnlock()
+	defer releaseSegments(segs)
+
+	infos := make([]SegmentInfo, 0, len(segs))
+	for i, seg := range segs {
+		records, err := seg.recordCount()
+		if err != nil {
+			return nil, err
+		}
+		size := seg.size()
+		infos = append(infos, SegmentInfo{
+			BaseOffset:    seg.baseOffset,
+			Size:          size,
+			Records:       records,
+			FirstSequence: seg.firstSeq,
+			Created:       time.Unix(0, int64(seg.baseOffset)).UTC(),
+			Sealed:        i < len(segs)-1,
+			Path:          seg.filePath,
+			ETag:          segmentETag(seg.baseOffset, size),
+		})
+	}
+	return infos, nil
+}
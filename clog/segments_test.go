@@ -0,0 +1,66 @@
+package clog
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSegments(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 100, 10_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	// each append, of a 100 byte record, fills a segment.
+	msg := []byte(strings.Repeat("a", 100-recordHeaderSize))
+	for i := 0; i < 2; i++ {
+		errA := l.Append(msg)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	errB := l.AppendBulk([][]byte{[]byte("hello"), []byte("world")})
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+
+	check := func(t *testing.T, l *Clog) {
+		t.Helper()
+
+		infos, errC := l.Segments()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if len(infos) != 3 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", infos, "3 segments")
+		}
+		wantRecords := []uint64{1, 1, 2}
+		wantSizes := []uint64{100, 100, 2 * (recordHeaderSize + 5)}
+		for i, info := range infos {
+			if info.Records != wantRecords[i] || info.Size != wantSizes[i] {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", info, []uint64{wantRecords[i], wantSizes[i]})
+			}
+			if info.Sealed != (i < 2) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", info.Sealed, i < 2)
+			}
+			if uint64(info.Created.UnixNano()) != info.BaseOffset {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", info.Created, info.BaseOffset)
+			}
+			if !strings.HasPrefix(info.Path, path) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", info.Path, path)
+			}
+		}
+	}
+	check(t, l)
+
+	// the records of the segments that a reopened commitlog finds are counted.
+	l2, errD := New(path, 100, 10_000, time.Hour)
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+	check(t, l2)
+}
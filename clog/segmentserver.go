@@ -0,0 +1,134 @@
+package clog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// segmentsURLPath is the URL path, of a SegmentHandler, under which sealed segments are served.
+const segmentsURLPath = "/segments/"
+
+// NewSegmentHandler returns a read-only http.Handler that serves the sealed segments of the commitlog l as raw files.
+// It allows replicas and backup jobs to pull segments using standard HTTP tooling.
+//
+// It serves;
+//
+//	GET /segments/              a JSON list of the sealed segments. see SegmentInfo
+//	GET /segments/<baseOffset>  the content of the sealed segment with baseOffset.
+//
+// Segment responses carry an ETag & support range requests(including If-Range),
+// so that interrupted transfers can be resumed. The active segment is never served since it is still being written to.
+//
+// usage:
+//
+//	http.Handle("/segments/", NewSegmentHandler(l))
+func NewSegmentHandler(l *Clog) http.Handler {
+	return &segmentHandler{l: l}
+}
+
+type segmentHandler struct {
+	l *Clog
+}
+
+func (h *segmentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, segmentsURLPath) {
+		http.NotFound(w, r)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, segmentsURLPath)
+	if name == "" {
+		h.serveList(w)
+		return
+	}
+	baseOffset, err := strconv.ParseUint(name, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	h.serveSegment(w, r, baseOffset)
+}
+
+func (h *segmentHandler) serveList(w http.ResponseWriter) {
+	all, err := h.l.Segments()
+	if err != nil {
+		h.l.reportCorruption(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	infos := make([]SegmentInfo, 0, len(all))
+	for _, info := range all {
+		if info.Sealed {
+			infos = append(infos, info)
+		}
+	}
+
+	b, err := json.Marshal(infos)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+func (h *segmentHandler) serveSegment(w http.ResponseWriter, r *http.Request, baseOffset uint64) {
+	var seg *segment
+	for _, s := range h.sealed() {
+		if s.baseOffset == baseOffset {
+			seg = s
+			break
+		}
+	}
+	if seg == nil || !seg.acquire() {
+		// the segment does not exist, or was deleted, eg by Clean, after we looked it up.
+		http.NotFound(w, r)
+		return
+	}
+	b, err := seg.Read()
+	_ = seg.release()
+	if err != nil {
+		h.l.reportCorruption(err)
+		if errors.Is(err, fs.ErrNotExist) {
+			// the segment's file was removed from under the commitlog.
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// sealed segments are immutable; so their baseOffset & size identify their content.
+	w.Header().Set("ETag", segmentETag(seg.baseOffset, uint64(len(b))))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(b))
+}
+
+// sealed returns the segments of the commitlog that are no longer written to.
+func (h *segmentHandler) sealed() []*segment {
+	h.l.mu.RLock()
+	defer h.l.mu.RUnlock()
+
+	segs := h.l.segmentRead()
+	if len(segs) <= 1 {
+		return nil
+	}
+	// the latest segment is the active one.
+	return append([]*segment{}, segs[:len(segs)-1]...)
+}
+
+func segmentETag(baseOffset, size uint64) string {
+	return fmt.Sprintf(`"%d-%d"`, baseOffset, size)
+}
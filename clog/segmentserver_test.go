@@ -0,0 +1,130 @@
+package clog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSegmentHandler(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 100, 10_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	// each append, of a 100 byte record, fills a segment; so we end up with 2 sealed segments and an active one.
+	for i := 0; i < 3; i++ {
+		errA := l.Append([]byte(strings.Repeat(fmt.Sprint(i), 100-recordHeaderSize)))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	first := l.segments[0].baseOffset
+	active := l.segments[2].baseOffset
+
+	srv := httptest.NewServer(NewSegmentHandler(l))
+	defer srv.Close()
+
+	get := func(t *testing.T, path string, headers map[string]string) (*http.Response, string) {
+		req, errA := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		res, errB := srv.Client().Do(req)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		defer res.Body.Close()
+		b, errC := io.ReadAll(res.Body)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		return res, string(b)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		res, body := get(t, "/segments/", nil)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusOK)
+		}
+		infos := []SegmentInfo{}
+		errA := json.Unmarshal([]byte(body), &infos)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		second := l.segments[1].baseOffset
+		want := []SegmentInfo{
+			{BaseOffset: first, Size: 100, Records: 1, FirstSequence: 1, Created: time.Unix(0, int64(first)).UTC(), Sealed: true, ETag: segmentETag(first, 100)},
+			{BaseOffset: second, Size: 100, Records: 1, FirstSequence: 2, Created: time.Unix(0, int64(second)).UTC(), Sealed: true, ETag: segmentETag(second, 100)},
+		}
+		if !cmp.Equal(infos, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", infos, want)
+		}
+	})
+
+	t.Run("segment", func(t *testing.T) {
+		res, body := get(t, fmt.Sprintf("/segments/%d", first), nil)
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusOK)
+		}
+		if want := string(encodeRecord([]byte(strings.Repeat("0", 100-recordHeaderSize)))); body != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", body, want)
+		}
+		if res.Header.Get("ETag") != segmentETag(first, 100) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.Header.Get("ETag"), segmentETag(first, 100))
+		}
+	})
+
+	t.Run("resume with a range", func(t *testing.T) {
+		res, body := get(t, fmt.Sprintf("/segments/%d", first), map[string]string{
+			"Range":    "bytes=90-",
+			"If-Range": segmentETag(first, 100),
+		})
+		if res.StatusCode != http.StatusPartialContent {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusPartialContent)
+		}
+		if body != strings.Repeat("0", 10) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", body, strings.Repeat("0", 10))
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		res, _ := get(t, fmt.Sprintf("/segments/%d", first), map[string]string{"If-None-Match": segmentETag(first, 100)})
+		if res.StatusCode != http.StatusNotModified {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusNotModified)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		for _, p := range []string{fmt.Sprintf("/segments/%d", active), "/segments/7", "/segments/abc", "/other"} {
+			res, _ := get(t, p, nil)
+			if res.StatusCode != http.StatusNotFound {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusNotFound)
+			}
+		}
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		res, errA := srv.Client().Post(srv.URL+"/segments/", "text/plain", strings.NewReader("hi"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusMethodNotAllowed)
+		}
+	})
+}
@@ -0,0 +1,432 @@
+package clog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shipURLPath is the URL path, of a ShipHandler, under which shipped segments are received.
+const shipURLPath = "/ship/"
+
+var errBadShipper = errors.New("shipper cannot have a nil commitLog/destination/onErr or a negative or zero interval")
+
+// Destination is where a Shipper ships the sealed segments of a commitlog to; eg a commitlog directory in another
+// region, or an object store. see NewDirDestination & NewHTTPDestination
+type Destination interface {
+	// Ship stores the segment with baseOffset; r is an export stream, see Clog.Export, that holds just that segment.
+	// A segment may be shipped more than once, eg if the shipper restarts after shipping it; so Ship should succeed
+	// if the segment is already stored.
+	Ship(ctx context.Context, baseOffset uint64, r io.Reader) error
+	// Latest returns the baseOffset of the latest segment that is stored; 0 if none is. Shipping resumes after it.
+	Latest(ctx context.Context) (uint64, error)
+}
+
+// ShipLagError is passed to the onErr of a Shipper whenever the data that has not yet been shipped is older than
+// the maxLag of the shipper.
+type ShipLagError struct {
+	// Lag is how far behind the commitlog the destination is. Its Time is the current recovery point objective(RPO).
+	Lag Lag
+	// MaxLag is the maxLag of the shipper.
+	MaxLag time.Duration
+}
+
+func (e *ShipLagError) Error() string {
+	return fmt.Sprintf("shipping lags by %s(%d segments, %d bytes), more than the max of %s",
+		e.Lag.Time, e.Lag.Segments, e.Lag.Bytes, e.MaxLag)
+}
+
+// Shipper continuously ships the sealed segments of a commitlog to a Destination; so that a warm standby of the
+// commitlog is kept, eg in another region.
+//
+// To create a shipper, use the NewShipper method.
+type Shipper struct {
+	l        *Clog
+	dst      Destination
+	interval time.Duration
+	maxLag   time.Duration
+	onErr    func(error)
+
+	// shipMu makes sure that segments are shipped by one goroutine at a time.
+	shipMu sync.Mutex
+	// mu protects shipped & resumed.
+	mu sync.Mutex
+	// shipped is the baseOffset of the latest segment shipped.
+	shipped uint64
+	// resumed is whether shipped has been read from the destination.
+	resumed bool
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// NewShipper creates a shipper of the commitlog l to dst.
+//
+// Once started, the shipper ships the sealed segments that dst does not yet have every interval, oldest first;
+// picking up, after a restart, from the latest segment in dst. The active segment is never shipped since it is still
+// being written to; so the data that a failover to dst loses, its recovery point objective(RPO), is the data appended
+// since the oldest segment that has not been shipped was created. That is at most the time a segment takes to fill up,
+// plus interval & the time shipping takes. see Lag
+//
+// Whenever shipping fails, onErr is called with the error; the segment is shipped again at the next interval.
+// If maxLag > 0, onErr is also called with a *ShipLagError whenever, after shipping, the lag is larger than maxLag.
+// Segments that are deleted, eg by Clean, before they are shipped are reported to onErr as an *OutOfRangeError.
+//
+// usage:
+//
+//	s, errN := NewShipper(l, NewHTTPDestination("https://standby.example.com", nil), time.Second, time.Minute, onErr)
+//	s.Start()
+//	defer s.Stop()
+func NewShipper(l *Clog, dst Destination, interval time.Duration, maxLag time.Duration, onErr func(error)) (*Shipper, error) {
+	if l == nil || dst == nil || onErr == nil || interval <= 0 {
+		return nil, errBadShipper
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Shipper{
+		l:        l,
+		dst:      dst,
+		interval: interval,
+		maxLag:   maxLag,
+		onErr:    onErr,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start runs the shipper in a background goroutine.
+// Calling Start more than once has no effect.
+func (s *Shipper) Start() {
+	s.startOnce.Do(func() {
+		go s.run()
+	})
+}
+
+// Stop stops the shipper, cancelling any shipping in progress, and waits for its goroutine to exit.
+// It is safe to call Stop more than once, or without having called Start.
+func (s *Shipper) Stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+	})
+	s.startOnce.Do(func() {
+		// the shipper was never started; there's no goroutine to wait for.
+		close(s.done)
+	})
+	<-s.done
+}
+
+func (s *Shipper) run() {
+	defer close(s.done)
+
+	for {
+		timer := time.NewTimer(s.interval)
+		select {
+		case <-s.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := s.Ship(s.ctx)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				// stopped while shipping.
+				return
+			}
+			s.onErr(err)
+		}
+		if s.maxLag <= 0 {
+			continue
+		}
+		lag, errL := s.Lag()
+		if errL == nil && lag.Time > s.maxLag {
+			s.onErr(&ShipLagError{Lag: lag, MaxLag: s.maxLag})
+		}
+	}
+}
+
+// Ship ships, now, the sealed segments that have not been shipped yet; eg before a planned failover.
+// It stops at the first segment that fails to ship, which is shipped again by the next call.
+func (s *Shipper) Ship(ctx context.Context) error {
+	s.shipMu.Lock()
+	defer s.shipMu.Unlock()
+
+	shipped, err := s.resume(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.l.mu.RLock()
+	all := s.l.segmentRead()
+	pending := []uint64{}
+	for i, seg := range all {
+		// the latest segment is the active one.
+		if i < len(all)-1 && seg.baseOffset > shipped {
+			pending = append(pending, seg.baseOffset)
+		}
+	}
+	s.l.mu.RUnlock()
+
+	for _, baseOffset := range pending {
+		errS := s.shipSegment(ctx, baseOffset)
+		if errS != nil {
+			return errS
+		}
+		s.mu.Lock()
+		s.shipped = baseOffset
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// resume returns the baseOffset of the latest segment shipped; asking the destination for it the first time.
+func (s *Shipper) resume(ctx context.Context) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.resumed {
+		latest, err := s.dst.Latest(ctx)
+		if err != nil {
+			return 0, err
+		}
+		s.shipped = latest
+		s.resumed = true
+	}
+	return s.shipped, nil
+}
+
+// shipSegment exports the sealed segment with baseOffset, & streams the export to the destination.
+func (s *Shipper) shipSegment(ctx context.Context, baseOffset uint64) error {
+	pr, pw := io.Pipe()
+	exported := make(chan error, 1)
+	go func() {
+		_, errE := s.l.ExportRange(pw, baseOffset, baseOffset+1)
+		_ = pw.CloseWithError(errE)
+		exported <- errE
+	}()
+
+	err := s.dst.Ship(ctx, baseOffset, pr)
+	// unblock the export, if the destination returned without reading all of it.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+	errE := <-exported
+	if errE != nil && !errors.Is(errE, io.ErrClosedPipe) {
+		// eg the segment was deleted before it was shipped.
+		return errE
+	}
+	return err
+}
+
+// Lag returns how far behind the commitlog the destination is; the data that a failover to it would lose.
+// It counts the active segment, which is never shipped. Until the shipper has asked the destination for the latest
+// segment it has, the whole commitlog is taken to lag.
+func (s *Shipper) Lag() (Lag, error) {
+	s.mu.Lock()
+	shipped := s.shipped
+	s.mu.Unlock()
+
+	lag, err := s.l.Lag(shipped)
+	var oe *OutOfRangeError
+	if errors.As(err, &oe) {
+		// segments after shipped have been deleted; what is left is still to be shipped.
+		return s.l.Lag(oe.LowWatermark)
+	}
+	return lag, err
+}
+
+// DirDestination is a Destination that stores shipped segments in a commitlog directory; eg on a disk in another
+// region, or on a filesystem backed by an object store. On failover, the commitlog is opened from the directory with New.
+// The directory should not be open as a commitlog while segments are shipped to it.
+//
+// To create a DirDestination, use the NewDirDestination method.
+type DirDestination struct {
+	fsys FileSystem
+	dir  string
+}
+
+// NewDirDestination returns a destination that stores shipped segments in the directory dir of fsys.
+// Segments are laid out as FlatLayout lays them out.
+func NewDirDestination(fsys FileSystem, dir string) *DirDestination {
+	return &DirDestination{fsys: fsys, dir: dir}
+}
+
+// Ship restores the export stream r, which should hold just the segment with baseOffset, into the directory; along with
+// the sequence numbers of its records. see Restore
+// A stream that holds any other segment fails with an error matching ErrBadExport.
+func (d *DirDestination) Ship(ctx context.Context, baseOffset uint64, r io.Reader) error {
+	errM := d.fsys.MkdirAll(d.dir, ownerReadableWritable)
+	if errM != nil {
+		return errMkDir(errM)
+	}
+	err := restore(r, d.fsys, d.dir, FlatLayout{}, func(b uint64) error {
+		if b != baseOffset {
+			return errBadExport("holds segment %d, rather than segment %d", b, baseOffset)
+		}
+		return nil
+	})
+	if errors.Is(err, errRestoreExists) {
+		// shipped before.
+		return nil
+	}
+	return err
+}
+
+// Latest returns the baseOffset of the latest segment in the directory.
+func (d *DirDestination) Latest(ctx context.Context) (uint64, error) {
+	entries, err := d.fsys.ReadDir(d.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errReadDir(err)
+	}
+	var latest uint64
+	for _, e := range entries {
+		baseOffset, ok, errP := FlatLayout{}.ParseSegmentPath(e.Name())
+		if errP != nil || !ok {
+			continue
+		}
+		if baseOffset > latest {
+			latest = baseOffset
+		}
+	}
+	return latest, nil
+}
+
+// latestResponse is the JSON body with which a ShipHandler responds to a GET of shipURLPath.
+type latestResponse struct {
+	Latest uint64 `json:"latest"`
+}
+
+// NewShipHandler returns an http.Handler that receives the segments shipped by a Shipper, whose destination is
+// a NewHTTPDestination, & stores them in dst. It lets a shifta node in another region keep a warm standby.
+//
+// It serves;
+//
+//	GET /ship/              the latest segment stored, as JSON {"latest": <baseOffset>}
+//	PUT /ship/<baseOffset>  an export stream, see Clog.Export, that holds the segment with baseOffset.
+//
+// usage:
+//
+//	http.Handle("/ship/", NewShipHandler(NewDirDestination(OSFileSystem{}, "/standby/orders")))
+func NewShipHandler(dst Destination) http.Handler {
+	return &shipHandler{dst: dst}
+}
+
+type shipHandler struct {
+	dst Destination
+}
+
+func (h *shipHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, shipURLPath) {
+		http.NotFound(w, r)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, shipURLPath)
+
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		latest, err := h.dst.Latest(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(latestResponse{Latest: latest})
+	case name != "" && r.Method == http.MethodPut:
+		baseOffset, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		errS := h.dst.Ship(r.Context(), baseOffset, r.Body)
+		if errors.Is(errS, ErrBadExport) {
+			http.Error(w, errS.Error(), http.StatusBadRequest)
+			return
+		}
+		if errS != nil {
+			http.Error(w, errS.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// HTTPDestination is a Destination that ships segments to a shifta node that serves a ShipHandler.
+//
+// To create an HTTPDestination, use the NewHTTPDestination method.
+type HTTPDestination struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPDestination returns a destination that ships segments to the ShipHandler served at baseURL, using client.
+// If client is nil, http.DefaultClient is used.
+func NewHTTPDestination(baseURL string, client *http.Client) *HTTPDestination {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPDestination{url: strings.TrimSuffix(baseURL, "/") + shipURLPath, client: client}
+}
+
+// Ship PUTs the export stream r to the ShipHandler.
+func (d *HTTPDestination) Ship(ctx context.Context, baseOffset uint64, r io.Reader) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, d.url+strconv.FormatUint(baseOffset, 10), r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	_, errD := d.do(req)
+	return errD
+}
+
+// Latest GETs the latest segment stored from the ShipHandler.
+func (d *HTTPDestination) Latest(ctx context.Context) (uint64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.url, nil)
+	if err != nil {
+		return 0, err
+	}
+	b, errD := d.do(req)
+	if errD != nil {
+		return 0, errD
+	}
+	resp := latestResponse{}
+	errU := json.Unmarshal(b, &resp)
+	if errU != nil {
+		return 0, fmt.Errorf("bad response from %s: %w", d.url, errU)
+	}
+	return resp.Latest, nil
+}
+
+// do sends req & returns the body of the response; or an error if the response is not a success.
+func (d *HTTPDestination) do(req *http.Request) ([]byte, error) {
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL, resp.Status, bytes.TrimSpace(b))
+	}
+	return b, nil
+}
@@ -0,0 +1,270 @@
+package clog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// failingDestination is a destination that fails every ship.
+type failingDestination struct{}
+
+func (failingDestination) Ship(ctx context.Context, baseOffset uint64, r io.Reader) error {
+	return errors.New("region is unreachable")
+}
+
+func (failingDestination) Latest(ctx context.Context) (uint64, error) { return 0, nil }
+
+func TestShipper(t *testing.T) {
+	t.Parallel()
+
+	appendForTests := func(t *testing.T, l *Clog, records ...string) {
+		t.Helper()
+		for _, r := range records {
+			err := l.Append([]byte(r))
+			if err != nil {
+				t.Fatal("\n\t", err)
+			}
+		}
+	}
+	// readStandby opens the commitlog in dir & returns its records.
+	readStandby := func(t *testing.T, dir string) []string {
+		t.Helper()
+		standby, err := New(dir, 20, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		records, _, errR := standby.ReadRecords(0, 0)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		got := []string{}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+		return got
+	}
+
+	t.Run("ship to a directory", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		standbyPath, removeStandbyPath := createPathForTests(t)
+		defer removeStandbyPath()
+		// each append fills a segment.
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		appendForTests(t, l, "order # 1", "order # 2", "order # 3")
+
+		s, errN := NewShipper(l, NewDirDestination(OSFileSystem{}, standbyPath), time.Hour, 0, func(e error) { t.Error(e) })
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		errS := s.Ship(context.Background())
+		if errS != nil {
+			t.Fatal("\n\t", errS)
+		}
+		// the active segment is not shipped.
+		want := []string{"order # 1", "order # 2"}
+		if got := readStandby(t, standbyPath); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// a new shipper resumes after the segments that were shipped.
+		appendForTests(t, l, "order # 4")
+		s2, errN2 := NewShipper(l, NewDirDestination(OSFileSystem{}, standbyPath), time.Hour, 0, func(e error) { t.Error(e) })
+		if errN2 != nil {
+			t.Fatal("\n\t", errN2)
+		}
+		errS2 := s2.Ship(context.Background())
+		if errS2 != nil {
+			t.Fatal("\n\t", errS2)
+		}
+		want = append(want, "order # 3")
+		if got := readStandby(t, standbyPath); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// only the active segment lags.
+		lag, errL := s2.Lag()
+		if errL != nil {
+			t.Fatal("\n\t", errL)
+		}
+		if lag.Segments > 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lag, "only the active segment to lag")
+		}
+	})
+
+	t.Run("ship over http", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		standbyPath, removeStandbyPath := createPathForTests(t)
+		defer removeStandbyPath()
+		ts := httptest.NewServer(NewShipHandler(NewDirDestination(OSFileSystem{}, standbyPath)))
+		defer ts.Close()
+
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		appendForTests(t, l, "order # 1", "order # 2", "order # 3")
+
+		s, errN := NewShipper(l, NewHTTPDestination(ts.URL, nil), 5*time.Millisecond, 0, func(e error) { t.Error(e) })
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		s.Start()
+		defer s.Stop()
+
+		want := []string{"order # 1", "order # 2"}
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			latest, errL := NewHTTPDestination(ts.URL, nil).Latest(context.Background())
+			if errL != nil {
+				t.Fatal("\n\t", errL)
+			}
+			if latest == l.segmentRead()[1].baseOffset {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		s.Stop()
+		// wait for a ship that the standby may still be restoring.
+		ts.Close()
+		if got := readStandby(t, standbyPath); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("put of another segment is rejected", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		standbyPath, removeStandbyPath := createPathForTests(t)
+		defer removeStandbyPath()
+		ts := httptest.NewServer(NewShipHandler(NewDirDestination(OSFileSystem{}, standbyPath)))
+		defer ts.Close()
+
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		appendForTests(t, l, "order # 1", "order # 2", "order # 3")
+		segs := l.segmentRead()
+
+		export := &bytes.Buffer{}
+		_, errE := l.ExportRange(export, segs[0].baseOffset, segs[0].baseOffset+1)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		req, errR := http.NewRequest(http.MethodPut, fmt.Sprintf("%s%s%d", ts.URL, shipURLPath, segs[1].baseOffset), export)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+		res, errD := http.DefaultClient.Do(req)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.StatusCode, http.StatusBadRequest)
+		}
+		if got := readStandby(t, standbyPath); len(got) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "nothing to be restored")
+		}
+	})
+
+	t.Run("sequence numbers are kept", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		standbyPath, removeStandbyPath := createPathForTests(t)
+		defer removeStandbyPath()
+
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		appendForTests(t, l, "order # 1", "order # 2", "order # 3")
+		_, offsets, errR := l.ReadRecords(0, 0)
+		if errR != nil {
+			t.Fatal("\n\t", errR)
+		}
+
+		// only the second segment is shipped, as if the first had already been cleaned up.
+		s, errN := NewShipper(l, NewDirDestination(OSFileSystem{}, standbyPath), time.Hour, 0, func(e error) { t.Error(e) })
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		errS := s.shipSegment(context.Background(), l.segmentRead()[1].baseOffset)
+		if errS != nil {
+			t.Fatal("\n\t", errS)
+		}
+
+		standby, errO := New(standbyPath, 20, 100_000, time.Hour)
+		if errO != nil {
+			t.Fatal("\n\t", errO)
+		}
+		_, got, errG := standby.ReadRecords(0, 0)
+		if errG != nil {
+			t.Fatal("\n\t", errG)
+		}
+		if len(got) == 0 || got[0].Sequence != offsets[1].Sequence {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, offsets[1])
+		}
+	})
+
+	t.Run("lag is reported", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		appendForTests(t, l, "order # 1", "order # 2")
+
+		mu := sync.Mutex{}
+		lagErrs := []*ShipLagError{}
+		s, errN := NewShipper(l, failingDestination{}, time.Millisecond, time.Nanosecond, func(e error) {
+			var le *ShipLagError
+			if errors.As(e, &le) {
+				mu.Lock()
+				lagErrs = append(lagErrs, le)
+				mu.Unlock()
+			}
+		})
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		s.Start()
+		time.Sleep(50 * time.Millisecond)
+		s.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(lagErrs) == 0 {
+			t.Fatal("expected the lag to be reported")
+		}
+		if lagErrs[0].Lag.Segments < 2 || lagErrs[0].MaxLag != time.Nanosecond {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", lagErrs[0], "the unshipped segments to lag")
+		}
+	})
+}
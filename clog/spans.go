@@ -0,0 +1,36 @@
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// ReadSpan describes the part of the data returned by Clog.ReadSpans that one segment contributed.
+type ReadSpan struct {
+	// BaseOffset is the baseOffset of the segment.
+	BaseOffset uint64
+	// FirstSequence is the sequence number of the first record of the segment; the records that follow it have the
+	// sequence numbers that follow it. see RecordOffset
+	FirstSequence uint64
+	// Start & End are the range, [Start, End), of the data read that holds the data of the segment.
+	// Whole segments are read, so End - Start is also the size of the segment.
+	Start uint64
+	End   uint64
+}
+
+// ReadSpans is Read; but alongside the data read, it returns the spans of that data that each segment contributed,
+// in order. It lets replication & backup tools reason about how the commitlog is laid out in the filesystem,
+// eg to mirror its segments one for one, without access to its segments.
+// The BaseOffset of the last span is the lastReadOffset that Read would have returned.
+//
+// usage:
+//
+//	data, spans, err := l.ReadSpans(offset, 0)
+//	for _, s := range spans {
+//		mirror(s.BaseOffset, data[s.Start:s.End])
+//	}
+func (l *Clog) ReadSpans(offset uint64, maxToRead uint64) (dataRead []byte, spans []ReadSpan, err error) {
+	defer func(start time.Time) { l.tracer.add("read-spans", start, len(dataRead), err) }(time.Now())
+
+	return l.read(context.Background(), offset, maxToRead)
+}
@@ -0,0 +1,78 @@
+package clog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadSpans(t *testing.T) {
+	t.Parallel()
+
+	t.Run("spans cover the data of each segment", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 5, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// each append fills a segment.
+		msgs := []string{"hello", "world", "there"}
+		for _, m := range msgs {
+			errA := l.Append([]byte(m))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+
+		data, spans, errB := l.ReadSpans(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(spans) != len(msgs) {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(spans), len(msgs))
+		}
+		segs := l.segmentRead()
+		for i, s := range spans {
+			if s.BaseOffset != segs[i].baseOffset {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", s.BaseOffset, segs[i].baseOffset)
+			}
+			if got := recordsForTests(t, data[s.Start:s.End]); got != msgs[i] {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, msgs[i])
+			}
+		}
+
+		_, last, errC := l.Read(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if last != spans[len(spans)-1].BaseOffset {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", last, spans[len(spans)-1].BaseOffset)
+		}
+	})
+
+	t.Run("no spans after the latest segment", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		a, _ := l.activeSegment()
+		data, spans, errB := l.ReadSpans(a.baseOffset, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if len(data) != 0 || len(spans) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", spans, nil)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package clog
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// FsyncStall describes a single fsync of a segment that took longer than the configured threshold.
+// see WithFsyncStallHandler
+type FsyncStall struct {
+	// Path is the path, in the filesystem, of the segment that was being synced.
+	Path string
+	// Duration is how long the fsync took.
+	Duration time.Duration
+	// At is when the fsync started.
+	At time.Time
+}
+
+// fsyncMonitor times the fsyncs of segments and reports the ones that take longer than threshold.
+// A nil *fsyncMonitor times nothing.
+type fsyncMonitor struct {
+	// stalls is accessed atomically. It is kept first in the struct so that it is 64-bit aligned on 32-bit platforms.
+	stalls    uint64
+	threshold time.Duration
+	onStall   func(FsyncStall)
+}
+
+// sync calls fn, which should fsync the file at path, and reports it if it stalls.
+func (m *fsyncMonitor) sync(path string, fn func() error) error {
+	if m == nil {
+		return fn()
+	}
+
+	start := time.Now()
+	err := fn()
+	d := time.Since(start)
+	if d > m.threshold {
+		atomic.AddUint64(&m.stalls, 1)
+		if m.onStall != nil {
+			m.onStall(FsyncStall{Path: path, Duration: d, At: start})
+		}
+	}
+	return err
+}
+
+func (m *fsyncMonitor) count() uint64 {
+	if m == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&m.stalls)
+}
+
+// FsyncStalls returns the number of fsyncs that have taken longer than the threshold configured by WithFsyncStallHandler,
+// since the commitlog was opened.
+// It is always 0 if WithFsyncStallHandler was not used.
+func (l *Clog) FsyncStalls() uint64 {
+	return l.fsyncMon.count()
+}
@@ -0,0 +1,100 @@
+package clog
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFsyncMonitor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stalls are reported", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		stalls := []FsyncStall{}
+		m := &fsyncMonitor{threshold: time.Millisecond, onStall: func(s FsyncStall) {
+			mu.Lock()
+			stalls = append(stalls, s)
+			mu.Unlock()
+		}}
+
+		errA := m.sync("/tmp/fast.log", func() error { return nil })
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		setErr := errors.New("sync failed")
+		errB := m.sync("/tmp/slow.log", func() error {
+			time.Sleep(5 * time.Millisecond)
+			return setErr
+		})
+		if !errors.Is(errB, setErr) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errB, setErr)
+		}
+
+		if m.count() != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.count(), 1)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if len(stalls) != 1 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(stalls), 1)
+		}
+		if stalls[0].Path != "/tmp/slow.log" {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stalls[0].Path, "/tmp/slow.log")
+		}
+		if stalls[0].Duration < 5*time.Millisecond {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stalls[0].Duration, ">=5ms")
+		}
+	})
+
+	t.Run("nil monitor", func(t *testing.T) {
+		t.Parallel()
+
+		var m *fsyncMonitor
+		errA := m.sync("/tmp/a.log", func() error { return nil })
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if m.count() != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.count(), 0)
+		}
+	})
+
+	t.Run("commitlog appends are tracked", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+
+		var mu sync.Mutex
+		paths := map[string]bool{}
+		// a threshold of 0 makes every fsync a stall.
+		l, err := New(path, 100, 10_000, time.Hour, WithFsyncStallHandler(0, func(s FsyncStall) {
+			mu.Lock()
+			paths[s.Path] = true
+			mu.Unlock()
+		}))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		for i := 0; i < 3; i++ {
+			errA := l.Append([]byte("hello"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		if l.FsyncStalls() < 3 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", l.FsyncStalls(), ">=3")
+		}
+		a, _ := l.activeSegment()
+		mu.Lock()
+		defer mu.Unlock()
+		if !paths[a.filePath] {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", paths, a.filePath)
+		}
+	})
+}
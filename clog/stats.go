@@ -0,0 +1,77 @@
+package clog
+
+import "time"
+
+// Stats is an overview of the state of a commitlog; for operators to alert on its retention & growth. see Clog.Stats
+type Stats struct {
+	// Segments is the number of segments of the commitlog.
+	Segments int
+	// Bytes is the size of the data of its segments. see SizeBytes
+	Bytes uint64
+	// OldestSegment & NewestSegment are when the oldest, & the newest(active), segments were created; the zero time if
+	// the commitlog has no segments.
+	OldestSegment time.Time
+	NewestSegment time.Time
+	// Appends is the number of records appended since the commitlog was opened.
+	Appends uint64
+	// LastClean is when Clean last ran, whether or not it deleted anything; the zero time if it has not run since the
+	// commitlog was opened.
+	LastClean time.Time
+	// PendingDeletion is what segments have been deleted but are kept on the filesystem for reads that use them.
+	PendingDeletion PendingDeletion
+	// Tags is, for each segment tag formatted as "key=value", the number of segments that have it. see TagSegment
+	Tags map[string]int
+	// LastOp is the latest event that the commitlog wrote to its ops journal since it was opened; the zero OpsEvent for a
+	// commitlog opened with OpenReadOnly. see OpsJournal
+	LastOp OpsEvent
+	// TraceErrors is the operations, out of the latest ones that WithTrace keeps, that failed; oldest first.
+	// It is always empty if WithTrace was not used. see Trace
+	TraceErrors []TraceEntry
+}
+
+// Stats returns an overview of the state of the commitlog.
+// It is served from what the commitlog keeps in memory, which appends, splits, cleans & tags keep up to date; it neither
+// reads nor stats any files, so it is cheap even for commitlogs with thousands of segments. It is exact as of the
+// time it is called.
+//
+// usage:
+//
+//	s, err := l.Stats()
+//	metrics.Gauge("clog.segments", s.Segments)
+func (l *Clog) Stats() (Stats, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return Stats{}, errC
+	}
+	segs := l.segmentRead()
+	s := Stats{
+		Segments:    len(segs),
+		Appends:     l.appends,
+		LastClean:   l.lastClean,
+		Tags:        map[string]int{},
+		LastOp:      l.journal.lastEvent(),
+		TraceErrors: l.tracer.errors(),
+	}
+	for _, seg := range segs {
+		s.Bytes = s.Bytes + seg.size()
+		for k, v := range seg.tagsCopy() {
+			s.Tags[k+"="+v]++
+		}
+	}
+	if len(segs) > 0 {
+		s.OldestSegment = time.Unix(0, int64(segs[0].baseOffset)).UTC()
+		s.NewestSegment = time.Unix(0, int64(segs[len(segs)-1].baseOffset)).UTC()
+	}
+	for _, seg := range l.pendingDeletes {
+		size, readers, ok := seg.pendingDeletion()
+		if !ok {
+			continue
+		}
+		s.PendingDeletion.Segments++
+		s.PendingDeletion.Bytes = s.PendingDeletion.Bytes + size
+		s.PendingDeletion.Readers = s.PendingDeletion.Readers + readers
+	}
+	return s, nil
+}
@@ -0,0 +1,84 @@
+package clog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	// each segment holds a single record.
+	l, err := New(path, 10, 100_000, time.Hour, WithClock(clk), WithTrace(10))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+
+	offsets := []RecordOffset{}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		o, errA := l.AppendOffset([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		offsets = append(offsets, o)
+	}
+	for _, o := range offsets[:2] {
+		errT := l.TagSegment(o.BaseOffset, map[string]string{"source": "node-7"})
+		if errT != nil {
+			t.Fatal("\n\t", errT)
+		}
+	}
+	_, _, errR := l.ReadRange(7, 7)
+	if errR == nil {
+		t.Fatal("\n\t", "wanted ReadRange of an unknown segment to fail")
+	}
+	errC := l.Clean()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	cleaned := now
+	journal, errJ := l.OpsJournal()
+	if errJ != nil {
+		t.Fatal("\n\t", errJ)
+	}
+
+	got, errS := l.Stats()
+	if errS != nil {
+		t.Fatal("\n\t", errS)
+	}
+	want := Stats{
+		Segments:      3,
+		Bytes:         3 * uint64(recordHeaderSize+len("order # 1")),
+		OldestSegment: time.Unix(0, int64(offsets[0].BaseOffset)).UTC(),
+		NewestSegment: time.Unix(0, int64(offsets[2].BaseOffset)).UTC(),
+		Appends:       3,
+		LastClean:     cleaned,
+		Tags:          map[string]int{"source=node-7": 2},
+		LastOp:        journal[len(journal)-1],
+	}
+	if len(got.TraceErrors) != 1 || got.TraceErrors[0].Op != "read-range" || got.TraceErrors[0].Err != errR {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got.TraceErrors, errR)
+	}
+	got.TraceErrors = nil
+	if !cmp.Equal(got, want) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	errL := l.Close()
+	if errL != nil {
+		t.Fatal("\n\t", errL)
+	}
+	if _, errS2 := l.Stats(); !errors.Is(errS2, ErrClosed) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errS2, ErrClosed)
+	}
+}
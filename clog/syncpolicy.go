@@ -0,0 +1,48 @@
+package clog
+
+import "time"
+
+// SyncPolicy decides when the appends to a commitlog are synced(fsync) to stable storage.
+// see WithSyncPolicy
+type SyncPolicy uint8
+
+const (
+	// SyncAlways syncs every append before it returns. It is the default.
+	// Appends made concurrently share their syncs; an append made while another one is being synced is synced along with
+	// the rest of the appends made meanwhile, by a single fsync.
+	SyncAlways SyncPolicy = iota
+	// SyncOnSeal leaves appends to be flushed by the operating system, and only syncs a segment once it is sealed.
+	// Appends are much faster, but the ones made to the active segment since it was created may be lost if the machine crashes.
+	// Use Clog.AppendDurable for the appends that should not be lost.
+	SyncOnSeal
+)
+
+// Sync syncs the data appended to the commitlog so far, & the directory of its active segment, to stable storage.
+// It lets applications that use SyncOnSeal force a flush at the boundaries that matter to them, eg at the end of a
+// transaction; rather than paying for a sync on every append. Segments other than the active one were synced when they
+// were sealed.
+//
+// Appends are not blocked while the sync is in progress.
+func (l *Clog) Sync() (err error) {
+	defer func(start time.Time) { l.tracer.add("sync", start, 0, err) }(time.Now())
+
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return errC
+	}
+	a, err := l.activeSegment()
+	l.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	// if a is sealed in the meantime, it is synced as it is closed; flush then has nothing to do.
+	errF := a.flush()
+	if errF != nil {
+		return errF
+	}
+	return l.syncDirs([]*segment{a})
+}
+
+var _ syncer = (*Clog)(nil)
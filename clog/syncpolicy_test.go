@@ -0,0 +1,116 @@
+package clog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyncPolicy(t *testing.T) {
+	t.Parallel()
+
+	// a stall threshold of 0 counts every fsync.
+	countSyncs := WithFsyncStallHandler(0, nil)
+
+	tt := []struct {
+		name      string
+		opts      []Option
+		durable   bool
+		wantSyncs uint64
+	}{
+		{name: "default syncs every append", opts: []Option{countSyncs}, wantSyncs: 3},
+		{name: "sync on seal", opts: []Option{countSyncs, WithSyncPolicy(SyncOnSeal)}, wantSyncs: 0},
+		{name: "durable appends always sync", opts: []Option{countSyncs, WithSyncPolicy(SyncOnSeal)}, durable: true, wantSyncs: 3},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			t.Parallel()
+
+			path, removePath := createPathForTests(t)
+			defer removePath()
+			l, err := New(path, 100, 10_000, time.Hour, v.opts...)
+			if err != nil {
+				t.Fatal("\n\t", err)
+			}
+
+			for i := 0; i < 3; i++ {
+				var errA error
+				if v.durable {
+					errA = l.AppendDurable([]byte("hello"))
+				} else {
+					errA = l.Append([]byte("hello"))
+				}
+				if errA != nil {
+					t.Fatal("\n\t", errA)
+				}
+			}
+			// sub-nanosecond fsyncs are not counted; so some fsyncs may be missed, but never added.
+			got := l.FsyncStalls()
+			if got > v.wantSyncs || (v.wantSyncs > 0 && got == 0) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, v.wantSyncs)
+			}
+		})
+	}
+
+	t.Run("sealed segments are synced", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour, countSyncs, WithSyncPolicy(SyncOnSeal))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		// the first append fills the segment, the second one seals it.
+		for i := 0; i < 2; i++ {
+			errA := l.Append([]byte(strings.Repeat("a", 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		if l.FsyncStalls() != 1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", l.FsyncStalls(), 1)
+		}
+	})
+}
+
+func TestClogSync(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// a stall threshold of 0 counts every fsync.
+	l, err := New(path, 100, 10_000, time.Hour, WithFsyncStallHandler(0, nil), WithSyncPolicy(SyncOnSeal))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for i := 0; i < 3; i++ {
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	if got := l.FsyncStalls(); got != 0 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 0)
+	}
+
+	errS := l.Sync()
+	if errS != nil {
+		t.Fatal("\n\t", errS)
+	}
+	if got := l.FsyncStalls(); got != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, 1)
+	}
+
+	errC := l.Close()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	errS2 := l.Sync()
+	if !errors.Is(errS2, ErrClosed) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errS2, ErrClosed)
+	}
+}
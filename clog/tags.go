@@ -0,0 +1,126 @@
+package clog
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maxTagBytes is the maximum size of a single tag; its key and value combined.
+// Tags are meant to be small, they are all held in memory and rewritten to the manifest whenever one changes.
+const maxTagBytes = 256
+
+var (
+	errBadTag          = fmt.Errorf("segment tags must have a non-empty key and be at most %d bytes", maxTagBytes)
+	errSegmentNotFound = errors.New("commitLog has no segment with that baseOffset")
+)
+
+// TagSegment attaches tags, small key/value pairs like "source=node-7" or "backfill=true", to the segment with baseOffset.
+// The tags are merged into the tags the segment already has; a tag with an empty value removes that key instead.
+//
+// Tags are persisted in the manifest of the commitlog and are available, after the commitlog is reopened, from SegmentTags.
+// They can be used to exempt segments from retention; see WithRetentionExempt.
+//
+// usage:
+//
+//	errT := l.TagSegment(baseOffset, map[string]string{"backfill": "true"})
+func (l *Clog) TagSegment(baseOffset uint64, tags map[string]string) error {
+	for k, v := range tags {
+		if k == "" || len(k)+len(v) > maxTagBytes {
+			return errBadTag
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if errC := l.checkOpen(); errC != nil {
+		return errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return errC
+	}
+	if l.relocating {
+		return ErrRelocating
+	}
+	seg := l.segmentByBaseOffset(baseOffset)
+	if seg == nil {
+		return errSegmentNotFound
+	}
+
+	seg.mu.Lock()
+	old := seg.tags
+	merged := make(map[string]string, len(old)+len(tags))
+	for k, v := range old {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		if v == "" {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	seg.tags = merged
+	seg.mu.Unlock()
+
+	err := l.writeManifest()
+	if err != nil {
+		// the tags were not persisted; do not pretend that they were.
+		seg.mu.Lock()
+		seg.tags = old
+		seg.mu.Unlock()
+		return err
+	}
+	l.journal.record(OpsTag, "segment %d tagged %v", baseOffset, tags)
+	return nil
+}
+
+// SegmentTags returns a copy of the tags of the segment with baseOffset. see TagSegment
+func (l *Clog) SegmentTags(baseOffset uint64) (map[string]string, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seg := l.segmentByBaseOffset(baseOffset)
+	if seg == nil {
+		return nil, errSegmentNotFound
+	}
+	return seg.tagsCopy(), nil
+}
+
+// segmentByBaseOffset returns the segment with baseOffset, or nil if there's none.
+// The caller should hold l.mu
+func (l *Clog) segmentByBaseOffset(baseOffset uint64) *segment {
+	for _, seg := range l.segments {
+		if seg.baseOffset == baseOffset {
+			return seg
+		}
+	}
+	return nil
+}
+
+// writeManifest persists the metadata of the current segments.
+// The caller should hold l.mu
+func (l *Clog) writeManifest() error {
+	l.generation++
+	m := &manifest{Version: FormatVersion, Stripes: l.knownStripes, Segments: map[uint64]segmentMeta{}, Generation: l.generation}
+	for _, seg := range l.segments {
+		meta := segmentMeta{FirstSequence: seg.firstSeq, Seal: seg.sealCopy()}
+		if tags := seg.tagsCopy(); len(tags) > 0 {
+			meta.Tags = tags
+		}
+		m.Segments[seg.baseOffset] = meta
+	}
+	return m.write(l.fs, l.path)
+}
+
+// tagsCopy returns a copy of the tags of the segment.
+func (s *segment) tagsCopy() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	r := make(map[string]string, len(s.tags))
+	for k, v := range s.tags {
+		r[k] = v
+	}
+	return r
+}
@@ -0,0 +1,163 @@
+package clog
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTagSegment(t *testing.T) {
+	t.Parallel()
+
+	t.Run("tags are merged & persisted", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		a, _ := l.activeSegment()
+
+		errA := l.TagSegment(a.baseOffset, map[string]string{"source": "node-7", "backfill": "true"})
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		errB := l.TagSegment(a.baseOffset, map[string]string{"backfill": "", "owner": "billing"})
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+
+		want := map[string]string{"source": "node-7", "owner": "billing"}
+		got, errC := l.SegmentTags(a.baseOffset)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// the returned tags are a copy.
+		got["source"] = "changed"
+
+		l2, errD := New(path, 100, 10_000, time.Hour)
+		if errD != nil {
+			t.Fatal("\n\t", errD)
+		}
+		got2, errE := l2.SegmentTags(a.baseOffset)
+		if errE != nil {
+			t.Fatal("\n\t", errE)
+		}
+		if !cmp.Equal(got2, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got2, want)
+		}
+	})
+
+	t.Run("bad tags", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t)
+		defer removePath()
+		a, _ := l.activeSegment()
+
+		tt := []map[string]string{
+			{"": "value"},
+			{"key": strings.Repeat("a", maxTagBytes)},
+		}
+		for _, tags := range tt {
+			err := l.TagSegment(a.baseOffset, tags)
+			if !errors.Is(err, errBadTag) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errBadTag)
+			}
+		}
+	})
+
+	t.Run("unknown segment", func(t *testing.T) {
+		t.Parallel()
+
+		l, removePath := createClogForTests(t)
+		defer removePath()
+
+		err := l.TagSegment(7, map[string]string{"a": "b"})
+		if !errors.Is(err, errSegmentNotFound) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, errSegmentNotFound)
+		}
+		_, errA := l.SegmentTags(7)
+		if !errors.Is(errA, errSegmentNotFound) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, errSegmentNotFound)
+		}
+	})
+}
+
+func TestRetentionExempt(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	l, err := New(path, 100, 250, time.Hour, WithRetentionExempt(func(tags map[string]string) bool {
+		return tags["backfill"] == "true"
+	}))
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+
+	msg := []byte(strings.Repeat("a", 200))
+	for i := 0; i < 5; i++ {
+		errA := l.Append(msg)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+	bases := []uint64{}
+	for _, seg := range l.segments {
+		bases = append(bases, seg.baseOffset)
+	}
+	for _, b := range []uint64{bases[0], bases[1]} {
+		errB := l.TagSegment(b, map[string]string{"backfill": "true"})
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+	}
+	errC := l.TagSegment(bases[2], map[string]string{"source": "node-7"})
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+
+	errD := l.Clean()
+	if errD != nil {
+		t.Fatal("\n\t", errD)
+	}
+
+	// bases[2] is not exempt & is beyond retention.
+	got := []uint64{}
+	for _, seg := range l.segments {
+		got = append(got, seg.baseOffset)
+	}
+	want := []uint64{bases[0], bases[1], bases[3], bases[4]}
+	if !cmp.Equal(got, want) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	// the tags of the deleted segment are dropped from the manifest.
+	m, errE := readManifest(l.fs, l.path)
+	if errE != nil {
+		t.Fatal("\n\t", errE)
+	}
+	_, ok := m.Segments[bases[2]]
+	if ok {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", m.Segments, "no tags for deleted segment")
+	}
+	tagged := 0
+	for _, meta := range m.Segments {
+		if len(meta.Tags) > 0 {
+			tagged++
+		}
+	}
+	if tagged != 2 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", tagged, 2)
+	}
+}
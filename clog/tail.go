@@ -0,0 +1,96 @@
+package clog
+
+import "context"
+
+// TailRecord is a record delivered by Clog.Tail.
+type TailRecord struct {
+	// Record is the record; it is owned by the receiver.
+	Record []byte
+	// Offset is where the record is in the commitlog. Tailing can be resumed after a restart by passing
+	// Offset.BaseOffset-1 to Tail & skipping the records whose Sequence is not larger than that of the last one handled.
+	Offset RecordOffset
+	// Err, if not nil, is the error that stopped the tail; it is the last item delivered.
+	// If records that the tail had not delivered were deleted, eg by Clean, it is an *OutOfRangeError.
+	Err error
+}
+
+// Tail delivers the records of the commitlog, from the segments after offset(exclusive), on the returned channel; and
+// then keeps delivering new records as they are appended, like `tail -f`. As in Read, an offset of 0 starts from the
+// earliest data available.
+//
+// Records are delivered one at a time, as the receiver takes them; a slow receiver slows the tail down, rather than
+// records being buffered for it. The channel is closed once ctx is done, or after an item whose Err is set. So the
+// receiver should either receive until the channel is closed, or cancel ctx when it is done.
+//
+// usage:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	records, errT := l.Tail(ctx, 0)
+//	for r := range records {
+//		if r.Err != nil {
+//			log.Fatal(r.Err)
+//		}
+//		process(r.Record)
+//	}
+func (l *Clog) Tail(ctx context.Context, offset uint64) (<-chan TailRecord, error) {
+	l.mu.RLock()
+	if !l.initialized {
+		l.mu.RUnlock()
+		return nil, errLogNotInitialized
+	}
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, errC
+	}
+	if offset != 0 && offset < l.lowWatermark {
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, &OutOfRangeError{Offset: offset, LowWatermark: lw}
+	}
+	l.mu.RUnlock()
+
+	records := make(chan TailRecord)
+	go l.tail(ctx, l.NewIterator(offset), records)
+	return records, nil
+}
+
+// tail delivers the records of it on records, waiting for appends once it has caught up; until ctx is done.
+func (l *Clog) tail(ctx context.Context, it *Iterator, records chan<- TailRecord) {
+	defer close(records)
+
+	for {
+		// the channel is taken before iterating; so that an append made while iterating is not missed.
+		appended := l.appendedChan()
+		for it.Next() {
+			select {
+			case records <- TailRecord{Record: it.Value(), Offset: it.Offset()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			select {
+			case records <- TailRecord{Err: err}:
+			case <-ctx.Done():
+			}
+			return
+		}
+
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// appendedChan returns a channel that is closed by the next append. see notifyAppended
+func (l *Clog) appendedChan() <-chan struct{} {
+	l.appendedMu.Lock()
+	defer l.appendedMu.Unlock()
+	if l.appended == nil {
+		l.appended = make(chan struct{})
+	}
+	return l.appended
+}
@@ -0,0 +1,68 @@
+package clog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTail(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each segment holds two records.
+	l, err := New(path, 2*(9+recordHeaderSize), 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	records, errT := l.Tail(ctx, 0)
+	if errT != nil {
+		t.Fatal("\n\t", errT)
+	}
+	receive := func(n int) []string {
+		got := []string{}
+		for i := 0; i < n; i++ {
+			select {
+			case r := <-records:
+				if r.Err != nil {
+					t.Fatal("\n\t", r.Err)
+				}
+				got = append(got, string(r.Record))
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, n)
+			}
+		}
+		return got
+	}
+
+	// the records already in the commitlog.
+	if got := receive(3); !cmp.Equal(got, []string{"order # 1", "order # 2", "order # 3"}) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "the records already appended")
+	}
+	// records appended afterwards, to the active segment & to new ones.
+	for _, r := range []string{"order # 4", "order # 5"} {
+		errA := l.Append([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if got := receive(1); !cmp.Equal(got, []string{r}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, []string{r})
+		}
+	}
+
+	cancel()
+	for r := range records {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", r, "the channel to be closed")
+	}
+}
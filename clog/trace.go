@@ -0,0 +1,82 @@
+package clog
+
+import (
+	"sync"
+	"time"
+)
+
+// TraceEntry describes a single operation on a commitlog. see WithTrace
+type TraceEntry struct {
+	// Op is the operation; eg "append", "read" or "clean".
+	Op string
+	// Start is when the operation started.
+	Start time.Time
+	// Duration is how long the operation took.
+	Duration time.Duration
+	// Bytes is the number of bytes appended or read.
+	Bytes int
+	// Err is the error the operation returned, if any.
+	Err error
+}
+
+// tracer keeps the latest operations on a commitlog in a fixed-size ring.
+// A nil *tracer keeps nothing.
+type tracer struct {
+	// mu protects ring & next
+	mu   sync.Mutex
+	ring []TraceEntry
+	next int // index in ring of the next entry; the ring is full once it wraps around.
+	full bool
+}
+
+func newTracer(size int) *tracer {
+	return &tracer{ring: make([]TraceEntry, size)}
+}
+
+// add records an operation, op, that started at start.
+func (t *tracer) add(op string, start time.Time, bytes int, err error) {
+	if t == nil {
+		return
+	}
+	e := TraceEntry{Op: op, Start: start, Duration: time.Since(start), Bytes: bytes, Err: err}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.ring[t.next] = e
+	t.next++
+	if t.next == len(t.ring) {
+		t.next = 0
+		t.full = true
+	}
+}
+
+// entries returns a copy of the entries in the ring; oldest first.
+func (t *tracer) entries() []TraceEntry {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.full {
+		return append([]TraceEntry{}, t.ring[:t.next]...)
+	}
+	return append(append([]TraceEntry{}, t.ring[t.next:]...), t.ring[:t.next]...)
+}
+
+// errors returns the entries in the ring that failed; oldest first.
+func (t *tracer) errors() []TraceEntry {
+	r := []TraceEntry{}
+	for _, e := range t.entries() {
+		if e.Err != nil {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// Trace returns the latest operations on the commitlog; oldest first. see WithTrace
+// It is always empty if WithTrace was not used.
+func (l *Clog) Trace() []TraceEntry {
+	return l.tracer.entries()
+}
@@ -0,0 +1,88 @@
+package clog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	t.Run("latest operations are kept", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour, WithTrace(3))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		for i := 0; i < 3; i++ {
+			errA := l.Append([]byte("hello"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		_, _, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		errC := l.Clean()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+
+		entries := l.Trace()
+		ops := []string{}
+		bytes := []int{}
+		for _, e := range entries {
+			ops = append(ops, e.Op)
+			bytes = append(bytes, e.Bytes)
+			if e.Start.IsZero() || e.Err != nil {
+				t.Errorf("bad entry: %#+v", e)
+			}
+		}
+		if !cmp.Equal(ops, []string{"append", "read", "clean"}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", ops, []string{"append", "read", "clean"})
+		}
+		if !cmp.Equal(bytes, []int{5, 3 * (5 + recordHeaderSize), 0}) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", bytes, []int{5, 3 * (5 + recordHeaderSize), 0})
+		}
+	})
+
+	t.Run("errors are kept", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour, WithTrace(10))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		_, _, errA := l.ReadRange(7, 7)
+		entries := l.Trace()
+		if len(entries) != 1 || entries[0].Op != "read-range" || entries[0].Err != errA {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", entries, errA)
+		}
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		errA := l.Append([]byte("hello"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if len(l.Trace()) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", l.Trace(), nil)
+		}
+	})
+}
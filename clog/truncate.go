@@ -0,0 +1,146 @@
+package clog
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"time"
+)
+
+var (
+	errNoRecordAt      = func(o RecordOffset) error { return fmt.Errorf("commitLog has no record at %+v", o) }
+	errSegmentTruncate = func(err error) error { return fmt.Errorf("segment truncate failed: %w", err) }
+)
+
+// TruncateTail removes the records that were appended after the record at after; so that the commitlog ends with it, as
+// if they had never been appended. It is for rollback, eg a replica that drops the records that its primary does not
+// have before it follows the primary again. The zero RecordOffset removes all the records of the commitlog.
+//
+// The segments after the one that holds after are deleted, & that segment is truncated to end with after; it becomes
+// the active segment again. Appends carry on after after, with the sequence numbers that follow it; the segments they
+// create have later baseOffsets than those that were deleted. Reads of the removed records that are in progress may
+// fail; & readers that had read past after should resume from it.
+//
+// after has to be the offset of a record, as returned by AppendOffset & the like; otherwise nothing is removed, & an
+// error is returned.
+//
+// usage:
+//
+//	o, errA := l.AppendOffset([]byte("order # 1"))
+//	errB := l.AppendBulk(uncommitted)
+//	errT := l.TruncateTail(o) // the commitlog ends with "order # 1" again.
+func (l *Clog) TruncateTail(after RecordOffset) (err error) {
+	defer func(start time.Time) { l.tracer.add("truncate-tail", start, 0, err) }(time.Now())
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.initialized {
+		return errLogNotInitialized
+	}
+	if errC := l.checkOpen(); errC != nil {
+		return errC
+	}
+	if errC := l.checkWritable(); errC != nil {
+		return errC
+	}
+	if l.relocating {
+		return ErrRelocating
+	}
+
+	segs := l.segmentRead()
+	if len(segs) == 0 {
+		return errNoRecordAt(after)
+	}
+	i, end := 0, uint64(0)
+	if after != (RecordOffset{}) {
+		i = -1
+		for j, seg := range segs {
+			if seg.baseOffset == after.BaseOffset {
+				i = j
+			}
+		}
+		if i < 0 {
+			return errNoRecordAt(after)
+		}
+		var header [recordHeaderSize]byte
+		if _, errR := segs[i].readAt(header[:], int64(after.Position)); errR != nil {
+			return errNoRecordAt(after)
+		}
+		end = after.Position + recordHeaderSize + uint64(binary.BigEndian.Uint32(header[:]))
+		if end > segs[i].size() {
+			return errNoRecordAt(after)
+		}
+	}
+
+	// the data that is kept has to be whole records; which also checks that after is at the start of one.
+	seg := segs[i]
+	b := make([]byte, end)
+	if end > 0 {
+		if _, errR := seg.readAt(b, 0); errR != nil {
+			return errR
+		}
+	}
+	records, errB := countRecords(seg.filePath, b)
+	if errB != nil {
+		return errNoRecordAt(after)
+	}
+
+	deleted := []uint64{}
+	for _, later := range segs[i+1:] {
+		errD := later.Delete()
+		if errD != nil {
+			return errD
+		}
+		if _, _, ok := later.pendingDeletion(); ok {
+			l.pendingDeletes = append(l.pendingDeletes, later)
+		}
+		l.removeEmptyDirs(later)
+		deleted = append(deleted, later.baseOffset)
+	}
+	l.segments = segs[:i+1]
+
+	errT := seg.truncateTail(b, records)
+	if errT != nil {
+		return errT
+	}
+	l.journal.record(OpsTruncateTail, "truncated segment %d to %d bytes, deleted segments %v", seg.baseOffset, end, deleted)
+	return l.writeManifest()
+}
+
+// truncateTail truncates the data of the segment to b, which holds n records; & makes it the active segment again.
+func (s *segment) truncateTail(b []byte, n uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		// it was closed when it was sealed.
+		f, err := s.fsys.OpenFile(s.filePath, os.O_RDWR|os.O_APPEND, ownerReadableWritable)
+		if err != nil {
+			return errOpenFile(err)
+		}
+		s.f = f
+		s.closed = false
+	}
+	err := s.f.Truncate(int64(len(b)))
+	if err != nil {
+		return errSegmentTruncate(err)
+	}
+	s.currentSegBytes = uint64(len(b))
+	s.records = n
+	s.recordsKnown = true
+	s.sum = crc32.Checksum(b, crcTable)
+	s.sumKnown = true
+	s.seal = nil
+	if s.cache != nil {
+		s.cache = append(s.cache[:0:0], b...)
+	}
+	s.age = s.clock.age(s.baseOffset)
+
+	errS := s.sync()
+	if errS != nil {
+		return errSegmentSync(errS)
+	}
+	return nil
+}
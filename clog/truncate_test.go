@@ -0,0 +1,116 @@
+package clog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTruncateTail(t *testing.T) {
+	t.Parallel()
+
+	records := func(t *testing.T, l *Clog) []string {
+		t.Helper()
+		rs, _, err := l.ReadRecords(0, 0)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		got := []string{}
+		for _, r := range rs {
+			got = append(got, string(r))
+		}
+		return got
+	}
+	// each segment holds three records.
+	setup := func(t *testing.T) (*Clog, []RecordOffset, string) {
+		t.Helper()
+		path, removePath := createPathForTests(t)
+		t.Cleanup(removePath)
+		l, err := New(path, 40, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		offsets := []RecordOffset{}
+		for i := 1; i <= 7; i++ {
+			o, errA := l.AppendOffset([]byte(fmt.Sprintf("order # %d", i)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+			offsets = append(offsets, o)
+		}
+		return l, offsets, path
+	}
+
+	t.Run("records after the offset are removed", func(t *testing.T) {
+		t.Parallel()
+
+		l, offsets, path := setup(t)
+		errT := l.TruncateTail(offsets[4])
+		if errT != nil {
+			t.Fatal("\n\t", errT)
+		}
+		want := []string{"order # 1", "order # 2", "order # 3", "order # 4", "order # 5"}
+		if got := records(t, l); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// appends carry on after the offset, in the segment that held it.
+		o, errA := l.AppendOffset([]byte("order # 6b"))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if o.BaseOffset != offsets[4].BaseOffset || o.Sequence != offsets[4].Sequence+1 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", o, "the record to follow the one truncated to")
+		}
+		want = append(want, "order # 6b")
+		if got := records(t, l); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+
+		// the truncation survives the commitlog being opened again.
+		errC := l.Close()
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		l2, errN := New(path, 40, 100_000, time.Hour)
+		if errN != nil {
+			t.Fatal("\n\t", errN)
+		}
+		defer l2.Close()
+		if got := records(t, l2); !cmp.Equal(got, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+		}
+	})
+
+	t.Run("not the offset of a record", func(t *testing.T) {
+		t.Parallel()
+
+		l, offsets, _ := setup(t)
+		defer l.Close()
+		bad := offsets[4]
+		bad.Position = bad.Position + 3
+		errT := l.TruncateTail(bad)
+		if errT == nil {
+			t.Fatal("expected an error")
+		}
+		if got := records(t, l); len(got) != 7 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "all the records")
+		}
+	})
+
+	t.Run("all records", func(t *testing.T) {
+		t.Parallel()
+
+		l, _, _ := setup(t)
+		defer l.Close()
+		errT := l.TruncateTail(RecordOffset{})
+		if errT != nil {
+			t.Fatal("\n\t", errT)
+		}
+		if got := records(t, l); len(got) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, []string{})
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package clog
+
+// Warmup reads the tail of the commitlog, the latest segments that hold at least lastNBytes, into the operating system's page cache.
+// It is meant to be called after New, when a process restarts, so that the first reads of consumers, which are usually
+// of the tail, are served from memory rather than from a cold disk.
+// The data read is discarded; the segments of the commitlog are already open.
+//
+// Whole segments are read. If lastNBytes == 0, nothing is read.
+// Warmup is best done before consumers start reading, since it competes with them for IO.
+//
+// usage:
+//
+//	l, err := clog.New(path, maxSegBytes, maxLogBytes, maxLogAge)
+//	errW := l.Warmup(64 * 1024 * 1024)
+func (l *Clog) Warmup(lastNBytes uint64) error {
+	if lastNBytes == 0 {
+		return nil
+	}
+
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return errC
+	}
+	all := l.segmentRead()
+	tail := []*segment{}
+	var size uint64
+	for i := len(all) - 1; i >= 0 && size < lastNBytes; i-- {
+		seg := all[i]
+		if !seg.acquire() {
+			continue
+		}
+		tail = append(tail, seg)
+		size = size + seg.size()
+	}
+	l.mu.RUnlock()
+	defer releaseSegments(tail)
+
+	for _, seg := range tail {
+		_, err := seg.Read()
+		if err != nil {
+			l.reportCorruption(err)
+			return err
+		}
+	}
+	return nil
+}
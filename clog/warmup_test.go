@@ -0,0 +1,103 @@
+package clog
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// readRecordingFS is a FileSystem that records the files read using ReadFile.
+type readRecordingFS struct {
+	OSFileSystem
+
+	mu    sync.Mutex
+	reads []string
+}
+
+func (r *readRecordingFS) ReadFile(name string) ([]byte, error) {
+	r.mu.Lock()
+	r.reads = append(r.reads, name)
+	r.mu.Unlock()
+	return r.OSFileSystem.ReadFile(name)
+}
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+
+	createFullClog := func(t *testing.T) (*Clog, *readRecordingFS, func()) {
+		path, removePath := createPathForTests(t)
+		fsys := &readRecordingFS{}
+		l, err := New(path, 100, 10_000, time.Hour, WithFileSystem(fsys))
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		// each append fills a segment.
+		for i := 0; i < 5; i++ {
+			errA := l.Append([]byte(strings.Repeat("a", 100)))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		fsys.reads = nil
+		return l, fsys, removePath
+	}
+
+	t.Run("reads the tail", func(t *testing.T) {
+		t.Parallel()
+
+		l, fsys, removePath := createFullClog(t)
+		defer removePath()
+
+		// each segment holds 100 bytes; so the active segment and the one before it make up 150 bytes.
+		err := l.Warmup(150)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		segs := l.segmentRead()
+		want := []string{}
+		for i := len(segs) - 1; i >= len(segs)-2; i-- {
+			want = append(want, segs[i].filePath)
+		}
+		if !cmp.Equal(fsys.reads, want) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.reads, want)
+		}
+	})
+
+	t.Run("zero reads nothing", func(t *testing.T) {
+		t.Parallel()
+
+		l, fsys, removePath := createFullClog(t)
+		defer removePath()
+
+		err := l.Warmup(0)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if len(fsys.reads) != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", fsys.reads, nil)
+		}
+	})
+
+	t.Run("corruption is reported", func(t *testing.T) {
+		t.Parallel()
+
+		l, _, removePath := createFullClog(t)
+		defer removePath()
+		segs := l.segmentRead()
+		errA := os.Truncate(segs[len(segs)-2].filePath, 10)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+
+		var ce *CorruptionError
+		err := l.Warmup(1_000)
+		if !errors.As(err, &ce) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", err, &CorruptionError{})
+		}
+	})
+}
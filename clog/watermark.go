@@ -0,0 +1,227 @@
+package clog
+
+import (
+	"context"
+	"time"
+)
+
+// HighWatermark returns the baseOffset of the latest segment of the commitlog that holds data; 0 if no segment does.
+// Data appended so far is read by a Read from any offset below it, & Read returns it as the lastReadOffset once
+// a consumer has caught up.
+func (l *Clog) HighWatermark() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.highWatermark()
+}
+
+// highWatermark is like HighWatermark. The caller should hold l.mu
+func (l *Clog) highWatermark() uint64 {
+	segs := l.segmentRead()
+	for i := len(segs) - 1; i >= 0; i-- {
+		if segs[i].size() > 0 {
+			return segs[i].baseOffset
+		}
+	}
+	return 0
+}
+
+// FirstOffset returns the offset from which a Read reads all the data that the commitlog retains; one below the
+// baseOffset of its oldest segment, since offsets are excluded by Read. 0 if the commitlog has no segments.
+// Clean moves it forward; reads from offsets below it fail with an *OutOfRangeError once data after them has been
+// deleted.
+func (l *Clog) FirstOffset() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	segs := l.segmentRead()
+	if len(segs) == 0 || segs[0].baseOffset == 0 {
+		return 0
+	}
+	return segs[0].baseOffset - 1
+}
+
+// LastOffset returns the offset up to which the data appended so far is read; it is the HighWatermark. A Read from
+// FirstOffset returns it as the lastReadOffset, & a Read from it returns nothing until more data is appended.
+func (l *Clog) LastOffset() uint64 {
+	return l.HighWatermark()
+}
+
+// SizeBytes returns the size, on disk, of the data of the segments of the commitlog; framing included. It is the size
+// that maxLogBytes caps. Segments that have been deleted, but whose removal is pending on reads, are not included;
+// see PendingDeletion
+func (l *Clog) SizeBytes() uint64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	total := uint64(0)
+	for _, seg := range l.segmentRead() {
+		total = total + seg.size()
+	}
+	return total
+}
+
+// WaitForOffset blocks until the high watermark of the commitlog reaches offset, ie until a Read could observe data in
+// the segment whose baseOffset is offset; or until ctx is done, in which case the error of ctx is returned.
+// It returns immediately if the high watermark is already at, or past, offset. see HighWatermark
+//
+// It is cheap; waiters are woken up by appends, rather than by polling the commitlog.
+//
+// usage:
+//
+//	// producer
+//	o, errA := l.AppendOffset([]byte("order # 1"))
+//	// replica or consumer, that tails the same commitlog
+//	errW := l.WaitForOffset(ctx, o.BaseOffset)
+func (l *Clog) WaitForOffset(ctx context.Context, offset uint64) error {
+	for {
+		// the channel is taken before the high watermark is checked; so that an append made meanwhile is not missed.
+		appended := l.appendedChan()
+		l.mu.RLock()
+		if !l.initialized {
+			l.mu.RUnlock()
+			return errLogNotInitialized
+		}
+		if errC := l.checkOpen(); errC != nil {
+			l.mu.RUnlock()
+			return errC
+		}
+		hw := l.highWatermark()
+		l.mu.RUnlock()
+		if hw >= offset {
+			return nil
+		}
+
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// ReadWait reads upto maxToRead bytes of the records appended after the position after, which a previous ReadWait
+// returned as next; so that it carries on from where that read stopped, even if it was part way through the active
+// segment. The zero RecordOffset reads from the earliest data available. see RecordOffset
+//
+// Unlike Read, if there is no data after it yet, eg because the consumer has caught up with the head of the commitlog,
+// it waits for data to be appended rather than returning nothing. So consumers do not have to poll Read in a busy loop.
+// If ctx is done first, the error of ctx is returned; with no data & with after as next.
+//
+// As with Read, the data read is framed records; use Records to split it. Reading from a position whose segment has
+// since been deleted, eg by Clean, returns an *OutOfRangeError.
+//
+// usage:
+//
+//	var after clog.RecordOffset
+//	for {
+//		data, next, err := l.ReadWait(ctx, after, 0)
+//		if err != nil {
+//			return err
+//		}
+//		process(data)
+//		after = next
+//	}
+func (l *Clog) ReadWait(ctx context.Context, after RecordOffset, maxToRead uint64) (dataRead []byte, next RecordOffset, err error) {
+	defer func(start time.Time) { l.tracer.add("read-wait", start, len(dataRead), err) }(time.Now())
+
+	for {
+		// the channel is taken before reading; so that an append made while reading is not missed.
+		appended := l.appendedChan()
+		data, nxt, errR := l.readFrom(ctx, after, maxToRead)
+		if errR != nil || len(data) > 0 {
+			return data, nxt, errR
+		}
+
+		select {
+		case <-appended:
+		case <-ctx.Done():
+			return nil, after, ctx.Err()
+		}
+	}
+}
+
+// readFrom is ReadWait, without the waiting. It reads the segment of after from its Position, & the segments after it
+// in full.
+func (l *Clog) readFrom(ctx context.Context, after RecordOffset, maxToRead uint64) (dataRead []byte, next RecordOffset, err error) {
+	next = after
+	l.mu.RLock()
+	if errC := l.checkOpen(); errC != nil {
+		l.mu.RUnlock()
+		return nil, next, errC
+	}
+	if after.BaseOffset != 0 && after.BaseOffset <= l.lowWatermark {
+		// the segment of after, or data after it, has been deleted.
+		lw := l.lowWatermark
+		l.mu.RUnlock()
+		return nil, next, &OutOfRangeError{Offset: after.BaseOffset, LowWatermark: lw}
+	}
+	from := after.BaseOffset
+	if from > 0 {
+		// acquireAfter excludes from; but the segment of after is to be read, from its Position.
+		from--
+	}
+	segs := acquireAfter(l.segmentRead(), from)
+	l.mu.RUnlock()
+	defer releaseSegments(segs)
+
+	var reserved uint64
+	defer func() { l.budget.release(reserved) }()
+
+	var max int = int(maxToRead)
+	if max <= 0 {
+		max = internalMaxToRead
+	} else if max > (internalMaxToRead * 10) {
+		// prevent OOM. see Read
+		max = internalMaxToRead * 10
+	}
+
+	for _, seg := range segs {
+		if errC := ctx.Err(); errC != nil {
+			return dataRead, next, errC
+		}
+		pos, seq := uint64(0), seg.firstSeq
+		if seg.baseOffset == after.BaseOffset {
+			pos, seq = after.Position, after.Sequence
+		}
+		end := seg.size()
+		if end <= pos {
+			continue
+		}
+		if reserved == 0 {
+			// make sure that we can always make progress.
+			r, errA := l.budget.acquire(ctx, end-pos)
+			if errA != nil {
+				return dataRead, next, errA
+			}
+			reserved = r
+		} else if l.budget.tryAcquire(end - pos) {
+			reserved = reserved + end - pos
+		} else {
+			// the budget is exhausted, trim the read.
+			break
+		}
+
+		b, n, errR := seg.readRecords(pos, end)
+		if errR != nil {
+			l.reportCorruption(errR)
+			return dataRead, next, errR
+		}
+		dataRead = append(dataRead, b...)
+		next = RecordOffset{BaseOffset: seg.baseOffset, Position: end, Sequence: seq + n}
+		if len(dataRead) >= max {
+			break
+		}
+	}
+	return dataRead, next, nil
+}
+
+// notifyAppended wakes up the callers of WaitForOffset, if any, so that they check the high watermark again.
+// The caller should hold l.mu
+func (l *Clog) notifyAppended() {
+	l.appendedMu.Lock()
+	defer l.appendedMu.Unlock()
+	if l.appended != nil {
+		close(l.appended)
+		l.appended = nil
+	}
+}
@@ -0,0 +1,237 @@
+package clog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForOffset(t *testing.T) {
+	t.Parallel()
+
+	t.Run("high watermark", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		if hw := l.HighWatermark(); hw != 0 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", hw, 0)
+		}
+
+		// each append fills a segment.
+		for i := 0; i < 2; i++ {
+			errA := l.Append([]byte("hello world"))
+			if errA != nil {
+				t.Fatal("\n\t", errA)
+			}
+		}
+		_, last, errB := l.Read(0, 0)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if hw := l.HighWatermark(); hw != last {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", hw, last)
+		}
+
+		// an offset that has been reached does not block.
+		errC := l.WaitForOffset(context.Background(), last)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+	})
+
+	t.Run("waits for an append", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		a, _ := l.activeSegment()
+
+		done := make(chan error, 1)
+		go func() {
+			done <- l.WaitForOffset(context.Background(), a.baseOffset)
+		}()
+		select {
+		case errA := <-done:
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, "WaitForOffset to block")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		errB := l.Append([]byte("hello"))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		select {
+		case errC := <-done:
+			if errC != nil {
+				t.Fatal("\n\t", errC)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("WaitForOffset should have returned after the append")
+		}
+	})
+
+	t.Run("context is done", func(t *testing.T) {
+		t.Parallel()
+
+		path, removePath := createPathForTests(t)
+		defer removePath()
+		l, err := New(path, 10_000, 100_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		errA := l.WaitForOffset(ctx, tNow()+uint64(time.Hour))
+		if !errors.Is(errA, context.DeadlineExceeded) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errA, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestReadWait(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each append fills a segment.
+	l, err := New(path, 10, 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	errA := l.Append([]byte("order # 1"))
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	data, next, errB := l.ReadWait(context.Background(), RecordOffset{}, 0)
+	if errB != nil {
+		t.Fatal("\n\t", errB)
+	}
+	if recordsForTests(t, data) != "order # 1" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", recordsForTests(t, data), "order # 1")
+	}
+
+	// at the head, it waits.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, next2, errC := l.ReadWait(ctx, next, 0)
+	if !errors.Is(errC, context.DeadlineExceeded) || next2 != next {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", errC, context.DeadlineExceeded)
+	}
+
+	read := make(chan []byte, 1)
+	go func() {
+		d, _, errD := l.ReadWait(context.Background(), next, 0)
+		if errD != nil {
+			t.Error("\n\t", errD)
+		}
+		read <- d
+	}()
+	errE := l.Append([]byte("order # 2"))
+	if errE != nil {
+		t.Fatal("\n\t", errE)
+	}
+	if got := recordsForTests(t, <-read); got != "order # 2" {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, "order # 2")
+	}
+
+	// appends to the active segment, after it was read, are read next; without rolling the segment.
+	path2, removePath2 := createPathForTests(t)
+	defer removePath2()
+	l2, err := New(path2, 1_000, 100_000, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	type result struct {
+		data []byte
+		next RecordOffset
+	}
+	after := RecordOffset{}
+	for i, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		results := make(chan result, 1)
+		go func(after RecordOffset) {
+			d, n, errD := l2.ReadWait(context.Background(), after, 0)
+			if errD != nil {
+				t.Error("\n\t", errD)
+			}
+			results <- result{data: d, next: n}
+		}(after)
+		o, errF := l2.AppendOffset([]byte(r))
+		if errF != nil {
+			t.Fatal("\n\t", errF)
+		}
+		res := <-results
+		if got := recordsForTests(t, res.data); got != r {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, r)
+		}
+		want := RecordOffset{BaseOffset: o.BaseOffset, Position: uint64(i+1) * (9 + recordHeaderSize), Sequence: o.Sequence + 1}
+		if res.next != want {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", res.next, want)
+		}
+		after = res.next
+	}
+	if segs, _ := l2.Segments(); len(segs) != 1 {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", len(segs), 1)
+	}
+}
+
+func TestOffsetRange(t *testing.T) {
+	t.Parallel()
+
+	path, removePath := createPathForTests(t)
+	defer removePath()
+	// each segment holds a single record; & the commitlog retains two of them.
+	l, err := New(path, 10, 34, time.Hour)
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer l.Close()
+
+	offsets := []RecordOffset{}
+	for _, r := range []string{"order # 1", "order # 2", "order # 3"} {
+		o, errA := l.AppendOffset([]byte(r))
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		offsets = append(offsets, o)
+	}
+	if got, want := l.FirstOffset(), offsets[0].BaseOffset-1; got != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+	if got, want := l.LastOffset(), offsets[2].BaseOffset; got != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+	if got, want := l.SizeBytes(), uint64(3*(recordHeaderSize+len("order # 1"))); got != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+
+	errC := l.Clean()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	first := l.FirstOffset()
+	if first < offsets[0].BaseOffset {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", first, "the first offset to move past the deleted segment")
+	}
+	// a read from the first offset reads up to the last one, without a gap.
+	_, last, errR := l.Read(first, 0)
+	if errR != nil {
+		t.Fatal("\n\t", errR)
+	}
+	if last != l.LastOffset() {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", last, l.LastOffset())
+	}
+	if got, want := l.SizeBytes(), uint64(2*(recordHeaderSize+len("order # 1"))); got != want {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, want)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os/exec"
+	"unicode/utf8"
+
+	"github.com/komuw/shifta/clog"
+)
+
+// decoders decode the data of a record, for humans to read, and write it to w.
+var decoders = map[string]func(w io.Writer, data []byte) error{
+	"raw": func(w io.Writer, data []byte) error {
+		_, err := w.Write(data)
+		return err
+	},
+	"utf8": func(w io.Writer, data []byte) error {
+		if !utf8.Valid(data) {
+			data = bytes.ToValidUTF8(data, []byte(string(utf8.RuneError)))
+		}
+		_, err := fmt.Fprintln(w, string(data))
+		return err
+	},
+	"base64": func(w io.Writer, data []byte) error {
+		_, err := fmt.Fprintln(w, base64.StdEncoding.EncodeToString(data))
+		return err
+	},
+	"json": func(w io.Writer, data []byte) error {
+		buf := &bytes.Buffer{}
+		err := json.Indent(buf, data, "", "  ")
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, errA := w.Write(buf.Bytes())
+		return errA
+	},
+}
+
+func cat(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `usage: shifta cat -dir <dir> [flags]
+
+cat prints the records of the commitlog in dir to stdout, oldest first. Each record is decoded on its own:
+	raw     as is.
+	utf8    as utf-8 text, one line per record; invalid bytes are replaced with U+FFFD.
+	base64  as base64, one line per record.
+	json    as a JSON value, that is pretty printed.
+-exec runs a command once per record, with the decoded record as its stdin & the stdout of cat as its stdout.
+cat stops at the first command that fails.
+Nothing in dir is modified; so it can be printed while another process appends to it.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+
+	var dir, decode, command string
+	var offset uint64
+	flags.StringVar(&dir, "dir", "", "directory of the commitlog.")
+	flags.StringVar(&decode, "decode", "utf8", "how to decode the data; raw, utf8, base64 or json.")
+	flags.StringVar(&command, "exec", "", "command, run by sh for each record, to pipe the decoded record through.")
+	flags.Uint64Var(&offset, "offset", 0, "only print the data after this offset(exclusive).")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if dir == "" {
+		fmt.Fprintln(stderr, "shifta cat: -dir is required")
+		flags.Usage()
+		return 2
+	}
+	decoder, ok := decoders[decode]
+	if !ok {
+		fmt.Fprintf(stderr, "shifta cat: unknown decoder %q\n", decode)
+		flags.Usage()
+		return 2
+	}
+
+	l, errB := clog.OpenReadOnly(dir)
+	if errB != nil {
+		fmt.Fprintln(stderr, "shifta cat:", errB)
+		return 1
+	}
+	defer l.Close()
+
+	out := func(data []byte) error { return decoder(stdout, data) }
+	if command != "" {
+		out = func(data []byte) error {
+			buf := &bytes.Buffer{}
+			errC := decoder(buf, data)
+			if errC != nil {
+				return errC
+			}
+			cmd := exec.Command("sh", "-c", command)
+			cmd.Stdin = buf
+			cmd.Stdout = stdout
+			cmd.Stderr = stderr
+			errD := cmd.Run()
+			if errD != nil {
+				return fmt.Errorf("exec %q: %w", command, errD)
+			}
+			return nil
+		}
+	}
+
+	errE := catRecords(l, offset, out)
+	if errE != nil {
+		fmt.Fprintln(stderr, "shifta cat:", errE)
+		return 1
+	}
+	return 0
+}
+
+// catRecords calls out with the data of each record of l, after offset(exclusive); oldest first.
+func catRecords(l *clog.Clog, offset uint64, out func(data []byte) error) error {
+	for {
+		// a maxToRead of 1 reads a single, non-empty, segment at a time.
+		data, lastReadOffset, err := l.Read(offset, 1)
+		if err != nil {
+			return err
+		}
+		if lastReadOffset == 0 {
+			return nil
+		}
+		records, errA := clog.Records(data)
+		if errA != nil {
+			return fmt.Errorf("segment %d: %w", lastReadOffset, errA)
+		}
+		for i, r := range records {
+			errB := out(r)
+			if errB != nil {
+				return fmt.Errorf("record %d of segment %d: %w", i, lastReadOffset, errB)
+			}
+		}
+		offset = lastReadOffset
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/komuw/shifta/clog"
+)
+
+func TestCat(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "shifta-cat")
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer os.RemoveAll(dir)
+	l, errA := clog.New(dir, 16, 10_000, time.Hour)
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	for _, r := range []string{`{"id":1}`, `{"id":2}`, `{"id":3}`} {
+		errB := l.Append([]byte(r))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+	}
+
+	before := dirForTests(t, dir)
+
+	tt := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{name: "no dir", args: []string{"cat"}, wantCode: 2},
+		{name: "unknown decoder", args: []string{"cat", "-dir", dir, "-decode", "xml"}, wantCode: 2},
+		{name: "utf8", args: []string{"cat", "-dir", dir}, wantCode: 0, wantStdout: "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"},
+		{name: "json", args: []string{"cat", "-dir", dir, "-decode", "json"}, wantCode: 0, wantStdout: "{\n  \"id\": 1\n}\n{\n  \"id\": 2\n}\n{\n  \"id\": 3\n}\n"},
+		{name: "base64", args: []string{"cat", "-dir", dir, "-decode", "base64"}, wantCode: 0, wantStdout: "eyJpZCI6MX0=\neyJpZCI6Mn0=\neyJpZCI6M30=\n"},
+		// the command is run once per record, with that record as its stdin.
+		{name: "exec", args: []string{"cat", "-dir", dir, "-decode", "raw", "-exec", "wc -c | tr -d ' '"}, wantCode: 0, wantStdout: "8\n8\n8\n"},
+		{name: "exec that ignores stdin", args: []string{"cat", "-dir", dir, "-exec", "echo x"}, wantCode: 0, wantStdout: "x\nx\nx\n"},
+		{name: "exec fails", args: []string{"cat", "-dir", dir, "-exec", "exit 3"}, wantCode: 1},
+	}
+	for _, v := range tt {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run(v.args, stdout, stderr)
+		if code != v.wantCode {
+			t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", v.name, code, v.wantCode, stdout, stderr)
+		}
+		if stdout.String() != v.wantStdout {
+			t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v", v.name, stdout.String(), v.wantStdout)
+		}
+	}
+	// cat opens the commitlog read-only.
+	if after := dirForTests(t, dir); !cmp.Equal(after, before) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", after, before)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+)
+
+// archiveSuffix is the suffix of the files, in the export format of clog.Export, that copy writes archives to.
+const archiveSuffix = ".shfx"
+
+// progressEvery is how often, in bytes copied, copy reports its progress.
+const progressEvery = 64 * 1024 * 1024
+
+var errCopyURL = errors.New("copying to a URL is not supported; copy to an archive file and upload that instead")
+
+func copyLog(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("copy", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `usage: shifta copy -src <dir> -dst <dir|file.shfx> [flags]
+
+copy copies the sealed segments of the commitlog in src to dst; the active segment is not copied since it may still be
+appended to. -since & -until select the segments created within a window of time.
+If dst ends with `+archiveSuffix+` the segments are written to it as an archive, in the export format of clog.Export.
+Otherwise dst is a commitlog directory, and only the segments that are newer than the latest one already in dst are
+copied; so an interrupted copy resumes from where it stopped when it is run again.
+The copy is verified, using the checksums of the export format, before it is moved into place.
+Nothing in src is modified; so it can be copied while another process appends to it.
+dst can not be a URL, eg of an S3 bucket; copy to an archive file & upload that with the tools of the object store.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+
+	var src, dst, since, until string
+	flags.StringVar(&src, "src", "", "directory of the commitlog to copy.")
+	flags.StringVar(&dst, "dst", "", "directory, or "+archiveSuffix+" archive file, to copy to.")
+	flags.StringVar(&since, "since", "", "only copy segments created at, or after, this RFC3339 time.")
+	flags.StringVar(&until, "until", "", "only copy segments created before this RFC3339 time.")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if src == "" || dst == "" {
+		fmt.Fprintln(stderr, "shifta copy: -src & -dst are required")
+		flags.Usage()
+		return 2
+	}
+	from, to, errA := copyWindow(since, until)
+	if errA != nil {
+		fmt.Fprintln(stderr, "shifta copy:", errA)
+		return 2
+	}
+	if strings.Contains(dst, "://") {
+		fmt.Fprintln(stderr, "shifta copy:", errCopyURL)
+		return 2
+	}
+
+	l, errC := clog.OpenReadOnly(src)
+	if errC != nil {
+		fmt.Fprintln(stderr, "shifta copy:", errC)
+		return 1
+	}
+	defer l.Close()
+
+	var n uint64
+	var errD error
+	if strings.HasSuffix(dst, archiveSuffix) {
+		n, errD = copyToArchive(l, dst, from, to, stdout)
+	} else {
+		n, errD = copyToDir(l, dst, from, to, stdout)
+	}
+	if errD != nil {
+		fmt.Fprintln(stderr, "shifta copy:", errD)
+		return 1
+	}
+	fmt.Fprintf(stdout, "copied %d bytes from %s to %s\n", n, src, dst)
+	return 0
+}
+
+// copyWindow parses the -since & -until flags into the range of baseOffsets, [from, to), to copy.
+// baseOffsets are the times at which segments were created, in nanoseconds since the unix epoch.
+func copyWindow(since, until string) (from, to uint64, err error) {
+	from, to = 0, math.MaxUint64
+	if since != "" {
+		t, errA := time.Parse(time.RFC3339, since)
+		if errA != nil {
+			return 0, 0, fmt.Errorf("bad -since: %w", errA)
+		}
+		from = uint64(t.UnixNano())
+	}
+	if until != "" {
+		t, errB := time.Parse(time.RFC3339, until)
+		if errB != nil {
+			return 0, 0, fmt.Errorf("bad -until: %w", errB)
+		}
+		to = uint64(t.UnixNano())
+	}
+	if from >= to {
+		return 0, 0, errors.New("-since should be before -until")
+	}
+	return from, to, nil
+}
+
+// copyToArchive exports the segments of l within [from, to) to the archive file dst.
+// The archive is written to a temporary file that only replaces dst once it is complete.
+func copyToArchive(l *clog.Clog, dst string, from, to uint64, stdout io.Writer) (uint64, error) {
+	tmp := dst + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp) // a no-op once tmp has been renamed.
+
+	pw := &progressWriter{w: f, out: stdout}
+	_, errA := l.ExportRange(pw, from, to)
+	if errA != nil {
+		_ = f.Close()
+		return pw.n, errA
+	}
+	errB := f.Sync()
+	if errB != nil {
+		_ = f.Close()
+		return pw.n, errB
+	}
+	errC := f.Close()
+	if errC != nil {
+		return pw.n, errC
+	}
+	return pw.n, os.Rename(tmp, dst)
+}
+
+// copyToDir copies the segments of l within [from, to), that are newer than the latest segment in the commitlog
+// directory dst, to dst.
+func copyToDir(l *clog.Clog, dst string, from, to uint64, stdout io.Writer) (uint64, error) {
+	err := os.MkdirAll(dst, 0o740)
+	if err != nil {
+		return 0, err
+	}
+	latest, errA := latestSegment(dst)
+	if errA != nil {
+		return 0, errA
+	}
+	if latest >= from {
+		// resume.
+		from = latest + 1
+	}
+	if from >= to {
+		fmt.Fprintln(stdout, "nothing to copy; dst is up to date")
+		return 0, nil
+	}
+	if latest != 0 {
+		fmt.Fprintf(stdout, "resuming after segment %d\n", latest)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		_, errB := l.ExportRange(pw, from, to)
+		_ = pw.CloseWithError(errB)
+	}()
+	// progress is reported from this goroutine, as the copy is restored, rather than from the one exporting it.
+	rd := &progressReader{r: pr, out: stdout}
+	errC := clog.Restore(rd, clog.OSFileSystem{}, dst, clog.FlatLayout{})
+	_ = pr.Close()
+	return rd.n, errC
+}
+
+// latestSegment returns the baseOffset of the latest segment in the commitlog directory dir, or 0 if it has none.
+func latestSegment(dir string) (uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var latest uint64
+	for _, e := range entries {
+		n, ok, errA := clog.FlatLayout{}.ParseSegmentPath(filepath.Base(e.Name()))
+		if errA != nil || !ok {
+			continue
+		}
+		if n > latest {
+			latest = n
+		}
+	}
+	return latest, nil
+}
+
+// reportProgress reports, to out, whenever the number of bytes copied crosses a multiple of progressEvery.
+func reportProgress(out io.Writer, before, after uint64) {
+	if after/progressEvery > before/progressEvery {
+		fmt.Fprintf(out, "copied %d MB\n", after/(1024*1024))
+	}
+}
+
+// progressWriter reports the progress of writes to w. see reportProgress
+type progressWriter struct {
+	w   io.Writer
+	out io.Writer
+	n   uint64
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	reportProgress(p.out, p.n, p.n+uint64(n))
+	p.n = p.n + uint64(n)
+	return n, err
+}
+
+// progressReader reports the progress of reads from r. see reportProgress
+type progressReader struct {
+	r   io.Reader
+	out io.Writer
+	n   uint64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	reportProgress(p.out, p.n, p.n+uint64(n))
+	p.n = p.n + uint64(n)
+	return n, err
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/komuw/shifta/clog"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	createSrc := func(t *testing.T) (string, func()) {
+		dir, err := ioutil.TempDir("", "shifta-copy")
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		l, errA := clog.New(filepath.Join(dir, "src"), 100, 10_000, time.Hour)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		// each append fills a segment; so we end up with 3 sealed segments and an active one.
+		for i := 0; i < 4; i++ {
+			errB := l.Append([]byte(strings.Repeat("a", 100)))
+			if errB != nil {
+				t.Fatal("\n\t", errB)
+			}
+		}
+		return dir, func() { os.RemoveAll(dir) }
+	}
+
+	t.Run("bad flags", func(t *testing.T) {
+		t.Parallel()
+
+		tt := []struct {
+			name     string
+			args     []string
+			wantCode int
+		}{
+			{name: "no src", args: []string{"copy", "-dst", "/tmp/x"}, wantCode: 2},
+			{name: "bad since", args: []string{"copy", "-src", "/tmp/x", "-dst", "/tmp/y", "-since", "yesterday"}, wantCode: 2},
+			{name: "since after until", args: []string{"copy", "-src", "/tmp/x", "-dst", "/tmp/y", "-since", "2021-02-01T00:00:00Z", "-until", "2021-01-01T00:00:00Z"}, wantCode: 2},
+			{name: "url", args: []string{"copy", "-src", "/tmp/x", "-dst", "s3://bucket/x"}, wantCode: 2},
+			{name: "src does not exist", args: []string{"copy", "-src", "/tmp/shifta-copy-does-not-exist", "-dst", "/tmp/y"}, wantCode: 1},
+		}
+		for _, v := range tt {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(v.args, stdout, stderr)
+			if code != v.wantCode {
+				t.Errorf("%s:\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", v.name, code, v.wantCode, stdout, stderr)
+			}
+		}
+	})
+
+	t.Run("to a directory, resumably", func(t *testing.T) {
+		t.Parallel()
+
+		dir, remove := createSrc(t)
+		defer remove()
+		src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+		before := dirForTests(t, src)
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run([]string{"copy", "-src", src, "-dst", dst}, stdout, stderr)
+		if code != 0 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, 0, stdout, stderr)
+		}
+		// copy opens src read-only.
+		if after := dirForTests(t, src); !cmp.Equal(after, before) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", after, before)
+		}
+		l, err := clog.New(dst, 100, 10_000, time.Hour)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		b, _, errA := l.Read(0, 0)
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		records, errB := clog.Records(b)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		if got := string(bytes.Join(records, nil)); got != strings.Repeat("a", 300) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", got, strings.Repeat("a", 300))
+		}
+
+		// copying again only copies what is new; nothing.
+		stdout.Reset()
+		code = run([]string{"copy", "-src", src, "-dst", dst}, stdout, stderr)
+		if code != 0 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, 0, stdout, stderr)
+		}
+		if !strings.Contains(stdout.String(), "resuming after segment") {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stdout.String(), "resuming after segment")
+		}
+		l2, errB := clog.New(dst, 100, 10_000, time.Hour)
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+		b2, _, errC := l2.Read(0, 0)
+		if errC != nil {
+			t.Fatal("\n\t", errC)
+		}
+		if string(b2) != string(b) {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", string(b2), string(b))
+		}
+	})
+
+	t.Run("to an archive", func(t *testing.T) {
+		t.Parallel()
+
+		dir, remove := createSrc(t)
+		defer remove()
+		src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "backup.shfx")
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run([]string{"copy", "-src", src, "-dst", dst, "-until", time.Now().Add(time.Hour).Format(time.RFC3339)}, stdout, stderr)
+		if code != 0 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, 0, stdout, stderr)
+		}
+		f, err := os.Open(dst)
+		if err != nil {
+			t.Fatal("\n\t", err)
+		}
+		defer f.Close()
+		var segments int
+		errA := clog.ReadExport(f, func(baseOffset uint64, data []byte) error {
+			segments++
+			return nil
+		})
+		if errA != nil {
+			t.Fatal("\n\t", errA)
+		}
+		if segments != 3 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", segments, 3)
+		}
+	})
+}
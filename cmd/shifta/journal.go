@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+)
+
+func journal(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("journal", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `usage: shifta journal -dir <dir>
+
+journal prints the ops journal of the commitlog in dir; the maintenance, like splits & cleans, that has been
+done on it and when. Oldest first, one event per line.
+The commitlog is not opened, so journal can be used on a commitlog that is in use.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+
+	var dir string
+	flags.StringVar(&dir, "dir", "", "directory of the commitlog.")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if dir == "" {
+		fmt.Fprintln(stderr, "shifta journal: -dir is required")
+		flags.Usage()
+		return 2
+	}
+
+	fi, errA := os.Stat(dir)
+	if errA != nil {
+		fmt.Fprintln(stderr, "shifta journal:", errA)
+		return 1
+	}
+	if !fi.IsDir() {
+		fmt.Fprintf(stderr, "shifta journal: %s is not a directory\n", dir)
+		return 1
+	}
+
+	events, errB := clog.ReadOpsJournal(clog.OSFileSystem{}, dir)
+	if errB != nil {
+		fmt.Fprintln(stderr, "shifta journal:", errB)
+		return 1
+	}
+	for _, e := range events {
+		fmt.Fprintf(stdout, "%s\t%s\t%s\n", e.Time.Format(time.RFC3339Nano), e.Op, e.Detail)
+	}
+	return 0
+}
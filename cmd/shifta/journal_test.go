@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+)
+
+func TestJournal(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "shifta-journal")
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer os.RemoveAll(dir)
+	_, errA := clog.New(dir, 100, 10_000, time.Hour)
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+
+	tt := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+	}{
+		{name: "no dir", args: []string{"journal"}, wantCode: 2},
+		{name: "dir does not exist", args: []string{"journal", "-dir", "/tmp/shifta-journal-does-not-exist"}, wantCode: 1},
+		{name: "prints events", args: []string{"journal", "-dir", dir}, wantCode: 0, wantStdout: "\t" + clog.OpsOpen + "\t"},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(v.args, stdout, stderr)
+			if code != v.wantCode {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, v.wantCode, stdout, stderr)
+			}
+			if !strings.Contains(stdout.String(), v.wantStdout) {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stdout.String(), v.wantStdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Command shifta provides tools for working with shifta commitlogs.
+//
+// usage:
+//
+//	shifta <command> [flags]
+//
+// The commands are:
+//
+//	soak    run concurrent workloads, optionally with injected faults, against a commitlog and verify its invariants.
+//	serve   serve the sealed segments of a commitlog over HTTP, read-only.
+//	journal print the ops journal of a commitlog; the maintenance that has been done on it.
+//	copy    copy a commitlog, or a window of time of it, to another directory or to an archive file.
+//	cat     print the data of a commitlog, decoded for humans to read.
+//
+// Use `shifta <command> -h` for more information about a command.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: shifta <command> [flags]
+
+The commands are:
+	soak    run concurrent workloads, optionally with injected faults, against a commitlog and verify its invariants.
+	serve   serve the sealed segments of a commitlog over HTTP, read-only.
+	journal print the ops journal of a commitlog; the maintenance that has been done on it.
+	copy    copy a commitlog, or a window of time of it, to another directory or to an archive file.
+	cat     print the data of a commitlog, decoded for humans to read.
+
+Use "shifta <command> -h" for more information about a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command in args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 1 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "soak":
+		return soak(args[1:], stdout, stderr)
+	case "serve":
+		return serve(args[1:], stdout, stderr)
+	case "journal":
+		return journal(args[1:], stdout, stderr)
+	case "copy":
+		return copyLog(args[1:], stdout, stderr)
+	case "cat":
+		return cat(args[1:], stdout, stderr)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "shifta: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// dirForTests returns the files in dir, with their contents.
+func dirForTests(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, errR := os.ReadFile(p)
+		files[p] = string(b)
+		return errR
+	})
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	return files
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	tt := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "no command", args: []string{}, wantCode: 2},
+		{name: "unknown command", args: []string{"nope"}, wantCode: 2},
+		{name: "help", args: []string{"help"}, wantCode: 0},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(v.args, stdout, stderr)
+			if code != v.wantCode {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", code, v.wantCode)
+			}
+			if !strings.Contains(stdout.String()+stderr.String(), "usage: shifta") {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stdout.String()+stderr.String(), "usage")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+)
+
+func serve(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `usage: shifta serve -dir <dir> [flags]
+
+serve serves the sealed segments of the commitlog in dir over HTTP, read-only.
+	GET /segments/              lists the sealed segments as JSON.
+	GET /segments/<baseOffset>  returns the raw segment file; with ETag & range support.
+Nothing in dir is modified. The commitlog can be served while another process writes to it;
+segments that it seals, or deletes, are picked up every -poll.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+
+	var dir, addr string
+	var fetchQuota uint64
+	var poll time.Duration
+	flags.StringVar(&dir, "dir", "", "directory of the commitlog to serve.")
+	flags.StringVar(&addr, "addr", "localhost:8080", "address to listen on.")
+	flags.Uint64Var(&fetchQuota, "fetch-quota", 0, "bytes per second that each client, by IP address, can fetch; clients beyond it get 429 with a Retry-After. 0 means no quota.")
+	flags.DurationVar(&poll, "poll", time.Second, "how often to pick up the segments sealed, or deleted, by the writer of the commitlog.")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if dir == "" {
+		fmt.Fprintln(stderr, "shifta serve: -dir is required")
+		flags.Usage()
+		return 2
+	}
+
+	fi, errA := os.Stat(dir)
+	if errA != nil {
+		fmt.Fprintln(stderr, "shifta serve:", errA)
+		return 1
+	}
+	if !fi.IsDir() {
+		fmt.Fprintf(stderr, "shifta serve: %s is not a directory\n", dir)
+		return 1
+	}
+
+	l, errB := clog.OpenReadOnly(dir, clog.WithPollInterval(poll, func(e error) { fmt.Fprintln(stderr, "shifta serve:", e) }))
+	if errB != nil {
+		fmt.Fprintln(stderr, "shifta serve:", errB)
+		return 1
+	}
+
+	defer l.Close()
+
+	ln, errC := net.Listen("tcp", addr)
+	if errC != nil {
+		fmt.Fprintln(stderr, "shifta serve:", errC)
+		return 1
+	}
+	fmt.Fprintf(stdout, "serving segments of %s on http://%s/segments/\n", dir, ln.Addr())
+
+	h := clog.NewSegmentHandler(l)
+	if fetchQuota > 0 {
+		h = clog.NewQuotaHandler(h, clog.Quota{FetchBytesPerSec: fetchQuota})
+	}
+	errD := http.Serve(ln, h)
+	fmt.Fprintln(stderr, "shifta serve:", errD)
+	return 1
+}
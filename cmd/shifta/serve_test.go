@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/komuw/shifta/clog"
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "shifta-serve")
+	if err != nil {
+		t.Fatal("\n\t", err)
+	}
+	defer os.RemoveAll(dir)
+	l, errA := clog.New(dir, 16, 10_000, time.Hour)
+	if errA != nil {
+		t.Fatal("\n\t", errA)
+	}
+	for _, r := range []string{`{"id":1}`, `{"id":2}`, `{"id":3}`} {
+		errB := l.Append([]byte(r))
+		if errB != nil {
+			t.Fatal("\n\t", errB)
+		}
+	}
+	errC := l.Close()
+	if errC != nil {
+		t.Fatal("\n\t", errC)
+	}
+	before := dirForTests(t, dir)
+
+	tt := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "no dir", args: []string{"serve"}, wantCode: 2},
+		{name: "dir does not exist", args: []string{"serve", "-dir", "/tmp/shifta-serve-does-not-exist"}, wantCode: 1},
+		{name: "bad address", args: []string{"serve", "-dir", dir, "-addr", "not-an-address"}, wantCode: 1},
+	}
+	for _, v := range tt {
+		v := v
+		t.Run(v.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			code := run(v.args, stdout, stderr)
+			if code != v.wantCode {
+				t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, v.wantCode, stdout, stderr)
+			}
+		})
+	}
+	// serve opens the commitlog read-only.
+	if after := dirForTests(t, dir); !cmp.Equal(after, before) {
+		t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", after, before)
+	}
+}
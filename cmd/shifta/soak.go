@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/komuw/shifta/clog"
+	"github.com/komuw/shifta/clog/clogtest"
+)
+
+var errSoakViolations = errors.New("soak found invariant violations")
+
+type soakConfig struct {
+	dir         string
+	duration    time.Duration
+	faultRate   float64
+	writers     int
+	readers     int
+	maxSegBytes uint64
+	seed        int64
+}
+
+type soakReport struct {
+	acked          uint64
+	failedAppends  uint64
+	reads          uint64
+	failedReads    uint64
+	cleans         uint64
+	failedCleans   uint64
+	faultsInjected uint64
+	violations     []string
+}
+
+func soak(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("soak", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, `usage: shifta soak [flags]
+
+soak runs concurrent append, read & clean workloads against a commitlog in dir, optionally injecting storage faults.
+Once done, it verifies that no acknowledged record was lost and that read offsets only ever moved forward.
+Retention is set high enough that cleaning never deletes data during the soak.
+
+flags:
+`)
+		flags.PrintDefaults()
+	}
+
+	c := soakConfig{}
+	flags.StringVar(&c.dir, "dir", "", "directory in which to create the commitlog. A temporary directory, removed afterwards, is used if empty.")
+	flags.DurationVar(&c.duration, "duration", 10*time.Second, "how long to run the workloads for.")
+	flags.Float64Var(&c.faultRate, "fault-rate", 0, "probability, in the range [0, 1], that a write or fsync fails.")
+	flags.IntVar(&c.writers, "writers", 8, "number of concurrent appenders.")
+	flags.IntVar(&c.readers, "readers", 4, "number of concurrent readers.")
+	flags.Uint64Var(&c.maxSegBytes, "max-seg-bytes", 1_000_000, "maximum size of each segment.")
+	flags.Int64Var(&c.seed, "seed", time.Now().UnixNano(), "seed for the fault injection.")
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+	if c.faultRate < 0 || c.faultRate > 1 || c.writers < 1 || c.readers < 0 || c.maxSegBytes < 1 {
+		fmt.Fprintln(stderr, "shifta soak: invalid flags")
+		flags.Usage()
+		return 2
+	}
+
+	if c.dir == "" {
+		dir, errA := ioutil.TempDir("", "shifta-soak")
+		if errA != nil {
+			fmt.Fprintln(stderr, "shifta soak:", errA)
+			return 1
+		}
+		defer os.RemoveAll(dir)
+		c.dir = dir
+	}
+
+	fmt.Fprintf(stdout, "soaking %s for %s with fault-rate %v (seed %d)\n", c.dir, c.duration, c.faultRate, c.seed)
+	r, errB := runSoak(c)
+	fmt.Fprintf(stdout,
+		"appends: %d acked, %d failed\nreads: %d ok, %d failed\ncleans: %d ok, %d failed\nfaults injected: %d\n",
+		r.acked, r.failedAppends, r.reads-r.failedReads, r.failedReads, r.cleans-r.failedCleans, r.failedCleans, r.faultsInjected,
+	)
+	for _, v := range r.violations {
+		fmt.Fprintln(stdout, "VIOLATION:", v)
+	}
+	if errB != nil {
+		fmt.Fprintln(stderr, "shifta soak:", errB)
+		return 1
+	}
+	fmt.Fprintln(stdout, "OK")
+	return 0
+}
+
+// soakRecord returns the record appended for id.
+func soakRecord(id uint64) []byte {
+	return []byte("soak-" + strconv.FormatUint(id, 10))
+}
+
+func runSoak(c soakConfig) (soakReport, error) {
+	r := soakReport{}
+
+	fsys := clogtest.NewFaultyFS(clog.OSFileSystem{}, c.seed)
+	l, err := clog.New(c.dir, c.maxSegBytes, 1<<62, 100_000*time.Hour, clog.WithFileSystem(fsys))
+	if err != nil {
+		return r, err
+	}
+	// l is closed before the directory is reopened; this only closes it on the paths that return earlier.
+	defer func() { _ = l.Close() }()
+	if c.faultRate > 0 {
+		fsys.SetRules(
+			clogtest.Rule{Op: clogtest.OpWrite, Probability: c.faultRate, ShortWrite: true},
+			clogtest.Rule{Op: clogtest.OpSync, Probability: c.faultRate},
+		)
+	}
+
+	var (
+		nextID  uint64
+		ackedMu sync.Mutex
+		acked   = map[uint64]struct{}{}
+
+		violationsMu sync.Mutex
+		violations   []string
+	)
+	violate := func(format string, a ...interface{}) {
+		violationsMu.Lock()
+		violations = append(violations, fmt.Sprintf(format, a...))
+		violationsMu.Unlock()
+	}
+
+	deadline := time.Now().Add(c.duration)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < c.writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				id := atomic.AddUint64(&nextID, 1)
+				errA := l.Append(soakRecord(id))
+				if errA != nil {
+					atomic.AddUint64(&r.failedAppends, 1)
+					continue
+				}
+				ackedMu.Lock()
+				acked[id] = struct{}{}
+				ackedMu.Unlock()
+			}
+		}()
+	}
+
+	for i := 0; i < c.readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var offset uint64
+			for time.Now().Before(deadline) {
+				atomic.AddUint64(&r.reads, 1)
+				_, lastReadOffset, errB := l.Read(offset, 0)
+				if errB != nil {
+					atomic.AddUint64(&r.failedReads, 1)
+					continue
+				}
+				if lastReadOffset == 0 {
+					// nothing new to read.
+					continue
+				}
+				if lastReadOffset <= offset {
+					violate("read offset moved backwards from %d to %d", offset, lastReadOffset)
+				}
+				offset = lastReadOffset
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for time.Now().Before(deadline) {
+			atomic.AddUint64(&r.cleans, 1)
+			errC := l.Clean()
+			if errC != nil {
+				atomic.AddUint64(&r.failedCleans, 1)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	wg.Wait()
+	fsys.SetRules()
+	r.acked = uint64(len(acked))
+	r.faultsInjected = fsys.Injected()
+
+	// verify the log as seen by the running commitlog, and as found on disk by a freshly opened one.
+	errD := verifySoak(l, acked, violate)
+	if errD != nil {
+		return r, errD
+	}
+	// only one writer may have the directory open at a time.
+	errClose := l.Close()
+	if errClose != nil {
+		return r, errClose
+	}
+	reopened, errE := clog.New(c.dir, c.maxSegBytes, 1<<62, 100_000*time.Hour)
+	if errE != nil {
+		return r, errE
+	}
+	defer reopened.Close()
+	errF := verifySoak(reopened, acked, violate)
+	if errF != nil {
+		return r, errF
+	}
+
+	r.violations = violations
+	if len(violations) > 0 {
+		return r, errSoakViolations
+	}
+	return r, nil
+}
+
+// verifySoak checks that every acked record can be read back from l.
+func verifySoak(l clog.CommitLog, acked map[uint64]struct{}, violate func(format string, a ...interface{})) error {
+	found := map[uint64]struct{}{}
+	var offset uint64
+	for {
+		data, lastReadOffset, err := l.Read(offset, 0)
+		if err != nil {
+			return err
+		}
+		records, errR := clog.Records(data)
+		if errR != nil {
+			violate("%v in %s", errR, l.Path())
+		}
+		for _, r := range records {
+			id, errA := strconv.ParseUint(string(bytes.TrimPrefix(r, []byte("soak-"))), 10, 64)
+			if errA != nil || !bytes.HasPrefix(r, []byte("soak-")) {
+				violate("torn record %q in %s", r, l.Path())
+				continue
+			}
+			found[id] = struct{}{}
+		}
+		if lastReadOffset == 0 {
+			break
+		}
+		offset = lastReadOffset
+	}
+
+	for id := range acked {
+		if _, ok := found[id]; !ok {
+			violate("acked record %d is missing from %s", id, l.Path())
+		}
+	}
+	return nil
+}
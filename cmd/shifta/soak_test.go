@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSoak(t *testing.T) {
+	t.Parallel()
+
+	t.Run("without faults", func(t *testing.T) {
+		t.Parallel()
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run([]string{"soak", "-duration", "300ms", "-max-seg-bytes", "500"}, stdout, stderr)
+		if code != 0 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, 0, stdout, stderr)
+		}
+		if !strings.Contains(stdout.String(), "OK") {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stdout.String(), "OK")
+		}
+	})
+
+	t.Run("with faults", func(t *testing.T) {
+		t.Parallel()
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run([]string{"soak", "-duration", "300ms", "-fault-rate", "0.2", "-seed", "7"}, stdout, stderr)
+		if code != 0 {
+			t.Fatalf("\ngot \n\t%#+v \nwanted \n\t%#+v\n%s%s", code, 0, stdout, stderr)
+		}
+		if strings.Contains(stdout.String(), "faults injected: 0\n") {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", stdout.String(), "some faults injected")
+		}
+	})
+
+	t.Run("bad flags", func(t *testing.T) {
+		t.Parallel()
+
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		code := run([]string{"soak", "-fault-rate", "3"}, stdout, stderr)
+		if code != 2 {
+			t.Errorf("\ngot \n\t%#+v \nwanted \n\t%#+v", code, 2)
+		}
+	})
+}